@@ -0,0 +1,117 @@
+package booking
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// TurnTime is how long a party of up to Max_Party keeps a table.
+type TurnTime struct {
+	Max_Party int `json:"max_party"`
+	Minutes   int `json:"minutes"`
+}
+
+// Config describes when tables can be booked and for how long. Opens and
+// Last_Seating are "15:04" clock times in Timezone.
+type Config struct {
+	Timezone              string     `json:"timezone"`
+	Opens                 string     `json:"opens"`
+	Last_Seating          string     `json:"last_seating"`
+	Slot_Minutes          int        `json:"slot_minutes"`
+	Turn_Times            []TurnTime `json:"turn_times"`
+	Default_Turn_Minutes  int        `json:"default_turn_minutes"`
+	Buffer_Minutes        int        `json:"buffer_minutes"`
+	No_Show_Grace_Minutes int        `json:"no_show_grace_minutes"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timezone:     "UTC",
+		Opens:        "11:00",
+		Last_Seating: "22:00",
+		Slot_Minutes: 15,
+		Turn_Times: []TurnTime{
+			{Max_Party: 2, Minutes: 75},
+			{Max_Party: 4, Minutes: 90},
+			{Max_Party: 6, Minutes: 120},
+		},
+		Default_Turn_Minutes:  150,
+		Buffer_Minutes:        0,
+		No_Show_Grace_Minutes: 15,
+	}
+}
+
+// LoadConfig reads a JSON config from path. Fields left out keep their
+// defaults; an empty path gives the default config.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("booking: reading %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("booking: parsing %s: %w", path, err)
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (cfg Config) Validate() error {
+	if _, err := time.LoadLocation(cfg.Timezone); err != nil {
+		return fmt.Errorf("booking: timezone %q: %w", cfg.Timezone, err)
+	}
+
+	opens, err := clockMinutes(cfg.Opens)
+	if err != nil {
+		return err
+	}
+
+	lastSeating, err := clockMinutes(cfg.Last_Seating)
+	if err != nil {
+		return err
+	}
+
+	if lastSeating < opens {
+		return fmt.Errorf("booking: last seating %s is before opening at %s", cfg.Last_Seating, cfg.Opens)
+	}
+
+	if cfg.Slot_Minutes <= 0 || cfg.Default_Turn_Minutes <= 0 {
+		return fmt.Errorf("booking: slot and default turn times must be positive")
+	}
+
+	if cfg.Buffer_Minutes < 0 || cfg.No_Show_Grace_Minutes < 0 {
+		return fmt.Errorf("booking: buffer and no-show grace cannot be negative")
+	}
+
+	for _, turn := range cfg.Turn_Times {
+		if turn.Max_Party <= 0 || turn.Minutes <= 0 {
+			return fmt.Errorf("booking: turn time for parties of %d is not valid", turn.Max_Party)
+		}
+	}
+
+	return nil
+}
+
+func (cfg Config) turnTimes() []TurnTime {
+	turns := append([]TurnTime{}, cfg.Turn_Times...)
+	sort.Slice(turns, func(i, j int) bool { return turns[i].Max_Party < turns[j].Max_Party })
+	return turns
+}
+
+// clockMinutes turns "15:04" into minutes after midnight.
+func clockMinutes(clock string) (int, error) {
+	at, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("booking: %q is not a 24-hour clock time", clock)
+	}
+
+	return at.Hour()*60 + at.Minute(), nil
+}
@@ -0,0 +1,158 @@
+package booking
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+// Slot is a bookable start time and how many tables are free for it.
+type Slot struct {
+	Start       time.Time `json:"start"`
+	Available   bool      `json:"available"`
+	Tables_Free int       `json:"tables_free"`
+}
+
+// Planner assigns tables to bookings. It only works out which tables are
+// free; keeping two bookings off the same table is up to the caller, which
+// must check and save a booking in one store transaction.
+type Planner struct {
+	cfg      Config
+	loc      *time.Location
+	opens    int
+	lastSeat int
+}
+
+func NewPlanner(cfg Config) (*Planner, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	loc, _ := time.LoadLocation(cfg.Timezone)
+	opens, _ := clockMinutes(cfg.Opens)
+	lastSeat, _ := clockMinutes(cfg.Last_Seating)
+
+	return &Planner{cfg: cfg, loc: loc, opens: opens, lastSeat: lastSeat}, nil
+}
+
+func (p *Planner) Location() *time.Location {
+	return p.loc
+}
+
+// TurnTime is how long a party of size keeps its table.
+func (p *Planner) TurnTime(size int) time.Duration {
+	for _, turn := range p.cfg.turnTimes() {
+		if size <= turn.Max_Party {
+			return time.Duration(turn.Minutes) * time.Minute
+		}
+	}
+
+	return time.Duration(p.cfg.Default_Turn_Minutes) * time.Minute
+}
+
+func (p *Planner) buffer() time.Duration {
+	return time.Duration(p.cfg.Buffer_Minutes) * time.Minute
+}
+
+// End is when a party of size seated at start gives its table back.
+func (p *Planner) End(start time.Time, size int) time.Time {
+	return start.Add(p.TurnTime(size))
+}
+
+// Window is the stretch of time in which bookings could clash with a party
+// of size starting at start, buffer included.
+func (p *Planner) Window(start time.Time, size int) (time.Time, time.Time) {
+	return start.Add(-p.buffer()), p.End(start, size).Add(p.buffer())
+}
+
+// CheckStart makes sure start is in the future and within seating hours.
+func (p *Planner) CheckStart(start, now time.Time) error {
+	if !start.After(now) {
+		return fmt.Errorf("the booking must be for a time in the future")
+	}
+
+	local := start.In(p.loc)
+	minutes := local.Hour()*60 + local.Minute()
+	if minutes < p.opens || minutes > p.lastSeat {
+		return fmt.Errorf("tables can be booked from %s to %s", p.cfg.Opens, p.cfg.Last_Seating)
+	}
+
+	return nil
+}
+
+// NoShowAfter is when a party that has not arrived may be marked a no-show.
+func (p *Planner) NoShowAfter(reservation models.Reservation) time.Time {
+	return reservation.Start_Time.Add(time.Duration(p.cfg.No_Show_Grace_Minutes) * time.Minute)
+}
+
+// Day parses a "2006-01-02" date, or today when empty, and returns the
+// start of that day and the next in the planner's timezone.
+func (p *Planner) Day(date string, now time.Time) (time.Time, time.Time, error) {
+	if date == "" {
+		date = now.In(p.loc).Format("2006-01-02")
+	}
+
+	day, err := time.ParseInLocation("2006-01-02", date, p.loc)
+	if err != nil {
+		return day, day, fmt.Errorf("date must look like 2006-01-02")
+	}
+
+	return day, day.AddDate(0, 0, 1), nil
+}
+
+// Free lists the tables that seat size and are not held by any of booked
+// at any point of the window starting at start. Tables closest in size to
+// the party come first so big tables are kept for big parties.
+func (p *Planner) Free(tables []models.Table, booked []models.Reservation, size int, start time.Time, ignore string) []models.Table {
+	from, to := p.Window(start, size)
+
+	held := map[string]bool{}
+	for _, reservation := range booked {
+		if reservation.Reservation_ID != ignore && reservation.Overlaps(from, to) {
+			held[reservation.Table_ID] = true
+		}
+	}
+
+	free := []models.Table{}
+	for _, table := range tables {
+		if table.Seats() >= size && !held[table.Table_ID] {
+			free = append(free, table)
+		}
+	}
+
+	sort.SliceStable(free, func(i, j int) bool {
+		if free[i].Seats() != free[j].Seats() {
+			return free[i].Seats() < free[j].Seats()
+		}
+		return tableNumber(free[i]) < tableNumber(free[j])
+	})
+
+	return free
+}
+
+// Availability lists every seating slot on the day starting at day for a
+// party of size.
+func (p *Planner) Availability(tables []models.Table, booked []models.Reservation, size int, day, now time.Time) []Slot {
+	slots := []Slot{}
+	for minutes := p.opens; minutes <= p.lastSeat; minutes += p.cfg.Slot_Minutes {
+		start := time.Date(day.Year(), day.Month(), day.Day(), 0, minutes, 0, 0, p.loc)
+		if !start.After(now) {
+			continue
+		}
+
+		free := len(p.Free(tables, booked, size, start, ""))
+		slots = append(slots, Slot{Start: start, Available: free > 0, Tables_Free: free})
+	}
+
+	return slots
+}
+
+func tableNumber(table models.Table) int {
+	if table.Table_Number == nil {
+		return 0
+	}
+
+	return *table.Table_Number
+}
@@ -0,0 +1,1001 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	helper "github.com/jamesconfy/restaurant-management/helpers"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/payments"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ManagerApproval is how a refund, void or comp gets signed off: with a
+// manager's email and password, or with an approval token they issued.
+// Managers acting from their own session need neither.
+type ManagerApproval struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Token    string `json:"token"`
+}
+
+// errApprovalSpent is returned from inside the transaction when another
+// adjustment has used the approval token first.
+var errApprovalSpent = errors.New("approval token already used")
+
+type AdjustmentRequest struct {
+	Reason_Code string          `json:"reason_code" validate:"required"`
+	Reason      string          `json:"reason" validate:"required,max=500"`
+	Amount      *models.Money   `json:"amount"`
+	Payment_ID  string          `json:"payment_id"`
+	Approval    ManagerApproval `json:"approval"`
+}
+
+// CreateApproval issues the calling manager a single-use approval token to
+// hand to whoever is making the adjustment.
+func CreateApproval() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, expiresAt, err := helper.GenerateApprovalToken(c.GetString("email"), c.GetString("first_name"), c.GetString("last_name"), c.GetString("userId"), c.GetString("role"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"approval_token": token, "expires_at": expiresAt, "approved_by_id": c.GetString("userId")})
+	}
+}
+
+func GetAdjustments(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		filter := store.AdjustmentFilter{
+			Invoice_ID:     c.Query("invoice_id"),
+			Order_Item_ID:  c.Query("order_item_id"),
+			Type:           c.Query("type"),
+			Approved_By_ID: c.Query("approved_by"),
+		}
+
+		var err error
+		if filter.From, err = parseDateParam(c.Query("from")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date or RFC 3339 time"})
+			return
+		}
+
+		if filter.To, err = parseDateParam(c.Query("to")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date or RFC 3339 time"})
+			return
+		}
+
+		adjustments, err := s.Adjustments.List(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching adjustments!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"total_count": len(adjustments), "adjustments": adjustments})
+	}
+}
+
+// RefundInvoice pays money back on an invoice, by default everything that
+// has been paid. Card payments are refunded through the provider.
+func RefundInvoice(s *store.Store, engine *pricing.Engine, provider payments.PaymentProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		input, adjustment, ok := bindAdjustment(ctx, s, c, models.AdjustmentRefund)
+		if !ok {
+			return
+		}
+
+		invoice, err := s.Invoices.Get(ctx, c.Param("invoice_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find invoice with that id"})
+			return
+		}
+
+		if invoice.Closed() {
+			c.JSON(http.StatusConflict, gin.H{"error": "The invoice is " + *invoice.Payment_Status})
+			return
+		}
+
+		refundable, found := refundableOn(invoice, input.Payment_ID)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that payment on the invoice"})
+			return
+		}
+
+		amount, ok := adjustmentAmount(c, input, refundable)
+		if !ok {
+			return
+		}
+
+		adjustment.Invoice_ID = invoice.Invoice_ID
+		adjustment.Order_ID = invoice.Order_ID
+		if !reserveRefund(ctx, s, c, input.Approval, &adjustment, amount, input.Payment_ID, nil) {
+			return
+		}
+
+		refundErr := sendRefunds(ctx, s, provider, &adjustment)
+		if !recordRefund(ctx, s, c, engine, &adjustment, nil) {
+			return
+		}
+
+		refundResponse(c, adjustment, refundErr)
+	}
+}
+
+// VoidInvoice cancels an invoice that has not been paid against.
+func VoidInvoice(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		input, adjustment, ok := bindAdjustment(ctx, s, c, models.AdjustmentVoid)
+		if !ok {
+			return
+		}
+
+		invoice, ok := unpaidInvoice(ctx, s, c, c.Param("invoice_id"))
+		if !ok {
+			return
+		}
+
+		summary, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while pricing the invoice"})
+			return
+		}
+
+		adjustment.Invoice_ID = invoice.Invoice_ID
+		adjustment.Order_ID = invoice.Order_ID
+		adjustment.Amount = summary.Payment_Due
+		if !saveAdjustment(ctx, s, c, input.Approval, adjustment, func(ctx context.Context) error {
+			return closeInvoice(ctx, s, invoice.Invoice_ID, models.PaymentVoided, adjustment.Created_At)
+		}) {
+			return
+		}
+
+		c.JSON(http.StatusAccepted, adjustment)
+	}
+}
+
+// CompInvoice gives the whole of an unpaid bill away.
+func CompInvoice(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		input, adjustment, ok := bindAdjustment(ctx, s, c, models.AdjustmentComp)
+		if !ok {
+			return
+		}
+
+		invoice, ok := unpaidInvoice(ctx, s, c, c.Param("invoice_id"))
+		if !ok {
+			return
+		}
+
+		summary, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while pricing the invoice"})
+			return
+		}
+
+		adjustment.Invoice_ID = invoice.Invoice_ID
+		adjustment.Order_ID = invoice.Order_ID
+		adjustment.Amount = summary.Payment_Due
+		if !saveAdjustment(ctx, s, c, input.Approval, adjustment, func(ctx context.Context) error {
+			orderItems, err := s.OrderItems.ListByOrder(ctx, invoice.Order_ID)
+			if err != nil {
+				return err
+			}
+
+			for _, orderItem := range orderItems {
+				if !orderItem.Billable() {
+					continue
+				}
+
+				orderItem.Adjustment = itemAdjustment(adjustment)
+				orderItem.Updated_At = adjustment.Created_At
+				if err := s.OrderItems.Update(ctx, orderItem); err != nil {
+					return err
+				}
+			}
+
+			return closeInvoice(ctx, s, invoice.Invoice_ID, models.PaymentComped, adjustment.Created_At)
+		}) {
+			return
+		}
+
+		c.JSON(http.StatusAccepted, adjustment)
+	}
+}
+
+// RefundOrderItem pays back one item's share of a paid invoice, tax and
+// service charge included, or part of it.
+func RefundOrderItem(s *store.Store, engine *pricing.Engine, provider payments.PaymentProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		input, adjustment, ok := bindAdjustment(ctx, s, c, models.AdjustmentRefund)
+		if !ok {
+			return
+		}
+
+		orderItem, ok := adjustableOrderItem(ctx, s, c, c.Param("order_item_id"))
+		if !ok {
+			return
+		}
+
+		invoice, err := s.Invoices.GetByOrder(ctx, *orderItem.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "The item's order has not been invoiced"})
+			return
+		}
+
+		if invoice.Closed() {
+			c.JSON(http.StatusConflict, gin.H{"error": "The invoice is " + *invoice.Payment_Status})
+			return
+		}
+
+		_, share, ok := orderItemShare(ctx, s, c, engine, orderItem)
+		if !ok {
+			return
+		}
+
+		refundable, found := refundableOn(invoice, input.Payment_ID)
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that payment on the invoice"})
+			return
+		}
+		if share.Amount < refundable.Amount {
+			refundable = share
+		}
+
+		amount, ok := adjustmentAmount(c, input, refundable)
+		if !ok {
+			return
+		}
+
+		adjustment.Invoice_ID = invoice.Invoice_ID
+		adjustment.Order_ID = invoice.Order_ID
+		adjustment.Order_Item_ID = orderItem.Order_Item_ID
+		marked := itemAdjustment(adjustment)
+		if !reserveRefund(ctx, s, c, input.Approval, &adjustment, amount, input.Payment_ID, func(ctx context.Context) error {
+			orderItem, err := s.OrderItems.Get(ctx, orderItem.Order_Item_ID)
+			if err != nil {
+				return err
+			}
+
+			if orderItem.Adjustment != nil {
+				return orderChanged("The item has already been adjusted by a " + orderItem.Adjustment.Type)
+			}
+
+			orderItem.Adjustment = marked
+			orderItem.Updated_At = adjustment.Created_At
+			return s.OrderItems.Update(ctx, orderItem)
+		}) {
+			return
+		}
+
+		refundErr := sendRefunds(ctx, s, provider, &adjustment)
+		if !recordRefund(ctx, s, c, engine, &adjustment, func(ctx context.Context) error {
+			orderItem, err := s.OrderItems.Get(ctx, orderItem.Order_Item_ID)
+			if err != nil {
+				return err
+			}
+
+			if orderItem.Adjustment == nil || orderItem.Adjustment.Adjustment_ID != adjustment.Adjustment_ID {
+				return nil
+			}
+
+			orderItem.Adjustment = nil
+			orderItem.Updated_At = adjustment.Created_At
+			return s.OrderItems.Update(ctx, orderItem)
+		}) {
+			return
+		}
+
+		refundResponse(c, adjustment, refundErr)
+	}
+}
+
+// VoidOrderItem takes an item off a bill nothing has been paid against. An
+// item the kitchen has not served yet is cancelled there too.
+func VoidOrderItem(s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory, engine *pricing.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		input, adjustment, ok := bindAdjustment(ctx, s, c, models.AdjustmentVoid)
+		if !ok {
+			return
+		}
+
+		orderItem, ok := adjustableOrderItem(ctx, s, c, c.Param("order_item_id"))
+		if !ok {
+			return
+		}
+
+		invoice, err := s.Invoices.GetByOrder(ctx, *orderItem.Order_ID)
+		invoiced := err == nil
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the invoice for that item"})
+			return
+		}
+
+		if invoiced {
+			if _, ok := unpaidInvoice(ctx, s, c, invoice.Invoice_ID); !ok {
+				return
+			}
+		}
+
+		_, share, ok := orderItemShare(ctx, s, c, engine, orderItem)
+		if !ok {
+			return
+		}
+
+		cancelled := orderItem.CurrentStatus() != models.ItemServed
+		if cancelled {
+			status := models.ItemCancelled
+			orderItem.Status = &status
+		}
+		orderItem.Adjustment = itemAdjustment(adjustment)
+		orderItem.Updated_At = adjustment.Created_At
+
+		adjustment.Order_ID = *orderItem.Order_ID
+		adjustment.Order_Item_ID = orderItem.Order_Item_ID
+		adjustment.Amount = share
+		if invoiced {
+			adjustment.Invoice_ID = invoice.Invoice_ID
+		}
+
+		if !saveAdjustment(ctx, s, c, input.Approval, adjustment, func(ctx context.Context) error {
+			if cancelled {
+				if err := returnStock(ctx, s, inv, &orderItem); err != nil {
+					return err
+				}
+			}
+			if err := s.OrderItems.Update(ctx, orderItem); err != nil {
+				return err
+			}
+			if invoiced {
+				return resettleInvoice(ctx, s, engine, invoice.Invoice_ID)
+			}
+			return nil
+		}) {
+			return
+		}
+
+		if cancelled {
+			publishTicket(ctx, s, hub, kitchen.ItemCancelled, orderItem)
+		}
+
+		c.JSON(http.StatusAccepted, adjustment)
+	}
+}
+
+// CompOrderItem takes an item off the bill as a give-away. Once the bill is
+// paid beyond what it would come to without the item, refund it instead.
+func CompOrderItem(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		input, adjustment, ok := bindAdjustment(ctx, s, c, models.AdjustmentComp)
+		if !ok {
+			return
+		}
+
+		orderItem, ok := adjustableOrderItem(ctx, s, c, c.Param("order_item_id"))
+		if !ok {
+			return
+		}
+
+		summary, share, ok := orderItemShare(ctx, s, c, engine, orderItem)
+		if !ok {
+			return
+		}
+
+		invoice, err := s.Invoices.GetByOrder(ctx, *orderItem.Order_ID)
+		invoiced := err == nil
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the invoice for that item"})
+			return
+		}
+
+		if invoiced {
+			if invoice.Closed() {
+				c.JSON(http.StatusConflict, gin.H{"error": "The invoice is " + *invoice.Payment_Status})
+				return
+			}
+
+			if invoice.AmountPaid("").Amount > summary.Payment_Due.Sub(share).Amount {
+				c.JSON(http.StatusConflict, gin.H{"error": "The bill has already been paid; refund the item instead"})
+				return
+			}
+		}
+
+		orderItem.Adjustment = itemAdjustment(adjustment)
+		orderItem.Updated_At = adjustment.Created_At
+
+		adjustment.Order_ID = *orderItem.Order_ID
+		adjustment.Order_Item_ID = orderItem.Order_Item_ID
+		adjustment.Amount = share
+		if invoiced {
+			adjustment.Invoice_ID = invoice.Invoice_ID
+		}
+
+		if !saveAdjustment(ctx, s, c, input.Approval, adjustment, func(ctx context.Context) error {
+			if err := s.OrderItems.Update(ctx, orderItem); err != nil {
+				return err
+			}
+			if invoiced {
+				return resettleInvoice(ctx, s, engine, invoice.Invoice_ID)
+			}
+			return nil
+		}) {
+			return
+		}
+
+		c.JSON(http.StatusAccepted, adjustment)
+	}
+}
+
+// bindAdjustment reads and validates the request, checks the manager's
+// approval and starts the audit record with who made and approved it.
+func bindAdjustment(ctx context.Context, s *store.Store, c *gin.Context, adjustmentType string) (AdjustmentRequest, models.Adjustment, bool) {
+	var input AdjustmentRequest
+
+	if err := c.BindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return input, models.Adjustment{}, false
+	}
+
+	adjustment, ok := startAdjustment(ctx, s, c, adjustmentType, input)
+	return input, adjustment, ok
+}
+
+// startAdjustment validates an adjustment request that has already been
+// read, checks the manager's approval and starts the audit record.
+func startAdjustment(ctx context.Context, s *store.Store, c *gin.Context, adjustmentType string, input AdjustmentRequest) (models.Adjustment, bool) {
+	var adjustment models.Adjustment
+
+	if err := validate.Struct(input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return adjustment, false
+	}
+
+	if !validReasonCode(input.Reason_Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown reason code", "reason_codes": models.ReasonCodes})
+		return adjustment, false
+	}
+
+	manager, method, ok := managerApproval(ctx, s, c, input.Approval)
+	if !ok {
+		return adjustment, false
+	}
+
+	adjustment = models.Adjustment{
+		ID:               primitive.NewObjectID(),
+		Type:             adjustmentType,
+		Reason_Code:      input.Reason_Code,
+		Reason:           input.Reason,
+		Actor_ID:         c.GetString("userId"),
+		Actor_Name:       c.GetString("first_name") + " " + c.GetString("last_name"),
+		Approved_By_ID:   manager.User_ID,
+		Approved_By_Name: fullName(manager),
+		Approval_Method:  method,
+	}
+	adjustment.Adjustment_ID = adjustment.ID.Hex()
+	adjustment.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+	return adjustment, true
+}
+
+func managerApproval(ctx context.Context, s *store.Store, c *gin.Context, approval ManagerApproval) (models.User, string, bool) {
+	var manager models.User
+	var err error
+
+	switch {
+	case approval.Token != "":
+		claims, err := helper.ValidateToken(ctx, s.Revocations, approval.Token)
+		if err != nil || claims.Token_Type != models.ApprovalToken {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The approval token is not valid"})
+			return manager, "", false
+		}
+
+		manager, err = s.Users.Get(ctx, claims.User_ID)
+		if err != nil || !middleware.HasPermission(manager.UserRole(), middleware.ApproveAdjustments) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The approval token is not valid"})
+			return manager, "", false
+		}
+
+		// Approval tokens are good for a single adjustment; saveAdjustment
+		// spends the token along with the adjustment it approves.
+		return manager, models.ApprovedByToken, true
+	case approval.Email != "":
+		manager, err = s.Users.GetByEmail(ctx, approval.Email)
+		if err != nil || manager.Password == nil || !VerifyPassword(*manager.Password, approval.Password) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The manager's email or password is incorrect"})
+			return manager, "", false
+		}
+
+		if !middleware.HasPermission(manager.UserRole(), middleware.ApproveAdjustments) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "That user cannot approve refunds, voids or comps"})
+			return manager, "", false
+		}
+
+		return manager, models.ApprovedByCredentials, true
+	case middleware.HasPermission(c.GetString("role"), middleware.ApproveAdjustments):
+		manager, err = s.Users.Get(ctx, c.GetString("userId"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+			return manager, "", false
+		}
+
+		return manager, models.ApprovedBySelf, true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "A manager has to approve this"})
+	return manager, "", false
+}
+
+// saveAdjustment applies the adjustment's effects and records it in one
+// transaction, spending the approval token with them so that a failed
+// adjustment leaves the token usable.
+func saveAdjustment(ctx context.Context, s *store.Store, c *gin.Context, approval ManagerApproval, adjustment models.Adjustment, apply func(ctx context.Context) error) bool {
+	var applied bool
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		applied = false
+		if err := spendApproval(ctx, s, approval, adjustment); err != nil {
+			return err
+		}
+
+		if err := apply(ctx); err != nil {
+			return err
+		}
+		applied = true
+
+		return s.Adjustments.Create(ctx, adjustment)
+	})
+
+	var changed orderChanged
+	switch {
+	case errors.Is(err, errApprovalSpent):
+		c.JSON(http.StatusForbidden, gin.H{"error": "The approval token has already been used"})
+		return false
+	case errors.As(err, &changed):
+		c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+		return false
+	case err != nil && !applied:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not apply the " + adjustment.Type})
+		return false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not record the " + adjustment.Type})
+		return false
+	}
+
+	return true
+}
+
+// spendApproval uses up the approval token the adjustment was approved
+// with, if it was approved with one.
+func spendApproval(ctx context.Context, s *store.Store, approval ManagerApproval, adjustment models.Adjustment) error {
+	if adjustment.Approval_Method != models.ApprovedByToken {
+		return nil
+	}
+
+	err := helper.SpendToken(ctx, s.Revocations, approval.Token)
+	if errors.Is(err, store.ErrDuplicate) {
+		return errApprovalSpent
+	}
+
+	return err
+}
+
+// adjustmentAmount is the amount asked for, or all of limit when none was.
+func adjustmentAmount(c *gin.Context, input AdjustmentRequest, limit models.Money) (models.Money, bool) {
+	if limit.Amount <= 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Nothing is left to refund"})
+		return limit, false
+	}
+
+	if input.Amount == nil {
+		return limit, true
+	}
+
+	if !input.Amount.SameCurrency(limit) || input.Amount.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The amount must be positive and in " + limit.Currency})
+		return limit, false
+	}
+
+	if input.Amount.Amount > limit.Amount {
+		c.JSON(http.StatusConflict, gin.H{"error": "At most " + limit.Decimal() + " can be refunded"})
+		return limit, false
+	}
+
+	return *input.Amount, true
+}
+
+// refundableOn is what has been paid on the invoice and not refunded yet,
+// or only on paymentId when one is given. It returns false if there is no
+// such payment.
+func refundableOn(invoice models.Invoice, paymentId string) (models.Money, bool) {
+	if paymentId == "" {
+		return invoice.AmountPaid("").Sub(invoice.AmountRefunded("")), true
+	}
+
+	payment, ok := findPayment(invoice, paymentId)
+	if !ok {
+		return models.Money{}, false
+	}
+
+	return payment.Amount.Sub(invoice.AmountRefunded(payment.Payment_ID)), true
+}
+
+// reserveRefund is the first step of a refund. In one transaction it spends
+// the approval token, checks amount against the invoice as it is now and
+// puts the refunds on it as pending, so that no other refund can take the
+// same money while the provider is called. hold, if given, runs in the same
+// transaction.
+func reserveRefund(ctx context.Context, s *store.Store, c *gin.Context, approval ManagerApproval, adjustment *models.Adjustment, amount models.Money, paymentId string, hold func(ctx context.Context) error) bool {
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		adjustment.Refunds = nil
+		if err := spendApproval(ctx, s, approval, *adjustment); err != nil {
+			return err
+		}
+
+		invoice, err := s.Invoices.Get(ctx, adjustment.Invoice_ID)
+		if err != nil {
+			return err
+		}
+
+		if invoice.Closed() {
+			return orderChanged("The invoice is " + *invoice.Payment_Status)
+		}
+
+		if refundable, _ := refundableOn(invoice, paymentId); amount.Amount > refundable.Amount {
+			return orderChanged("At most " + refundable.Decimal() + " is left to refund")
+		}
+
+		planRefunds(&invoice, adjustment, amount, paymentId)
+		if hold != nil {
+			if err := hold(ctx); err != nil {
+				return err
+			}
+		}
+
+		invoice.Updated_At = adjustment.Created_At
+		return s.Invoices.Update(ctx, invoice)
+	})
+
+	var changed orderChanged
+	switch {
+	case errors.Is(err, errApprovalSpent):
+		c.JSON(http.StatusForbidden, gin.H{"error": "The approval token has already been used"})
+		return false
+	case errors.As(err, &changed):
+		c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+		return false
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not start the refund"})
+		return false
+	}
+
+	return true
+}
+
+// planRefunds spreads amount over the invoice's payments, newest first, or
+// takes it all from paymentId, and puts the refunds on the invoice and the
+// adjustment as pending.
+func planRefunds(invoice *models.Invoice, adjustment *models.Adjustment, amount models.Money, paymentId string) {
+	remaining := amount
+	for i := len(invoice.Payments) - 1; i >= 0 && remaining.Amount > 0; i-- {
+		payment := invoice.Payments[i]
+		if paymentId != "" && payment.Payment_ID != paymentId {
+			continue
+		}
+
+		part := payment.Amount.Sub(invoice.AmountRefunded(payment.Payment_ID))
+		if part.Amount <= 0 {
+			continue
+		}
+		if remaining.Amount < part.Amount {
+			part = remaining
+		}
+
+		refund := models.Refund{
+			Refund_ID:      primitive.NewObjectID().Hex(),
+			Payment_ID:     payment.Payment_ID,
+			Tender:         payment.Tender,
+			Amount:         part,
+			Transaction_ID: payment.Transaction_ID,
+			Adjustment_ID:  adjustment.Adjustment_ID,
+			Status:         models.RefundPending,
+			Created_At:     adjustment.Created_At,
+		}
+		invoice.Refunds = append(invoice.Refunds, refund)
+		adjustment.Refunds = append(adjustment.Refunds, refund)
+		remaining = remaining.Sub(part)
+	}
+}
+
+// sendRefunds makes the adjustment's pending refunds, card ones through the
+// provider, and marks each one made as completed. It stops at the first
+// refund the provider refuses, leaving that one and the rest pending.
+func sendRefunds(ctx context.Context, s *store.Store, provider payments.PaymentProvider, adjustment *models.Adjustment) error {
+	for i, refund := range adjustment.Refunds {
+		if refund.Transaction_ID != "" {
+			transaction, err := s.Transactions.Get(ctx, refund.Transaction_ID)
+			if err != nil {
+				return err
+			}
+
+			if err := refundCharge(ctx, s, provider, &transaction, refund.Amount, refund.Refund_ID); err != nil {
+				return err
+			}
+		}
+
+		adjustment.Refunds[i].Status = models.RefundCompleted
+	}
+
+	return nil
+}
+
+// recordRefund is the last step of a refund. It marks the refunds that were
+// made as completed on the invoice, takes the ones that were not back off
+// it and records the adjustment for what was refunded. When nothing was,
+// release undoes what the first step held and no adjustment is recorded.
+//
+// If this fails after money was refunded, the refunds stay pending on the
+// invoice, under the adjustment's id, for someone to reconcile.
+func recordRefund(ctx context.Context, s *store.Store, c *gin.Context, engine *pricing.Engine, adjustment *models.Adjustment, release func(ctx context.Context) error) bool {
+	completed := map[string]bool{}
+	made := []models.Refund{}
+	adjustment.Amount = models.Money{}
+	for _, refund := range adjustment.Refunds {
+		if refund.Status == models.RefundCompleted {
+			completed[refund.Refund_ID] = true
+			made = append(made, refund)
+			adjustment.Amount = adjustment.Amount.Add(refund.Amount)
+		}
+	}
+	adjustment.Refunds = made
+
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		invoice, err := s.Invoices.Get(ctx, adjustment.Invoice_ID)
+		if err != nil {
+			return err
+		}
+
+		refunds := []models.Refund{}
+		for _, refund := range invoice.Refunds {
+			if refund.Adjustment_ID == adjustment.Adjustment_ID && refund.Pending() {
+				if !completed[refund.Refund_ID] {
+					continue
+				}
+				refund.Status = models.RefundCompleted
+			}
+			refunds = append(refunds, refund)
+		}
+		invoice.Refunds = refunds
+
+		summary, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+		if err != nil {
+			return err
+		}
+
+		settleInvoice(&invoice, summary.Payment_Due)
+		if err := s.Invoices.Update(ctx, invoice); err != nil {
+			return err
+		}
+
+		if len(made) == 0 {
+			if release != nil {
+				return release(ctx)
+			}
+			return nil
+		}
+
+		return s.Adjustments.Create(ctx, *adjustment)
+	})
+
+	if err != nil {
+		if len(made) > 0 {
+			log.Printf("adjustments: refunds for %s were made with the provider but could not be recorded; they are left pending on invoice %s: %v", adjustment.Adjustment_ID, adjustment.Invoice_ID, err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not record the refund", "adjustment_id": adjustment.Adjustment_ID})
+		return false
+	}
+
+	return true
+}
+
+// refundResponse reports how a recorded refund went.
+func refundResponse(c *gin.Context, adjustment models.Adjustment, refundErr error) {
+	switch {
+	case len(adjustment.Refunds) == 0:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "The refund could not be made"})
+	case refundErr != nil:
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Only part of the refund could be made", "adjustment": adjustment})
+	default:
+		c.JSON(http.StatusAccepted, adjustment)
+	}
+}
+
+// closeInvoice voids or comps an invoice, re-reading it so that nothing
+// recorded on it since it was checked is lost. It fails with orderChanged
+// if the invoice has been paid against or closed in the meantime.
+func closeInvoice(ctx context.Context, s *store.Store, invoiceId, status string, at time.Time) error {
+	invoice, err := s.Invoices.Get(ctx, invoiceId)
+	if err != nil {
+		return err
+	}
+
+	if invoice.Closed() {
+		return orderChanged("The invoice is " + *invoice.Payment_Status)
+	}
+
+	if len(invoice.Payments) > 0 {
+		return orderChanged("Payments have been taken on the invoice; refund them instead")
+	}
+
+	invoice.Payment_Status = &status
+	invoice.Updated_At = at
+	return s.Invoices.Update(ctx, invoice)
+}
+
+// unpaidInvoice loads an invoice that is still open and has no payments.
+func unpaidInvoice(ctx context.Context, s *store.Store, c *gin.Context, invoiceId string) (models.Invoice, bool) {
+	invoice, err := s.Invoices.Get(ctx, invoiceId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find invoice with that id"})
+		return invoice, false
+	}
+
+	if invoice.Closed() {
+		c.JSON(http.StatusConflict, gin.H{"error": "The invoice is " + *invoice.Payment_Status})
+		return invoice, false
+	}
+
+	if len(invoice.Payments) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Payments have been taken on the invoice; refund them instead"})
+		return invoice, false
+	}
+
+	return invoice, true
+}
+
+// adjustableOrderItem loads an order item that is on the bill and has not
+// been adjusted yet.
+func adjustableOrderItem(ctx context.Context, s *store.Store, c *gin.Context, orderItemId string) (models.OrderItem, bool) {
+	orderItem, err := s.OrderItems.Get(ctx, orderItemId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that order item"})
+		return orderItem, false
+	}
+
+	if orderItem.Adjustment != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The item has already been adjusted by a " + orderItem.Adjustment.Type})
+		return orderItem, false
+	}
+
+	if !orderItem.Billable() || orderItem.Order_ID == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The item is not on the bill"})
+		return orderItem, false
+	}
+
+	return orderItem, true
+}
+
+// orderItemShare prices the item's order and works out the item's share of
+// the total, tax and service charge included.
+func orderItemShare(ctx context.Context, s *store.Store, c *gin.Context, engine *pricing.Engine, orderItem models.OrderItem) (OrderItemsSummary, models.Money, bool) {
+	summary, err := ItemsByOrder(ctx, s, engine, *orderItem.Order_ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while pricing the order"})
+		return summary, models.Money{}, false
+	}
+
+	weights := make([]int64, len(summary.Breakdown.Lines))
+	index := -1
+	for i, line := range summary.Breakdown.Lines {
+		weights[i] = line.Charged().Amount
+		if line.Reference == orderItem.Order_Item_ID {
+			index = i
+		}
+	}
+
+	if index < 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "The item is not on the bill"})
+		return summary, models.Money{}, false
+	}
+
+	return summary, pricing.Allocate(summary.Payment_Due, weights)[index], true
+}
+
+// resettleInvoice brings an invoice's status up to date after its items
+// changed.
+func resettleInvoice(ctx context.Context, s *store.Store, engine *pricing.Engine, invoiceId string) error {
+	invoice, err := s.Invoices.Get(ctx, invoiceId)
+	if err != nil {
+		return err
+	}
+
+	summary, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+	if err != nil {
+		return err
+	}
+
+	settleInvoice(&invoice, summary.Payment_Due)
+	return s.Invoices.Update(ctx, invoice)
+}
+
+func itemAdjustment(adjustment models.Adjustment) *models.ItemAdjustment {
+	return &models.ItemAdjustment{
+		Adjustment_ID: adjustment.Adjustment_ID,
+		Type:          adjustment.Type,
+		Reason_Code:   adjustment.Reason_Code,
+		At:            adjustment.Created_At,
+	}
+}
+
+func findPayment(invoice models.Invoice, paymentId string) (models.Payment, bool) {
+	for _, payment := range invoice.Payments {
+		if payment.Payment_ID == paymentId {
+			return payment, true
+		}
+	}
+
+	return models.Payment{}, false
+}
+
+func validReasonCode(code string) bool {
+	for _, known := range models.ReasonCodes {
+		if code == known {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fullName(user models.User) string {
+	name := ""
+	if user.First_Name != nil {
+		name = *user.First_Name
+	}
+	if user.Last_Name != nil {
+		name += " " + *user.Last_Name
+	}
+
+	return name
+}
+
+// parseDateParam accepts either a bare date or an RFC 3339 time; an empty
+// value gives the zero time.
+func parseDateParam(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if at, err := time.Parse("2006-01-02", value); err == nil {
+		return at, nil
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
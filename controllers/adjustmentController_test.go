@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/payments"
+)
+
+// paidInvoice puts an item at price on the test order and pays for it in
+// cash.
+func paidInvoice(t *testing.T, env *testEnv, price string) {
+	t.Helper()
+
+	env.addItem(t, env.order.Order_ID, price, 1)
+	amount, err := models.ParseMoney(price, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payment := PaymentRequest{Amount: &amount, Tender: models.TenderCash}
+	if code := env.do(t, "POST", "/invoices/"+env.invoice.Invoice_ID+"/payments", payment, nil); code != http.StatusAccepted {
+		t.Fatalf("paying the invoice got %d, want %d", code, http.StatusAccepted)
+	}
+}
+
+func TestRefundInvoice(t *testing.T) {
+	env := newTestEnv(t)
+	provider := payments.NewSimulator("test", "")
+	env.router.POST("/invoices/:invoice_id/payments", AddPayment(env.s, env.engine, provider))
+	env.router.POST("/invoices/:invoice_id/refund", RefundInvoice(env.s, env.engine, provider))
+	paidInvoice(t, env, "20.00")
+
+	amount := models.NewMoney(500, models.DefaultCurrency)
+	refund := AdjustmentRequest{
+		Reason_Code: "ENTERED_IN_ERROR",
+		Reason:      "Rang up twice",
+		Amount:      &amount,
+		Approval:    ManagerApproval{Token: env.approval(t)},
+	}
+	path := "/invoices/" + env.invoice.Invoice_ID + "/refund"
+
+	var adjustment models.Adjustment
+	if code := env.do(t, "POST", path, refund, &adjustment); code != http.StatusAccepted {
+		t.Fatalf("got %d, want %d", code, http.StatusAccepted)
+	}
+	if adjustment.Amount != amount {
+		t.Errorf("refunded %v, want %v", adjustment.Amount, amount)
+	}
+
+	invoice := env.getInvoice(t, env.invoice.Invoice_ID)
+	if *invoice.Payment_Status != models.PaymentPartiallyRefunded {
+		t.Errorf("the invoice is %s, want %s", *invoice.Payment_Status, models.PaymentPartiallyRefunded)
+	}
+
+	if code := env.do(t, "POST", path, refund, nil); code != http.StatusForbidden {
+		t.Errorf("reusing the approval token got %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+// TestRefundInvoiceRace checks that refunds of the whole bill made at once
+// do not give the money back more than once.
+func TestRefundInvoiceRace(t *testing.T) {
+	env := newTestEnv(t)
+	provider := payments.NewSimulator("test", "")
+	env.router.POST("/invoices/:invoice_id/payments", AddPayment(env.s, env.engine, provider))
+	env.router.POST("/invoices/:invoice_id/refund", RefundInvoice(env.s, env.engine, provider))
+	paidInvoice(t, env, "20.00")
+
+	tokens := []string{}
+	for i := 0; i < 5; i++ {
+		tokens = append(tokens, env.approval(t))
+	}
+
+	codes := env.race(t, len(tokens), "POST", "/invoices/"+env.invoice.Invoice_ID+"/refund", func(i int) interface{} {
+		return AdjustmentRequest{Reason_Code: "CUSTOMER_COMPLAINT", Reason: "Cold food", Approval: ManagerApproval{Token: tokens[i]}}
+	})
+	if codes[http.StatusAccepted] != 1 {
+		t.Errorf("got %v, want exactly one refund accepted", codes)
+	}
+
+	invoice := env.getInvoice(t, env.invoice.Invoice_ID)
+	if refunded := invoice.AmountRefunded(""); refunded.Amount != 2000 {
+		t.Errorf("%v was refunded, want 20.00", refunded)
+	}
+}
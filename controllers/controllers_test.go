@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	helper "github.com/jamesconfy/restaurant-management/helpers"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testEnv is a memory store with an admin signed in, a table and an open
+// order with its invoice, for handlers to be called against.
+type testEnv struct {
+	s       *store.Store
+	engine  *pricing.Engine
+	router  *gin.Engine
+	admin   models.User
+	table   models.Table
+	order   models.Order
+	invoice models.Invoice
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SECRET_KEY", "test")
+
+	ctx := context.Background()
+	engine, err := pricing.NewEngine(pricing.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := &testEnv{s: store.NewMemoryStore(), engine: engine}
+
+	first, last, email, role := "Test", "Admin", "admin@example.com", models.RoleAdmin
+	env.admin = models.User{ID: primitive.NewObjectID(), First_Name: &first, Last_Name: &last, Email: &email, Role: &role}
+	env.admin.User_ID = env.admin.ID.Hex()
+	if err := env.s.Users.Create(ctx, env.admin); err != nil {
+		t.Fatal(err)
+	}
+
+	env.router = gin.New()
+	env.router.Use(func(c *gin.Context) {
+		c.Set("userId", env.admin.User_ID)
+		c.Set("role", role)
+		c.Set("email", email)
+		c.Set("first_name", first)
+		c.Set("last_name", last)
+	})
+
+	guests, number := 4, 1
+	env.table = models.Table{ID: primitive.NewObjectID(), Number_Of_Guests: &guests, Table_Number: &number}
+	env.table.Table_ID = env.table.ID.Hex()
+	if err := env.s.Tables.Create(ctx, env.table); err != nil {
+		t.Fatal(err)
+	}
+
+	env.order, env.invoice = env.newOrder(t, &env.table.Table_ID)
+	return env
+}
+
+// newOrder places an order, at tableId if it is not nil, with a pending
+// invoice.
+func (env *testEnv) newOrder(t *testing.T, tableId *string) (models.Order, models.Invoice) {
+	t.Helper()
+	ctx := context.Background()
+
+	order := models.Order{Table_ID: tableId}
+	placeOrder(&order, env.admin.User_ID)
+	if err := env.s.Orders.Create(ctx, order); err != nil {
+		t.Fatal(err)
+	}
+
+	status := models.PaymentPending
+	invoice := models.Invoice{
+		ID:             primitive.NewObjectID(),
+		Order_ID:       order.Order_ID,
+		Payment_Status: &status,
+		Payments:       []models.Payment{},
+		Splits:         []models.BillSplit{},
+		Refunds:        []models.Refund{},
+		Created_At:     order.Created_At,
+		Updated_At:     order.Created_At,
+	}
+	invoice.Invoice_ID = invoice.ID.Hex()
+	if err := env.s.Invoices.Create(ctx, invoice); err != nil {
+		t.Fatal(err)
+	}
+
+	return order, invoice
+}
+
+// addItem puts quantity of a new food at price on the order.
+func (env *testEnv) addItem(t *testing.T, orderId, price string, quantity int) models.OrderItem {
+	t.Helper()
+	ctx := context.Background()
+
+	money, err := models.ParseMoney(price, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := "Food " + price
+	food := models.Food{ID: primitive.NewObjectID(), Name: &name, Price: &money}
+	food.Food_ID = food.ID.Hex()
+	if err := env.s.Foods.Create(ctx, food); err != nil {
+		t.Fatal(err)
+	}
+
+	status := models.ItemQueued
+	now := time.Now()
+	orderItem := models.OrderItem{
+		ID:         primitive.NewObjectID(),
+		Quantity:   &quantity,
+		Unit_Price: &money,
+		Food_ID:    &food.Food_ID,
+		Order_ID:   &orderId,
+		Status:     &status,
+		Created_At: now,
+		Updated_At: now,
+	}
+	orderItem.Order_Item_ID = orderItem.ID.Hex()
+	if err := env.s.OrderItems.Create(ctx, orderItem); err != nil {
+		t.Fatal(err)
+	}
+
+	return orderItem
+}
+
+// approval is a manager's single-use approval token.
+func (env *testEnv) approval(t *testing.T) string {
+	t.Helper()
+
+	token, _, err := helper.GenerateApprovalToken(*env.admin.Email, *env.admin.First_Name, *env.admin.Last_Name, env.admin.User_ID, *env.admin.Role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// do sends body as JSON and decodes the response into out, if it is not nil.
+func (env *testEnv) do(t *testing.T, method, path string, body, out interface{}) int {
+	t.Helper()
+
+	raw, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	env.router.ServeHTTP(w, req)
+
+	if out != nil && w.Code < 300 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decoding %s: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// race sends the same request n times at once and counts the responses by
+// status code.
+func (env *testEnv) race(t *testing.T, n int, method, path string, body func(i int) interface{}) map[int]int {
+	t.Helper()
+
+	var mu sync.Mutex
+	codes := map[int]int{}
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code := env.do(t, method, path, body(i), nil)
+
+			mu.Lock()
+			codes[code]++
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+
+	return codes
+}
+
+func (env *testEnv) getInvoice(t *testing.T, id string) models.Invoice {
+	t.Helper()
+
+	invoice, err := env.s.Invoices.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return invoice
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"github.com/jamesconfy/restaurant-management/waitlist"
+)
+
+// FloorTable is a table on the floor plan with its live status.
+type FloorTable struct {
+	models.Table
+	Status       string     `json:"status"`
+	Order_IDs    []string   `json:"order_ids"`
+	Seated_Since *time.Time `json:"seated_since"`
+}
+
+type FloorSection struct {
+	Name   string       `json:"name"`
+	Tables []FloorTable `json:"tables"`
+}
+
+// GetFloor returns every table grouped by section with its live status, for
+// the host stand to draw. ?section= narrows it to one section.
+func GetFloor(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		tables, err := s.Tables.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tables!"})
+			return
+		}
+
+		floor, err := liveTables(ctx, s, tables, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while working out table statuses"})
+			return
+		}
+
+		only := c.Query("section")
+		bySection := map[string][]FloorTable{}
+		counts := map[string]int{
+			models.TableFree: 0, models.TableSeated: 0, models.TableOrdered: 0,
+			models.TableAwaitingBill: 0, models.TableDirty: 0,
+		}
+		for _, table := range floor {
+			name := table.SectionName()
+			if only != "" && name != only {
+				continue
+			}
+
+			bySection[name] = append(bySection[name], table)
+			counts[table.Status]++
+		}
+
+		sections := []FloorSection{}
+		for name, tables := range bySection {
+			sort.Slice(tables, func(i, j int) bool { return tableNumber(tables[i].Table) < tableNumber(tables[j].Table) })
+			sections = append(sections, FloorSection{Name: name, Tables: tables})
+		}
+		sort.Slice(sections, func(i, j int) bool { return sections[i].Name < sections[j].Name })
+
+		c.JSON(http.StatusOK, gin.H{"sections": sections, "counts": counts})
+	}
+}
+
+// CleanTable marks a dirty table as reset and offers it to the waitlist.
+func CleanTable(s *store.Store, notifier waitlist.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		table, err := s.Tables.Get(ctx, c.Param("table_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that table"})
+			return
+		}
+
+		if table.Dirty_Since == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "The table is not waiting to be cleaned"})
+			return
+		}
+
+		table.Dirty_Since = nil
+		table.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := s.Tables.Update(ctx, table); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update table!"})
+			return
+		}
+
+		offerFreedTable(ctx, s, notifier, table.Table_ID)
+
+		c.JSON(http.StatusAccepted, table)
+	}
+}
+
+// releaseTable marks the table dirty once its last open order is done.
+func releaseTable(ctx context.Context, s *store.Store, tableId string, at time.Time) {
+	openOrders, err := s.Orders.ListByStatus(ctx, openOrderStatuses...)
+	if err != nil {
+		log.Printf("floor: listing open orders: %v", err)
+		return
+	}
+
+	for _, order := range openOrders {
+		if order.Table_ID != nil && *order.Table_ID == tableId {
+			return
+		}
+	}
+
+	table, err := s.Tables.Get(ctx, tableId)
+	if err != nil {
+		return
+	}
+
+	table.Dirty_Since = &at
+	table.Updated_At = at
+	if err := s.Tables.Update(ctx, table); err != nil {
+		log.Printf("floor: marking table %s dirty: %v", tableId, err)
+	}
+}
+
+// liveTables works out each table's status from its open orders, their items
+// and bills, and any reservation seated at it.
+func liveTables(ctx context.Context, s *store.Store, tables []models.Table, now time.Time) ([]FloorTable, error) {
+	openOrders, err := s.Orders.ListByStatus(ctx, openOrderStatuses...)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations, err := s.Reservations.Between(ctx, now.AddDate(0, 0, -1), now)
+	if err != nil {
+		return nil, err
+	}
+
+	activity := map[string]models.TableActivity{}
+	since := map[string]time.Time{}
+	seatedAt := func(tableId string, at time.Time) {
+		if first, ok := since[tableId]; !ok || at.Before(first) {
+			since[tableId] = at
+		}
+	}
+
+	for _, order := range openOrders {
+		if order.Table_ID == nil {
+			continue
+		}
+		tableId := *order.Table_ID
+
+		items, err := s.OrderItems.ListByOrder(ctx, order.Order_ID)
+		if err != nil {
+			return nil, err
+		}
+
+		current := activity[tableId]
+		current.Open_Orders = append(current.Open_Orders, order.Order_ID)
+		for _, item := range items {
+			if item.Billable() {
+				current.Items++
+			}
+		}
+
+		invoice, err := s.Invoices.GetByOrder(ctx, order.Order_ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return nil, err
+		}
+		if err == nil && invoice.Unpaid() {
+			current.Unpaid_Bills++
+		}
+
+		activity[tableId] = current
+		seatedAt(tableId, order.Created_At)
+	}
+
+	for _, reservation := range reservations {
+		if reservation.Status != models.ReservationSeated || !reservation.End_Time.After(now) {
+			continue
+		}
+
+		current := activity[reservation.Table_ID]
+		current.Seated_Booking = true
+		activity[reservation.Table_ID] = current
+		seatedAt(reservation.Table_ID, *reservation.Start_Time)
+	}
+
+	floor := []FloorTable{}
+	for _, table := range tables {
+		current := activity[table.Table_ID]
+		view := FloorTable{Table: table, Status: table.LiveStatus(current), Order_IDs: current.Open_Orders}
+		if view.Order_IDs == nil {
+			view.Order_IDs = []string{}
+		}
+		if at, ok := since[table.Table_ID]; ok {
+			view.Seated_Since = &at
+		}
+
+		floor = append(floor, view)
+	}
+
+	return floor, nil
+}
+
+func tableNumber(table models.Table) int {
+	if table.Table_Number == nil {
+		return 0
+	}
+
+	return *table.Table_Number
+}
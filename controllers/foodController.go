@@ -2,28 +2,30 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"log"
-	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
-	"github.com/jamesconfy/restaurant-management/database"
+	"github.com/jamesconfy/restaurant-management/inventory"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/jamesconfy/restaurant-management/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var foodCollection *mongo.Collection = database.OpenCollection(database.Client, "food")
 var validate = validator.New()
 
-func GetFoods() gin.HandlerFunc {
+// FoodAvailability 86s a food or puts it back on.
+type FoodAvailability struct {
+	Available *bool `json:"available" validate:"required"`
+}
+
+func GetFoods(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var allFoods []bson.M
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
@@ -42,32 +44,51 @@ func GetFoods() gin.HandlerFunc {
 			startIndex = (page - 1) * recordPerPage
 		}
 
-		matchStage := bson.D{{Key: "$match", Value: bson.D{{}}}}
-		groupStage := bson.D{{Key: "$group", Value: bson.D{{Key: "id", Value: bson.D{{Key: "_id", Value: "null"}}}, {Key: "total_count", Value: bson.D{{Key: "sum", Value: 1}}}, {Key: "$data", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}}}}}
-		projectStage := bson.D{{
-			Key: "$project", Value: bson.D{
-				{Key: "_id", Value: 0},
-				{Key: "total_count", Value: 1},
-				{Key: "food_items", Value: bson.D{{Key: "$slice", Value: []interface{}{"$data", startIndex, recordPerPage}}}},
-			},
-		}}
+		// ?allergen_free=MILK,PEANUTS leaves out foods containing any of
+		// them; ?dietary=VEGAN,HALAL keeps foods tagged with all of them.
+		var filter store.FoodFilter
+		for _, allergen := range queryList(c, "allergen_free") {
+			if err := validate.Var(allergen, "oneof=CELERY GLUTEN CRUSTACEANS EGGS FISH LUPIN MILK MOLLUSCS MUSTARD TREE_NUTS PEANUTS SESAME SOYA SULPHITES"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": allergen + " is not one of the declared allergens"})
+				return
+			}
+			filter.Without_Allergens = append(filter.Without_Allergens, allergen)
+		}
+
+		for _, tag := range queryList(c, "dietary") {
+			if err := validate.Var(tag, "oneof=VEGAN VEGETARIAN HALAL GLUTEN_FREE"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": tag + " is not a dietary tag"})
+				return
+			}
+			filter.Dietary_Tags = append(filter.Dietary_Tags, tag)
+		}
+
+		// ?available=true leaves out foods that are 86'd.
+		if c.Query("available") != "" {
+			available, err := strconv.ParseBool(c.Query("available"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "available must be true or false"})
+				return
+			}
+			filter.Available = &available
+		}
 
-		result, err := foodCollection.Aggregate(ctx, mongo.Pipeline{matchStage, groupStage, projectStage})
+		foods, total, err := s.Foods.List(ctx, filter, startIndex, recordPerPage)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing all the foods!"})
 			return
 		}
 
-		if err = result.All(ctx, &allFoods); err != nil {
-			log.Fatal(err.Error())
+		for i := range foods {
+			showAvailability(&foods[i])
 		}
-		c.JSON(http.StatusOK, allFoods)
+
+		c.JSON(http.StatusOK, gin.H{"total_count": total, "food_items": foods})
 	}
 }
 
-func CreateFood() gin.HandlerFunc {
+func CreateFood(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var menu models.Menu
 		var food models.Food
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
@@ -82,8 +103,29 @@ func CreateFood() gin.HandlerFunc {
 			c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
 			return
 		}
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.Menu_ID}).Decode(&menu)
-		if err != nil {
+
+		if food.Price.Amount < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Price cannot be negative"})
+			return
+		}
+
+		if err := food.PrepareModifiers(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !recipeStocked(ctx, s, c, food.Recipe) {
+			return
+		}
+
+		// Foods are 86'd through SetFoodAvailability or by running out.
+		food.Restore()
+		if err := followStock(ctx, s, &food); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check the recipe's stock"})
+			return
+		}
+
+		if _, err := s.Menus.Get(ctx, *food.Menu_ID); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Menu was not found"})
 			return
 		}
@@ -92,117 +134,272 @@ func CreateFood() gin.HandlerFunc {
 		food.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		food.ID = primitive.NewObjectID()
 		food.Food_ID = food.ID.Hex()
-		var num = toFixed(*food.Price, 2)
-		food.Price = &num
 
-		result, insertEr := foodCollection.InsertOne(ctx, &food)
-		if insertEr != nil {
+		if err := s.Foods.Create(ctx, food); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Food was not created!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
+		c.JSON(http.StatusAccepted, food)
 	}
 }
 
-func GetFood() gin.HandlerFunc {
+func GetFood(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
-		foodId := c.Param("food_id")
-		var food models.Food
-
-		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food)
 		defer cancel()
+		foodId := c.Param("food_id")
 
+		food, err := s.Foods.Get(ctx, foodId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot find that food item"})
 			return
 		}
+
+		showAvailability(&food)
 		c.JSON(http.StatusOK, food)
 	}
 }
 
-func UpdateFood() gin.HandlerFunc {
+func UpdateFood(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var food models.Food
-		var menu models.Menu
-		var updateObj primitive.D
+		var input models.Food
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		if err := c.BindJSON(&food); err != nil {
+		if err := c.BindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		foodId := c.Param("food_id")
-		filter := bson.M{"food_id": foodId}
+		food, err := s.Foods.Get(ctx, foodId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find food with that id"})
+			return
+		}
 
-		if food.Name != nil {
-			updateObj = append(updateObj, bson.E{Key: "name", Value: food.Name})
+		if input.Name != nil {
+			food.Name = input.Name
 		}
 
-		if food.Price != nil {
-			updateObj = append(updateObj, bson.E{Key: "price", Value: food.Price})
+		if input.Price != nil {
+			if input.Price.Amount < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Price cannot be negative"})
+				return
+			}
+
+			food.Price = input.Price
 		}
 
-		if food.Food_Image != nil {
-			updateObj = append(updateObj, bson.E{Key: "food_image", Value: food.Food_Image})
+		if input.Food_Image != nil {
+			food.Food_Image = input.Food_Image
 		}
 
-		if food.Menu_ID != nil {
-			err := menuCollection.FindOne(ctx, bson.M{"menu_id": food.Menu_ID}).Decode(&menu)
-			if err != nil {
+		if input.Menu_ID != nil {
+			if _, err := s.Menus.Get(ctx, *input.Menu_ID); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Menu item not found"})
 				return
 			}
 
-			updateObj = append(updateObj, bson.E{Key: "menu_id", Value: food.Menu_ID})
+			food.Menu_ID = input.Menu_ID
 		}
-		defer cancel()
 
-		updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updated_at})
+		if input.Modifier_Groups != nil {
+			food.Modifier_Groups = input.Modifier_Groups
+		}
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		if input.Allergens != nil {
+			food.Allergens = input.Allergens
 		}
 
-		result, err := menuCollection.UpdateOne(ctx, filter, bson.D{{Key: "set", Value: updateObj}}, &opt)
-		if err != nil {
+		if input.Dietary_Tags != nil {
+			food.Dietary_Tags = input.Dietary_Tags
+		}
+
+		if input.Recipe != nil {
+			food.Recipe = input.Recipe
+		}
+
+		if err := validate.Struct(food); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := food.PrepareModifiers(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !recipeStocked(ctx, s, c, food.Recipe) {
+			return
+		}
+
+		if input.Recipe != nil {
+			if err := followStock(ctx, s, &food); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check the recipe's stock"})
+				return
+			}
+		}
+
+		food.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Foods.Update(ctx, food); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update food!"})
 			return
 		}
-		defer cancel()
-		c.JSON(http.StatusAccepted, result)
+
+		c.JSON(http.StatusAccepted, food)
 	}
 }
 
-func DeleteFood() gin.HandlerFunc {
+func DeleteFood(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var food models.Food
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		foodId := c.Param("food_id")
 
-		err := foodCollection.FindOne(ctx, bson.M{"food_id": foodId}).Decode(&food)
+		food, err := s.Foods.Get(ctx, foodId)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find food with that id"})
 			return
 		}
+
+		if err := s.Foods.Delete(ctx, foodId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete food!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, food)
+	}
+}
+
+// SetFoodAvailability 86s a food by hand, or puts it back on whatever took
+// it off.
+func SetFoodAvailability(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input FoodAvailability
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		result := foodCollection.FindOneAndDelete(ctx, bson.M{"food_id": foodId}).Decode(&food)
-		c.JSON(http.StatusAccepted, result)
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		food, err := s.Foods.Get(ctx, c.Param("food_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find food with that id"})
+			return
+		}
+
+		if *input.Available {
+			food.Restore()
+		} else {
+			food.EightySix(models.UnavailableManual)
+		}
+		food.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Foods.Update(ctx, food); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update food!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, food)
+	}
+}
+
+// FollowStock 86s the foods that use an ingredient once it runs out and
+// puts them back when it is restocked.
+func FollowStock(s *store.Store) inventory.Handler {
+	return func(ctx context.Context, ev inventory.Event) {
+		if ev.Level != models.StockOut && ev.Previous != models.StockOut {
+			return
+		}
+
+		filter := store.FoodFilter{Uses_Ingredient: ev.Ingredient.Ingredient_ID}
+		for offset := 0; ; offset += 100 {
+			foods, _, err := s.Foods.List(ctx, filter, offset, 100)
+			if err != nil {
+				log.Printf("inventory: listing foods using %s: %v", ev.Ingredient.Ingredient_ID, err)
+				return
+			}
+
+			for _, food := range foods {
+				available := food.IsAvailable()
+				if err := followStock(ctx, s, &food); err != nil {
+					log.Printf("inventory: checking stock for food %s: %v", food.Food_ID, err)
+					continue
+				}
+				if food.IsAvailable() == available {
+					continue
+				}
+
+				food.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+				if err := s.Foods.Update(ctx, food); err != nil {
+					log.Printf("inventory: updating food %s: %v", food.Food_ID, err)
+					continue
+				}
+
+				if available {
+					log.Printf("inventory: 86'd %s, %s is out of stock", *food.Name, *ev.Ingredient.Name)
+				} else {
+					log.Printf("inventory: %s is available again", *food.Name)
+				}
+			}
+
+			if len(foods) < 100 {
+				return
+			}
+		}
+	}
+}
+
+// followStock 86s a food when an ingredient in its recipe is out of stock and
+// puts it back when none is. Foods 86'd by hand are left alone.
+func followStock(ctx context.Context, s *store.Store, food *models.Food) error {
+	if food.Unavailable_Reason != nil && *food.Unavailable_Reason == models.UnavailableManual {
+		return nil
+	}
+
+	for _, line := range food.Recipe {
+		ingredient, err := s.Ingredients.Get(ctx, line.Ingredient_ID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if ingredient.Level() == models.StockOut {
+			food.EightySix(models.UnavailableOutOfStock)
+			return nil
+		}
 	}
+
+	food.Restore()
+	return nil
 }
 
-func rounded(num float64) int {
-	return int(num + math.Copysign(0.5, num))
+// showAvailability fills in the flag for foods saved before it was tracked.
+func showAvailability(food *models.Food) {
+	available := food.IsAvailable()
+	food.Available = &available
 }
 
-func toFixed(num float64, precision int) float64 {
-	ouptut := math.Pow(10, float64(precision))
-	return float64(rounded(num * ouptut))
+// queryList splits a comma separated query parameter, upper-casing each value.
+func queryList(c *gin.Context, key string) []string {
+	values := []string{}
+	for _, value := range strings.Split(c.Query(key), ",") {
+		if value = strings.ToUpper(strings.TrimSpace(value)); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
 }
@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StockCount changes what is on hand, either by a delta or, after a stock
+// take, to the count.
+type StockCount struct {
+	Delta *float64 `json:"delta"`
+	Count *float64 `json:"count" validate:"omitempty,gte=0"`
+}
+
+// GetIngredients lists ingredients; ?level=LOW_STOCK,OUT_OF_STOCK shows only
+// those at the given stock levels.
+func GetIngredients(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		levels := map[string]bool{}
+		for _, level := range queryList(c, "level") {
+			if err := validate.Var(level, "oneof=IN_STOCK LOW_STOCK OUT_OF_STOCK"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": level + " is not a stock level"})
+				return
+			}
+			levels[level] = true
+		}
+
+		allIngredients, err := s.Ingredients.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the ingredients!"})
+			return
+		}
+
+		ingredients := []models.Ingredient{}
+		for _, ingredient := range allIngredients {
+			if len(levels) == 0 || levels[ingredient.Level()] {
+				ingredients = append(ingredients, ingredient)
+			}
+		}
+
+		c.JSON(http.StatusOK, ingredients)
+	}
+}
+
+func CreateIngredient(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var ingredient models.Ingredient
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if ingredient.Supplier_ID != nil && !supplierKnown(ctx, s, c, *ingredient.Supplier_ID) {
+			return
+		}
+
+		// Costs come from deliveries.
+		ingredient.Last_Cost = nil
+		ingredient.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		ingredient.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		ingredient.ID = primitive.NewObjectID()
+		ingredient.Ingredient_ID = ingredient.ID.Hex()
+
+		if err := s.Ingredients.Create(ctx, ingredient); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ingredient was not created!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, ingredient)
+	}
+}
+
+func GetIngredient(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		ingredient, err := s.Ingredients.Get(ctx, c.Param("ingredient_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that ingredient"})
+			return
+		}
+
+		c.JSON(http.StatusOK, ingredient)
+	}
+}
+
+// UpdateIngredient changes an ingredient's details. What is on hand only
+// changes through UpdateStock and the items that use it.
+func UpdateIngredient(s *store.Store, inv *inventory.Inventory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.Ingredient
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ingredient, err := s.Ingredients.Get(ctx, c.Param("ingredient_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find ingredient with that id"})
+			return
+		}
+		before := ingredient
+
+		if input.Name != nil {
+			ingredient.Name = input.Name
+		}
+
+		if input.Unit != nil {
+			ingredient.Unit = input.Unit
+		}
+
+		if input.Low_Stock_Threshold != nil {
+			ingredient.Low_Stock_Threshold = input.Low_Stock_Threshold
+		}
+
+		if input.Par_Level != nil {
+			ingredient.Par_Level = input.Par_Level
+		}
+
+		if input.Supplier_ID != nil {
+			if !supplierKnown(ctx, s, c, *input.Supplier_ID) {
+				return
+			}
+
+			ingredient.Supplier_ID = input.Supplier_ID
+		}
+
+		if err := validate.Struct(ingredient); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ingredient.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		ingredient, err = s.Ingredients.UpdateDetails(ctx, ingredient)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update ingredient!"})
+			return
+		}
+
+		// A new threshold can put the ingredient at another level. Stock
+		// that moved in the meantime raised its own alert.
+		before.On_Hand = ingredient.On_Hand
+		inv.Moved(ctx, before, ingredient)
+		c.JSON(http.StatusAccepted, ingredient)
+	}
+}
+
+// DeleteIngredient removes an ingredient no recipe uses any more.
+func DeleteIngredient(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		ingredientId := c.Param("ingredient_id")
+
+		ingredient, err := s.Ingredients.Get(ctx, ingredientId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find ingredient with that id"})
+			return
+		}
+
+		_, used, err := s.Foods.List(ctx, store.FoodFilter{Uses_Ingredient: ingredientId}, 0, 1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check the recipes for that ingredient"})
+			return
+		}
+
+		if used > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The ingredient is still in a recipe"})
+			return
+		}
+
+		if err := s.Ingredients.Delete(ctx, ingredientId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete ingredient!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, ingredient)
+	}
+}
+
+// UpdateStock records a delivery, correction or stock take.
+func UpdateStock(s *store.Store, inv *inventory.Inventory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input StockCount
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if (input.Delta == nil) == (input.Count == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Give either a delta or a count"})
+			return
+		}
+
+		ingredient, err := s.Ingredients.Get(ctx, c.Param("ingredient_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that ingredient"})
+			return
+		}
+
+		delta := 0.0
+		if input.Delta != nil {
+			delta = *input.Delta
+		} else {
+			delta = *input.Count - ingredient.On_Hand
+		}
+
+		ingredient, err = moveStock(ctx, s, inv, ingredient.Ingredient_ID, delta)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the stock!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, ingredient)
+	}
+}
+
+// moveStock adds delta to an ingredient's stock and raises an alert if that
+// moved it to another level.
+func moveStock(ctx context.Context, s *store.Store, inv *inventory.Inventory, ingredientId string, delta float64) (models.Ingredient, error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	after, err := s.Ingredients.Adjust(ctx, ingredientId, delta, now)
+	if err != nil {
+		return after, err
+	}
+
+	before := after
+	before.On_Hand -= delta
+	inv.Moved(ctx, before, after)
+	return after, nil
+}
+
+// takeStock takes what an item's recipe needs out of stock if stock is
+// taken at stage and has not been taken for the item already. Ingredients
+// deleted since the recipe was written are skipped. If a line cannot be
+// taken, the ones before it are put back so nothing is taken at all.
+func takeStock(ctx context.Context, s *store.Store, inv *inventory.Inventory, orderItem *models.OrderItem, stage string) error {
+	if orderItem.Stock_Depleted || !inv.DepletesOn(stage) {
+		return nil
+	}
+
+	needed, err := stockNeeded(ctx, s, *orderItem)
+	if err != nil {
+		return err
+	}
+
+	used := []models.RecipeLine{}
+	for _, line := range needed {
+		if _, err := moveStock(ctx, s, inv, line.Ingredient_ID, -line.Quantity); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				continue
+			}
+
+			// Put back the lines already taken, or taking stock again
+			// for the item would take them twice.
+			taken := models.OrderItem{Stock_Depleted: true, Stock_Used: used}
+			if err := returnStock(ctx, s, inv, &taken); err != nil {
+				log.Printf("stock: putting back what was taken for order item %s: %v", orderItem.Order_Item_ID, err)
+			}
+			return err
+		}
+		used = append(used, line)
+	}
+
+	orderItem.Stock_Depleted = true
+	orderItem.Stock_Used = used
+	return nil
+}
+
+// retakeStock brings what was taken for an edited item in line with what
+// its recipe needs now. Only the difference moves, so an edit does not
+// raise alerts for stock that never really came back.
+func retakeStock(ctx context.Context, s *store.Store, inv *inventory.Inventory, orderItem *models.OrderItem) error {
+	if !orderItem.Stock_Depleted {
+		return nil
+	}
+
+	needed, err := stockNeeded(ctx, s, *orderItem)
+	if err != nil {
+		return err
+	}
+
+	deltas := map[string]float64{}
+	for _, line := range orderItem.Stock_Used {
+		deltas[line.Ingredient_ID] += line.Quantity
+	}
+	for _, line := range needed {
+		deltas[line.Ingredient_ID] -= line.Quantity
+	}
+
+	gone := map[string]bool{}
+	for ingredientId, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+
+		if _, err := moveStock(ctx, s, inv, ingredientId, delta); err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				gone[ingredientId] = true
+				continue
+			}
+			return err
+		}
+	}
+
+	used := []models.RecipeLine{}
+	for _, line := range needed {
+		if !gone[line.Ingredient_ID] {
+			used = append(used, line)
+		}
+	}
+
+	orderItem.Stock_Used = used
+	return nil
+}
+
+// returnStock puts back what was taken for an item that will not be made.
+func returnStock(ctx context.Context, s *store.Store, inv *inventory.Inventory, orderItem *models.OrderItem) error {
+	if !orderItem.Stock_Depleted {
+		return nil
+	}
+
+	for _, line := range orderItem.Stock_Used {
+		if _, err := moveStock(ctx, s, inv, line.Ingredient_ID, line.Quantity); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+
+	orderItem.Stock_Depleted = false
+	orderItem.Stock_Used = nil
+	return nil
+}
+
+// stockNeeded is what the recipe for an item's food calls for at the item's
+// quantity. A food deleted since the item was ordered needs nothing.
+func stockNeeded(ctx context.Context, s *store.Store, orderItem models.OrderItem) ([]models.RecipeLine, error) {
+	if orderItem.Food_ID == nil {
+		return nil, nil
+	}
+
+	food, err := s.Foods.Get(ctx, *orderItem.Food_ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	quantity := 1
+	if orderItem.Quantity != nil {
+		quantity = *orderItem.Quantity
+	}
+
+	return food.StockNeeded(quantity), nil
+}
+
+// supplierKnown writes a 400 and returns false when there is no such supplier.
+func supplierKnown(ctx context.Context, s *store.Store, c *gin.Context, supplierId string) bool {
+	_, err := s.Suppliers.Get(ctx, supplierId)
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Supplier " + supplierId + " was not found"})
+		return false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check the supplier"})
+		return false
+	}
+
+	return true
+}
+
+// recipeStocked writes a 400 and returns false when a recipe calls for an
+// ingredient that is not kept.
+func recipeStocked(ctx context.Context, s *store.Store, c *gin.Context, recipe []models.RecipeLine) bool {
+	seen := map[string]bool{}
+	for _, line := range recipe {
+		if seen[line.Ingredient_ID] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ingredient " + line.Ingredient_ID + " appears twice in the recipe"})
+			return false
+		}
+		seen[line.Ingredient_ID] = true
+
+		_, err := s.Ingredients.Get(ctx, line.Ingredient_ID)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ingredient " + line.Ingredient_ID + " was not found"})
+			return false
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check the recipe's ingredients"})
+			return false
+		}
+	}
+
+	return true
+}
@@ -2,21 +2,17 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jamesconfy/restaurant-management/database"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var invoiceCollection *mongo.Collection = database.OpenCollection(database.Client, "invoice")
-
 type InvoiceViewFormat struct {
 	Invoice_ID       string
 	Order_ID         string
@@ -26,31 +22,35 @@ type InvoiceViewFormat struct {
 	Payment_Due_Date time.Time
 	Table_Number     interface{}
 	Order_Details    interface{}
+	Breakdown        pricing.Breakdown
+	Amount_Paid      models.Money
+	Amount_Refunded  models.Money
+	Balance_Due      models.Money
+	Payments         []models.Payment
+	Refunds          []models.Refund
+	Splits           []SplitView
+	// Notes are those left on the invoice and its order, pinned first.
+	Notes []models.Note
 }
 
-func GetInvoices() gin.HandlerFunc {
+func GetInvoices(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var allInvoices []bson.M
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		result, err := invoiceCollection.Find(context.TODO(), bson.M{})
+		allInvoices, err := s.Invoices.List(ctx)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching invoices!"})
 			return
 		}
 
-		if err = result.All(ctx, &allInvoices); err != nil {
-			log.Fatal(err.Error())
-		}
 		c.JSON(http.StatusOK, allInvoices)
 	}
 }
 
-func CreateInvoice() gin.HandlerFunc {
+func CreateInvoice(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var invoice models.Invoice
-		var order models.Order
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
@@ -65,8 +65,7 @@ func CreateInvoice() gin.HandlerFunc {
 			return
 		}
 
-		err := orderCollection.FindOne(ctx, bson.M{"table_id": invoice.Order_ID}).Decode(&order)
-		if err != nil {
+		if _, err := s.Orders.Get(ctx, invoice.Order_ID); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order was not found"})
 			return
 		}
@@ -76,10 +75,13 @@ func CreateInvoice() gin.HandlerFunc {
 			invoice.Payment_Method = &method
 		}
 
-		status := "PENDING"
-		if invoice.Payment_Status == nil {
-			invoice.Payment_Status = &status
-		}
+		// The status follows the payments recorded against the invoice, so a
+		// new invoice always starts out pending.
+		status := models.PaymentPending
+		invoice.Payment_Status = &status
+		invoice.Payments = []models.Payment{}
+		invoice.Splits = []models.BillSplit{}
+		invoice.Refunds = []models.Refund{}
 
 		invoice.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		invoice.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -87,32 +89,34 @@ func CreateInvoice() gin.HandlerFunc {
 		invoice.ID = primitive.NewObjectID()
 		invoice.Invoice_ID = invoice.ID.Hex()
 
-		result, insertEr := invoiceCollection.InsertOne(ctx, &invoice)
-		if insertEr != nil {
+		if err := s.Invoices.Create(ctx, invoice); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invoice was not created!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
+		c.JSON(http.StatusAccepted, invoice)
 	}
 }
 
-func GetInvoice() gin.HandlerFunc {
+func GetInvoice(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var invoice models.Invoice
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		invoiceId := c.Param("invoice_id")
 
-		err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		invoice, err := s.Invoices.Get(ctx, invoiceId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot find that invoice item"})
 			return
 		}
 
-		var invoiceView InvoiceViewFormat
-		allOrdersItem, _ := ItemsByOrder(invoice.Order_ID)
+		allOrdersItem, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while listing order items for the invoice"})
+			return
+		}
 
+		var invoiceView InvoiceViewFormat
 		invoiceView.Order_ID = invoice.Order_ID
 		invoiceView.Payment_Due_Date = invoice.Payment_Due_Date
 		invoiceView.Payment_Method = "null"
@@ -122,76 +126,109 @@ func GetInvoice() gin.HandlerFunc {
 
 		invoiceView.Invoice_ID = invoice.Invoice_ID
 		invoiceView.Payment_Status = invoice.Payment_Status
-		invoiceView.Payment_Due = allOrdersItem[0]["payment_due"]
-		invoiceView.Table_Number = allOrdersItem[0]["table_number"]
-		invoiceView.Order_Details = allOrdersItem[0]["invoice_items"]
+		invoiceView.Payment_Due = allOrdersItem.Payment_Due
+		invoiceView.Table_Number = allOrdersItem.Table_Number
+		invoiceView.Order_Details = allOrdersItem.Order_Items
+		invoiceView.Breakdown = allOrdersItem.Breakdown
+		invoiceView.Amount_Paid = invoice.AmountPaid("")
+		invoiceView.Balance_Due = balanceDue(allOrdersItem.Payment_Due, invoiceView.Amount_Paid)
+		invoiceView.Amount_Refunded = invoice.AmountRefunded("")
+		invoiceView.Payments = invoice.Payments
+		invoiceView.Refunds = invoice.Refunds
+		invoiceView.Splits = splitViews(invoice)
+
+		invoiceView.Notes, err = s.Notes.Attached(ctx,
+			models.NoteSubject{Type: models.NoteInvoice, ID: invoice.Invoice_ID},
+			models.NoteSubject{Type: models.NoteOrder, ID: invoice.Order_ID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while listing notes for the invoice"})
+			return
+		}
 
 		c.JSON(http.StatusOK, invoiceView)
 	}
 }
 
-func UpdateInvoice() gin.HandlerFunc {
+func UpdateInvoice(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var invoice models.Invoice
-		var updateObj primitive.D
+		var input models.Invoice
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		if err := c.BindJSON(&invoice); err != nil {
+		if err := c.BindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		invoiceId := c.Param("invoice_id")
-		filter := bson.M{"invoice_id": invoiceId}
-
-		method := "CASH"
-		if invoice.Payment_Method != nil {
-			updateObj = append(updateObj, bson.E{Key: "payment_method", Value: invoice.Payment_Method})
-		} else {
-			invoice.Payment_Method = &method
+		invoice, err := s.Invoices.Get(ctx, invoiceId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find invoice with that id"})
+			return
 		}
 
-		status := "PENDING"
-		if invoice.Payment_Status != nil {
-			updateObj = append(updateObj, bson.E{Key: "payment_status", Value: invoice.Payment_Status})
-		} else {
-			invoice.Payment_Status = &status
+		if input.Payment_Status != nil && (invoice.Payment_Status == nil || *input.Payment_Status != *invoice.Payment_Status) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The payment status follows the payments recorded on the invoice"})
+			return
 		}
 
-		updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updated_at})
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			// Read the invoice again so payments, refunds and splits
+			// recorded since the check above are not written over.
+			var err error
+			if invoice, err = s.Invoices.Get(ctx, invoiceId); err != nil {
+				return err
+			}
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
+			if input.Payment_Status != nil && (invoice.Payment_Status == nil || *input.Payment_Status != *invoice.Payment_Status) {
+				return orderChanged("The payment status follows the payments recorded on the invoice")
+			}
 
-		result, err := invoiceCollection.UpdateOne(ctx, filter, bson.D{{Key: "set", Value: updateObj}}, &opt)
+			if input.Payment_Method != nil {
+				invoice.Payment_Method = input.Payment_Method
+			}
+
+			invoice.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return s.Invoices.Update(ctx, invoice)
+		})
 		if err != nil {
+			var changed orderChanged
+			if errors.As(err, &changed) {
+				c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+				return
+			}
+
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update invoice!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
-
+		c.JSON(http.StatusAccepted, invoice)
 	}
 }
 
-func DeleteInvoice() gin.HandlerFunc {
+func DeleteInvoice(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var invoice models.Invoice
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		invoiceId := c.Param("invoice_id")
 
-		err := invoiceCollection.FindOne(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
+		invoice, err := s.Invoices.Get(ctx, invoiceId)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find invoice with that id"})
 			return
 		}
 
-		result := invoiceCollection.FindOneAndDelete(ctx, bson.M{"invoice_id": invoiceId}).Decode(&invoice)
-		c.JSON(http.StatusAccepted, result)
+		// Paid invoices are part of the books; void or refund them instead.
+		if len(invoice.Payments) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "An invoice with payments cannot be deleted"})
+			return
+		}
+
+		if err := s.Invoices.Delete(ctx, invoiceId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete invoice!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, invoice)
 	}
 }
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+)
+
+const kitchenHeartbeat = 15 * time.Second
+
+// GetKitchenTickets returns every open ticket grouped by station.
+func GetKitchenTickets(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		tickets, err := openTickets(ctx, s, c.Query("station"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching kitchen tickets!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, tickets)
+	}
+}
+
+// KitchenStream pushes kitchen events over Server-Sent Events. The first
+// event is a "snapshot" of open tickets so a screen that reconnects can
+// resync; after that every change arrives as its own event.
+func KitchenStream(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		station := c.Query("station")
+		events, unsubscribe := hub.Subscribe(station)
+		defer unsubscribe()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		tickets, err := openTickets(ctx, s, station)
+		cancel()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching kitchen tickets!"})
+			return
+		}
+
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.SSEvent("snapshot", tickets)
+		c.Writer.Flush()
+
+		heartbeat := time.NewTicker(kitchenHeartbeat)
+		defer heartbeat.Stop()
+
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return false
+				}
+				c.SSEvent(ev.Type, ev)
+				return true
+			case at := <-heartbeat.C:
+				c.SSEvent("heartbeat", at)
+				return true
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	}
+}
+
+// BumpOrderItem is the kitchen screen acknowledging an item, which moves it
+// to its next status.
+func BumpOrderItem(s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		orderItemId := c.Param("order_item_id")
+
+		orderItem, err := s.OrderItems.Get(ctx, orderItemId)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find order item with that id"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := orderItem.Bump(now); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		depleted := orderItem.Stock_Depleted
+		if orderItem.CurrentStatus() == models.ItemServed {
+			if err := takeStock(ctx, s, inv, &orderItem, inventory.DepleteServed); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not take the item's ingredients out of stock!"})
+				return
+			}
+		}
+
+		if err := s.OrderItems.Update(ctx, orderItem); err != nil {
+			if !depleted {
+				returnStock(ctx, s, inv, &orderItem)
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update order item!"})
+			return
+		}
+
+		publishTicket(ctx, s, hub, kitchen.ItemBumped, orderItem)
+		c.JSON(http.StatusAccepted, orderItem)
+	}
+}
+
+func openTickets(ctx context.Context, s *store.Store, station string) (map[string][]kitchen.Ticket, error) {
+	orderItems, err := s.OrderItems.ListByStatus(ctx, models.ItemQueued, models.ItemCooking, models.ItemReady)
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := map[string][]kitchen.Ticket{}
+	for _, orderItem := range orderItems {
+		ticket, err := kitchenTicket(ctx, s, orderItem)
+		if err != nil {
+			return nil, err
+		}
+
+		if station != "" && ticket.Station != station {
+			continue
+		}
+		tickets[ticket.Station] = append(tickets[ticket.Station], ticket)
+	}
+
+	return tickets, nil
+}
+
+func kitchenTicket(ctx context.Context, s *store.Store, orderItem models.OrderItem) (kitchen.Ticket, error) {
+	ticket := kitchen.Ticket{
+		Order_Item_ID: orderItem.Order_Item_ID,
+		Quantity:      orderItem.Quantity,
+		Modifiers:     []string{},
+		Allergies:     []string{},
+		Notes:         []string{},
+		Allergy_Alert: models.AllergyAlert(orderItem.Allergen_Warnings),
+		Status:        orderItem.CurrentStatus(),
+		Station:       models.DefaultStation,
+		Created_At:    orderItem.Created_At,
+	}
+
+	for _, modifier := range orderItem.Modifiers {
+		ticket.Modifiers = append(ticket.Modifiers, modifier.Name)
+	}
+
+	if orderItem.Food_ID != nil {
+		ticket.Food_ID = *orderItem.Food_ID
+		food, err := s.Foods.Get(ctx, *orderItem.Food_ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return ticket, err
+		}
+
+		if food.Name != nil {
+			ticket.Food_Name = *food.Name
+		}
+		ticket.Station = food.KitchenStation()
+	}
+
+	if orderItem.Order_ID != nil {
+		ticket.Order_ID = *orderItem.Order_ID
+		order, err := s.Orders.Get(ctx, *orderItem.Order_ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return ticket, err
+		}
+
+		ticket.Allergies = order.DeclaredAllergens(orderItem.Seat)
+
+		if order.Table_ID != nil {
+			ticket.Table_ID = *order.Table_ID
+			table, err := s.Tables.Get(ctx, *order.Table_ID)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				return ticket, err
+			}
+			ticket.Table_Number = table.Table_Number
+		}
+	}
+
+	notes, err := s.Notes.Attached(ctx,
+		models.NoteSubject{Type: models.NoteOrderItem, ID: orderItem.Order_Item_ID},
+		models.NoteSubject{Type: models.NoteOrder, ID: ticket.Order_ID})
+	if err != nil {
+		return ticket, err
+	}
+
+	for _, note := range notes {
+		ticket.Notes = append(ticket.Notes, note.NoteLine())
+	}
+
+	return ticket, nil
+}
+
+// publishTicket is best effort: a kitchen screen missing an event must
+// never fail the request that caused it.
+func publishTicket(ctx context.Context, s *store.Store, hub *kitchen.Hub, eventType string, orderItem models.OrderItem) {
+	ticket, err := kitchenTicket(ctx, s, orderItem)
+	if err != nil {
+		return
+	}
+
+	hub.Publish(kitchen.Event{Type: eventType, Station: ticket.Station, Ticket: ticket})
+}
+
+// cancelOrderItems marks every unserved item on an order as cancelled and
+// tells the kitchen to drop them. Stock taken for them goes back.
+func cancelOrderItems(ctx context.Context, s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory, orderId string, at time.Time) error {
+	orderItems, err := s.OrderItems.ListByOrder(ctx, orderId)
+	if err != nil {
+		return err
+	}
+
+	for _, orderItem := range orderItems {
+		switch orderItem.CurrentStatus() {
+		case models.ItemServed, models.ItemCancelled:
+			continue
+		}
+
+		if err := returnStock(ctx, s, inv, &orderItem); err != nil {
+			return err
+		}
+
+		status := models.ItemCancelled
+		orderItem.Status = &status
+		orderItem.Updated_At = at
+		if err := s.OrderItems.Update(ctx, orderItem); err != nil {
+			return err
+		}
+
+		publishTicket(ctx, s, hub, kitchen.ItemCancelled, orderItem)
+	}
+
+	return nil
+}
@@ -2,41 +2,32 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jamesconfy/restaurant-management/database"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/jamesconfy/restaurant-management/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var menuCollection *mongo.Collection = database.OpenCollection(database.Client, "menu")
-
-func GetMenus() gin.HandlerFunc {
+func GetMenus(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var allMenus []bson.M
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		result, err := menuCollection.Find(context.TODO(), bson.M{}) //.Decode(&foods)
+		allMenus, err := s.Menus.List(ctx)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing all the menus!"})
 			return
 		}
 
-		if err = result.All(ctx, &allMenus); err != nil {
-			log.Fatal(err.Error())
-		}
 		c.JSON(http.StatusOK, allMenus)
 	}
 }
 
-func CreateMenu() gin.HandlerFunc {
+func CreateMenu(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var menu models.Menu
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
@@ -53,30 +44,32 @@ func CreateMenu() gin.HandlerFunc {
 			return
 		}
 
+		if err := checkMenuTimes(menu); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
 		menu.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		menu.ID = primitive.NewObjectID()
 		menu.Menu_ID = menu.ID.Hex()
 
-		result, insertEr := menuCollection.InsertOne(ctx, &menu)
-		if insertEr != nil {
+		if err := s.Menus.Create(ctx, menu); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Menu was not created!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
+		c.JSON(http.StatusAccepted, menu)
 	}
 }
 
-func GetMenu() gin.HandlerFunc {
+func GetMenu(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var menu models.Menu
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		menuId := c.Param("menu_id")
 
-		err := menuCollection.FindOne(ctx, bson.M{"menu_id": menuId}).Decode(&menu)
-
+		menu, err := s.Menus.Get(ctx, menuId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot find that menu"})
 			return
@@ -85,76 +78,159 @@ func GetMenu() gin.HandlerFunc {
 	}
 }
 
-func UpdateMenu() gin.HandlerFunc {
+func UpdateMenu(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var menu models.Menu
-		var updateObj primitive.D
+		var input models.Menu
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		if err := c.BindJSON(&menu); err != nil {
+		if err := c.BindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		menuId := c.Param("menu_id")
-		filter := bson.M{"menu_id": menuId}
+		menu, err := s.Menus.Get(ctx, menuId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find menu with that id"})
+			return
+		}
 
-		if menu.Start_Date != nil && menu.End_Date != nil {
-			if !inTimeSpan(*menu.Start_Date, *menu.End_Date, time.Now()) {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Kindly provide an acceptable time format"})
-				return
-			}
+		if input.Start_Date != nil {
+			menu.Start_Date = input.Start_Date
+		}
 
-			updateObj = append(updateObj, bson.E{Key: "start_date", Value: menu.Start_Date})
-			updateObj = append(updateObj, bson.E{Key: "end_date", Value: menu.End_Date})
+		if input.End_Date != nil {
+			menu.End_Date = input.End_Date
 		}
 
-		if menu.Name != "" {
-			updateObj = append(updateObj, bson.E{Key: "name", Value: menu.Name})
+		if input.Schedule != nil {
+			menu.Schedule = input.Schedule
 		}
 
-		if menu.Category != "" {
-			updateObj = append(updateObj, bson.E{Key: "category", Value: menu.Category})
+		if input.Name != "" {
+			menu.Name = input.Name
 		}
 
-		updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updated_at})
+		if input.Category != "" {
+			menu.Category = input.Category
+		}
 
-		// menuCollection.UpdateByID(ctx, menuId, updateObj)
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		if err := validate.Struct(menu); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
-		result, err := menuCollection.UpdateOne(ctx, filter, bson.D{{Key: "set", Value: updateObj}}, &opt)
-		if err != nil {
+		if err := checkMenuTimes(menu); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		menu.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Menus.Update(ctx, menu); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update menu!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
+		c.JSON(http.StatusAccepted, menu)
 	}
 }
 
-func DeleteMenu() gin.HandlerFunc {
+func DeleteMenu(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var menu models.Menu
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		menuId := c.Param("menu_id")
 
-		err := menuCollection.FindOne(ctx, bson.M{"food_id": menuId}).Decode(&menu)
+		menu, err := s.Menus.Get(ctx, menuId)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find menu with that id"})
 			return
 		}
 
-		result := menuCollection.FindOneAndDelete(ctx, bson.M{"food_id": menuId}).Decode(&menu)
-		c.JSON(http.StatusAccepted, result)
+		if err := s.Menus.Delete(ctx, menuId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete menu!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, menu)
+	}
+}
+
+// GetActiveMenus lists the menus being served at ?at=, an RFC 3339 time,
+// or now.
+func GetActiveMenus(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		at := time.Now()
+		if c.Query("at") != "" {
+			var err error
+			if at, err = time.Parse(time.RFC3339, c.Query("at")); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC 3339 time"})
+				return
+			}
+		}
+
+		allMenus, err := s.Menus.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing all the menus!"})
+			return
+		}
+
+		active := []models.Menu{}
+		for _, menu := range allMenus {
+			if menu.ActiveAt(at) {
+				active = append(active, menu)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"at": at, "menus": active})
 	}
 }
 
-func inTimeSpan(start, end, check time.Time) bool {
-	return start.After(check) && end.After(start)
+// checkMenuTimes makes sure the menu's dates run forwards and its schedule
+// can be read.
+func checkMenuTimes(menu models.Menu) error {
+	if menu.Start_Date != nil && menu.End_Date != nil && !menu.End_Date.After(*menu.Start_Date) {
+		return errors.New("end_date must be after start_date")
+	}
+
+	if menu.Schedule != nil {
+		return menu.Schedule.Validate()
+	}
+
+	return nil
+}
+
+// menuServing writes a 409 and returns false when food's menu is not being
+// served at the given time. Menus already loaded are kept in menus.
+func menuServing(ctx context.Context, s *store.Store, c *gin.Context, menus map[string]models.Menu, food models.Food, at time.Time) bool {
+	if food.Menu_ID == nil {
+		return true
+	}
+
+	menu, ok := menus[*food.Menu_ID]
+	if !ok {
+		var err error
+		menu, err = s.Menus.Get(ctx, *food.Menu_ID)
+		if errors.Is(err, store.ErrNotFound) {
+			return true
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the menu for " + *food.Name})
+			return false
+		}
+		menus[*food.Menu_ID] = menu
+	}
+
+	if !menu.ActiveAt(at) {
+		c.JSON(http.StatusConflict, gin.H{"error": *food.Name + " is on the " + menu.Name + " menu, which is not being served now"})
+		return false
+	}
+
+	return true
 }
@@ -1 +1,284 @@
 package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetNotes lists notes, pinned first. ?subject_type= and ?subject_id= pick
+// out the notes on one thing, ?q= searches titles and text, ?author_id= and
+// ?pinned= narrow further.
+func GetNotes(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		recordPerPage, err := strconv.Atoi(c.Query("recordPerPage"))
+		if err != nil || recordPerPage < 1 {
+			recordPerPage = 20
+		}
+
+		page, err := strconv.Atoi(c.Query("page"))
+		if err != nil || page < 1 {
+			page = 1
+		}
+
+		startIndex, err := strconv.Atoi(c.Query("startIndex"))
+		if err != nil || startIndex < 1 {
+			startIndex = (page - 1) * recordPerPage
+		}
+
+		filter := store.NoteFilter{
+			Subject_Type: c.Query("subject_type"),
+			Subject_ID:   c.Query("subject_id"),
+			Author_ID:    c.Query("author_id"),
+			Text:         strings.TrimSpace(c.Query("q")),
+		}
+
+		if filter.Subject_Type != "" {
+			if err := validate.Var(filter.Subject_Type, "oneof=ORDER ORDER_ITEM TABLE INVOICE GUEST"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": filter.Subject_Type + " is not something notes are attached to"})
+				return
+			}
+		}
+
+		if c.Query("pinned") != "" {
+			pinned, err := strconv.ParseBool(c.Query("pinned"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "pinned must be true or false"})
+				return
+			}
+			filter.Pinned = &pinned
+		}
+
+		notes, total, err := s.Notes.List(ctx, filter, startIndex, recordPerPage)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the notes!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"total_count": total, "notes": notes})
+	}
+}
+
+// CreateNote attaches a note to an order, order item, table, invoice or
+// guest. The author is whoever is signed in.
+func CreateNote(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var note models.Note
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&note); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(note); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !noteSubjectExists(ctx, s, c, note.Subject()) {
+			return
+		}
+
+		note.Author_ID = c.GetString("userId")
+		note.Author_Name = strings.TrimSpace(c.GetString("first_name") + " " + c.GetString("last_name"))
+		note.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		note.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		note.ID = primitive.NewObjectID()
+		note.Note_ID = note.ID.Hex()
+
+		if err := s.Notes.Create(ctx, note); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Note was not created!"})
+			return
+		}
+
+		noteTickets(ctx, s, hub, note)
+		c.JSON(http.StatusAccepted, note)
+	}
+}
+
+func GetNote(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		note, err := s.Notes.Get(ctx, c.Param("note_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that note"})
+			return
+		}
+
+		c.JSON(http.StatusOK, note)
+	}
+}
+
+// UpdateNote changes a note's title or text. Notes stay on what they were
+// attached to.
+func UpdateNote(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.Note
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		note, ok := ownNote(ctx, s, c)
+		if !ok {
+			return
+		}
+
+		if input.Subject_Type != "" && input.Subject_Type != note.Subject_Type || input.Subject_ID != "" && input.Subject_ID != note.Subject_ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A note cannot be moved to something else"})
+			return
+		}
+
+		if input.Title != "" {
+			note.Title = input.Title
+		}
+
+		if input.Text != "" {
+			note.Text = input.Text
+		}
+
+		note.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Notes.Update(ctx, note); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update note!"})
+			return
+		}
+
+		noteTickets(ctx, s, hub, note)
+		c.JSON(http.StatusAccepted, note)
+	}
+}
+
+// PinNote pins a note to the top of its subject's notes, or unpins it.
+// Anyone who can write notes can pin them.
+func PinNote(s *store.Store, hub *kitchen.Hub, pinned bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		note, err := s.Notes.Get(ctx, c.Param("note_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that note"})
+			return
+		}
+
+		note.Pinned = pinned
+		note.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Notes.Update(ctx, note); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update note!"})
+			return
+		}
+
+		noteTickets(ctx, s, hub, note)
+		c.JSON(http.StatusAccepted, note)
+	}
+}
+
+func DeleteNote(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		note, ok := ownNote(ctx, s, c)
+		if !ok {
+			return
+		}
+
+		if err := s.Notes.Delete(ctx, note.Note_ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete note!"})
+			return
+		}
+
+		noteTickets(ctx, s, hub, note)
+		c.JSON(http.StatusAccepted, note)
+	}
+}
+
+// ownNote loads the note in the path. Only its author, or someone who can
+// manage notes, may change it; anyone else gets a 403.
+func ownNote(ctx context.Context, s *store.Store, c *gin.Context) (models.Note, bool) {
+	note, err := s.Notes.Get(ctx, c.Param("note_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that note"})
+		return note, false
+	}
+
+	if note.Author_ID != c.GetString("userId") && !middleware.HasPermission(c.GetString("role"), middleware.ManageNotes) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can change that note"})
+		return note, false
+	}
+
+	return note, true
+}
+
+// noteSubjectExists writes a 400 and returns false when there is nothing
+// for a note to be attached to.
+func noteSubjectExists(ctx context.Context, s *store.Store, c *gin.Context, subject models.NoteSubject) bool {
+	var err error
+	switch subject.Type {
+	case models.NoteOrder:
+		_, err = s.Orders.Get(ctx, subject.ID)
+	case models.NoteOrderItem:
+		_, err = s.OrderItems.Get(ctx, subject.ID)
+	case models.NoteTable:
+		_, err = s.Tables.Get(ctx, subject.ID)
+	case models.NoteInvoice:
+		_, err = s.Invoices.Get(ctx, subject.ID)
+	case models.NoteGuest:
+		_, err = s.Users.Get(ctx, subject.ID)
+	}
+
+	if errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find the " + strings.ToLower(strings.ReplaceAll(subject.Type, "_", " ")) + " to attach the note to"})
+		return false
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check what the note is attached to"})
+		return false
+	}
+
+	return true
+}
+
+// noteTickets updates the kitchen's tickets for the items a note shows on.
+// Like publishTicket it is best effort.
+func noteTickets(ctx context.Context, s *store.Store, hub *kitchen.Hub, note models.Note) {
+	var orderItems []models.OrderItem
+	switch note.Subject_Type {
+	case models.NoteOrderItem:
+		orderItem, err := s.OrderItems.Get(ctx, note.Subject_ID)
+		if err != nil {
+			return
+		}
+		orderItems = []models.OrderItem{orderItem}
+	case models.NoteOrder:
+		var err error
+		if orderItems, err = s.OrderItems.ListByOrder(ctx, note.Subject_ID); err != nil {
+			return
+		}
+	}
+
+	republishTickets(ctx, s, hub, orderItems)
+}
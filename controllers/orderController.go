@@ -2,44 +2,37 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jamesconfy/restaurant-management/database"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var orderCollection *mongo.Collection = database.OpenCollection(database.Client, "order")
-
-func GetOrders() gin.HandlerFunc {
+func GetOrders(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var allOrders []bson.M
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		result, err := orderCollection.Find(context.TODO(), bson.M{})
+		allOrders, err := s.Orders.List(ctx)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching orders!"})
 			return
 		}
 
-		if err = result.All(ctx, &allOrders); err != nil {
-			log.Fatal(err.Error())
-		}
 		c.JSON(http.StatusOK, allOrders)
 	}
 }
 
-func CreateOrder() gin.HandlerFunc {
+func CreateOrder(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var order models.Order
-		var table models.Table
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
@@ -54,36 +47,78 @@ func CreateOrder() gin.HandlerFunc {
 			return
 		}
 
-		err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_ID}).Decode(&table)
+		table, err := s.Tables.Get(ctx, *order.Table_ID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Table was not found"})
 			return
 		}
 
-		order.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		order.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		order.ID = primitive.NewObjectID()
-		order.Order_ID = order.ID.Hex()
+		if order.Waitlist_Entry_ID != nil {
+			entry, err := s.Waitlist.Get(ctx, *order.Waitlist_Entry_ID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find that waitlist entry"})
+				return
+			}
+
+			if !entry.Waiting() {
+				c.JSON(http.StatusConflict, gin.H{"error": "A party that has " + entry.Status + " cannot be seated"})
+				return
+			}
+
+			if *entry.Party_Size > table.Seats() {
+				c.JSON(http.StatusConflict, gin.H{"error": "The table does not seat the party"})
+				return
+			}
+		}
+
+		placeOrder(&order, c.GetString("userId"))
+
+		// The order is only placed if the party is seated with it, and
+		// seating only succeeds while the party is still waiting, so two
+		// orders cannot both seat the same party.
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			if order.Waitlist_Entry_ID != nil {
+				entry, err := s.Waitlist.Get(ctx, *order.Waitlist_Entry_ID)
+				if err != nil {
+					return err
+				}
+
+				if *entry.Party_Size > table.Seats() {
+					return orderChanged("The table does not seat the party")
+				}
+			}
+
+			if err := s.Orders.Create(ctx, order); err != nil {
+				return err
+			}
+
+			if order.Waitlist_Entry_ID != nil {
+				return seatWaitlistEntry(ctx, s, *order.Waitlist_Entry_ID, order)
+			}
+			return nil
+		})
+		if err != nil {
+			var changed orderChanged
+			if errors.As(err, &changed) {
+				c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+				return
+			}
 
-		result, insertEr := orderCollection.InsertOne(ctx, &order)
-		if insertEr != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order was not created!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
+		c.JSON(http.StatusAccepted, order)
 	}
 }
 
-func GetOrder() gin.HandlerFunc {
+func GetOrder(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var order models.Order
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		orderId := c.Param("order_id")
 
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
-
+		order, err := s.Orders.Get(ctx, orderId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot find that order"})
 			return
@@ -92,76 +127,250 @@ func GetOrder() gin.HandlerFunc {
 	}
 }
 
-func UpdateOrder() gin.HandlerFunc {
+// UpdateOrder changes an order's table and allergies. Its status moves
+// through TransitionOrder and its promotions through ApplyPromotion.
+func UpdateOrder(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var order models.Order
-		var table models.Table
-		var updateObj primitive.D
+		var input struct {
+			Table_ID  *string                     `json:"table_id"`
+			Allergies []models.AllergyDeclaration `json:"allergies"`
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		if err := c.BindJSON(&order); err != nil {
+		if err := c.BindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		orderId := c.Param("order_id")
-		filter := bson.M{"order_id": orderId}
+		order, err := s.Orders.Get(ctx, orderId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find order with that id"})
+			return
+		}
 
-		if order.Table_ID != nil {
-			err := tableCollection.FindOne(ctx, bson.M{"table_id": order.Table_ID}).Decode(&table)
-			if err != nil {
+		if input.Table_ID != nil {
+			if _, err := s.Tables.Get(ctx, *input.Table_ID); err != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Table does not exist"})
 				return
 			}
+		}
 
-			updateObj = append(updateObj, bson.E{Key: "menu_id", Value: order.Table_ID})
+		if input.Allergies != nil {
+			order.Allergies = input.Allergies
+			if err := validate.Struct(order); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 		}
 
-		updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updated_at})
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			// Read the order again so a transition, promotion or merge
+			// made since it was read above is not written over.
+			var err error
+			if order, err = s.Orders.Get(ctx, orderId); err != nil {
+				return err
+			}
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
-		}
+			if !order.Open() {
+				return orderChanged("Order " + orderId + " is " + order.CurrentStatus())
+			}
+
+			if input.Table_ID != nil {
+				order.Table_ID = input.Table_ID
+			}
+			if input.Allergies != nil {
+				order.Allergies = input.Allergies
+			}
 
-		result, err := orderCollection.UpdateOne(ctx, filter, bson.D{{Key: "set", Value: updateObj}}, &opt)
+			order.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return s.Orders.Update(ctx, order)
+		})
 		if err != nil {
+			var changed orderChanged
+			if errors.As(err, &changed) {
+				c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+				return
+			}
+
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update order!"})
 			return
 		}
-		defer cancel()
-		c.JSON(http.StatusAccepted, result)
+
+		c.JSON(http.StatusAccepted, order)
 	}
 }
 
-func DeleteOrder() gin.HandlerFunc {
+// TransitionOrder moves an order along its lifecycle. Cancelling or voiding
+// takes every item off the order, so like any other void it needs a
+// manager's approval, is recorded as an adjustment and is refused once the
+// bill has been paid against.
+func TransitionOrder(s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory, engine *pricing.Engine) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var order models.Order
+		var input struct {
+			Status      *string         `json:"status" validate:"required,eq=PLACED|eq=ACCEPTED|eq=PREPARING|eq=READY|eq=SERVED|eq=CLOSED|eq=CANCELLED|eq=VOIDED"`
+			Reason      string          `json:"reason"`
+			Reason_Code string          `json:"reason_code"`
+			Approval    ManagerApproval `json:"approval"`
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
-		orderId := c.Param("order_id")
 
-		err := orderCollection.FindOne(ctx, bson.M{"order_id": orderId}).Decode(&order)
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		orderId := c.Param("order_id")
+		order, err := s.Orders.Get(ctx, orderId)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find order with that id"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find order with that id"})
 			return
 		}
 
-		result := orderCollection.FindOneAndDelete(ctx, bson.M{"order_id": orderId}).Decode(&order)
-		c.JSON(http.StatusAccepted, result)
+		voiding := *input.Status == models.OrderCancelled || *input.Status == models.OrderVoided
+		var adjustment models.Adjustment
+		var bills map[string]*models.Invoice
+		if voiding {
+			request := AdjustmentRequest{Reason_Code: input.Reason_Code, Reason: input.Reason, Approval: input.Approval}
+			var ok bool
+			if adjustment, ok = startAdjustment(ctx, s, c, models.AdjustmentVoid, request); !ok {
+				return
+			}
+
+			if bills, ok = unpaidBills(ctx, s, c, order.Order_ID); !ok {
+				return
+			}
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := order.Transition(*input.Status, c.GetString("userId"), input.Reason, now); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !voiding {
+			err := s.WithTransaction(ctx, func(ctx context.Context) error {
+				// Read the order again so the transition is made from
+				// its current status and nothing changed since is lost.
+				var err error
+				if order, err = s.Orders.Get(ctx, orderId); err != nil {
+					return err
+				}
+
+				if err := order.Transition(*input.Status, c.GetString("userId"), input.Reason, now); err != nil {
+					return orderChanged(err.Error())
+				}
+
+				return s.Orders.Update(ctx, order)
+			})
+			if err != nil {
+				var changed orderChanged
+				if errors.As(err, &changed) {
+					c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+					return
+				}
+
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update order!"})
+				return
+			}
+		} else {
+			summary, err := ItemsByOrder(ctx, s, engine, order.Order_ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while pricing the order"})
+				return
+			}
+
+			adjustment.Order_ID = order.Order_ID
+			adjustment.Amount = summary.Payment_Due
+			if invoice := bills[order.Order_ID]; invoice != nil {
+				adjustment.Invoice_ID = invoice.Invoice_ID
+			}
+
+			if !saveAdjustment(ctx, s, c, input.Approval, adjustment, func(ctx context.Context) error {
+				// Read the order and its bill again, so the void is
+				// made from the order's current status, for what is on
+				// it now, and not over a payment taken meanwhile.
+				orders, bills, err := reloadOrders(ctx, s, orderId)
+				if err != nil {
+					return err
+				}
+
+				order = orders[0]
+				if err := order.Transition(*input.Status, c.GetString("userId"), input.Reason, now); err != nil {
+					return orderChanged(err.Error())
+				}
+
+				summary, err := ItemsByOrder(ctx, s, engine, orderId)
+				if err != nil {
+					return err
+				}
+				if summary.Payment_Due != adjustment.Amount {
+					return orderChanged("Order " + orderId + " changed while it was being voided")
+				}
+
+				invoice := bills[orderId]
+				if invoice != nil {
+					status := models.PaymentVoided
+					invoice.Payment_Status = &status
+					invoice.Updated_At = now
+				}
+
+				if err := s.Orders.Update(ctx, order); err != nil {
+					return err
+				}
+				if err := cancelOrderItems(ctx, s, hub, inv, order.Order_ID, now); err != nil {
+					return err
+				}
+				if invoice != nil {
+					return s.Invoices.Update(ctx, *invoice)
+				}
+				return nil
+			}) {
+				return
+			}
+		}
+
+		if !order.Open() && order.Table_ID != nil {
+			releaseTable(ctx, s, *order.Table_ID, now)
+		}
+
+		c.JSON(http.StatusAccepted, order)
 	}
 }
 
-func OrderItemCreator(order models.Order) string {
-	ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
-	defer cancel()
+// placeOrder stamps a new order with its ids and its initial PLACED status.
+// Whoever places the order looks after it unless a server was given.
+// Promotions are only ever added through ApplyPromotion.
+func placeOrder(order *models.Order, by string) {
+	if order.Server_ID == nil {
+		order.Server_ID = &by
+	}
+	order.Merged_Into = nil
+	order.Promotions = nil
+
 	order.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 	order.ID = primitive.NewObjectID()
 	order.Order_ID = order.ID.Hex()
 
-	orderCollection.InsertOne(ctx, order)
-	return order.Order_ID
+	status := models.OrderPlaced
+	order.Status = &status
+	order.Status_History = []models.OrderTransition{{To: models.OrderPlaced, At: order.Created_At, By: by}}
+}
+
+func OrderItemCreator(ctx context.Context, s *store.Store, order models.Order, by string) (string, error) {
+	placeOrder(&order, by)
+
+	if err := s.Orders.Create(ctx, order); err != nil {
+		return "", err
+	}
+
+	return order.Order_ID, nil
 }
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TestCreateOrderSeatsWaitlistOnce checks that orders placed at once for
+// the same waiting party do not all seat it.
+func TestCreateOrderSeatsWaitlistOnce(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	env.router.POST("/orders", CreateOrder(env.s))
+
+	name, phone, size := "Ada", "+15550100", 2
+	entry := models.WaitlistEntry{ID: primitive.NewObjectID(), Party_Name: &name, Party_Size: &size, Phone: &phone, Status: models.WaitlistWaiting}
+	entry.Entry_ID = entry.ID.Hex()
+	if err := env.s.Waitlist.Create(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	codes := env.race(t, 5, "POST", "/orders", func(i int) interface{} {
+		return models.Order{Order_Date: time.Now(), Table_ID: &env.table.Table_ID, Waitlist_Entry_ID: &entry.Entry_ID}
+	})
+	if codes[http.StatusAccepted] != 1 || codes[http.StatusConflict] != 4 {
+		t.Errorf("got %v, want one order placed and the rest refused", codes)
+	}
+
+	orders, err := env.s.Orders.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The test environment starts with an order of its own.
+	if len(orders) != 2 {
+		t.Errorf("got %d orders, want only the one that seated the party added", len(orders))
+	}
+
+	seated, err := env.s.Waitlist.Get(ctx, entry.Entry_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seated.Status != models.WaitlistSeated {
+		t.Errorf("the party is %s, want %s", seated.Status, models.WaitlistSeated)
+	}
+}
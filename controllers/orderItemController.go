@@ -2,46 +2,69 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jamesconfy/restaurant-management/database"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var orderItemCollection *mongo.Collection = database.OpenCollection(database.Client, "orderItem")
-
+// OrderItemPack starts a new order at Table_ID, or adds to an open order
+// when Order_ID is set. Allergies are added to the order's declarations
+// before the items are checked against them.
 type OrderItemPack struct {
 	Table_ID    *string
+	Order_ID    *string
+	Allergies   []models.AllergyDeclaration
 	Order_Items []models.OrderItem
 }
 
-func GetOrderItems() gin.HandlerFunc {
+type OrderItemView struct {
+	Order_Item_ID string                    `json:"order_item_id"`
+	Amount        models.Money              `json:"amount"`
+	Food_Name     *string                   `json:"food_name"`
+	Food_Image    *string                   `json:"food_image"`
+	Table_Number  *int                      `json:"table_number"`
+	Table_ID      string                    `json:"table_id"`
+	Order_ID      string                    `json:"order_id"`
+	Price         *models.Money             `json:"price"`
+	Quantity      *int                      `json:"quantity"`
+	Status        string                    `json:"status"`
+	Adjustment    *models.ItemAdjustment    `json:"adjustment,omitempty"`
+	Modifiers     []models.SelectedModifier `json:"modifiers"`
+	Price_Rule    *models.AppliedPriceRule  `json:"price_rule,omitempty"`
+}
+
+type OrderItemsSummary struct {
+	Payment_Due  models.Money      `json:"payment_due"`
+	Total_Count  int               `json:"total_count"`
+	Table_Number *int              `json:"table_number"`
+	Order_Items  []OrderItemView   `json:"order_items"`
+	Breakdown    pricing.Breakdown `json:"breakdown"`
+}
+
+func GetOrderItems(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var allOrderItems []bson.M
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
-		result, err := orderCollection.Find(context.TODO(), bson.M{})
 
+		allOrderItems, err := s.OrderItems.List(ctx)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching order items!"})
 			return
 		}
 
-		if err = result.All(ctx, &allOrderItems); err != nil {
-			log.Fatal(err.Error())
-		}
 		c.JSON(http.StatusOK, allOrderItems)
 	}
 }
 
-func CreateOrderItem() gin.HandlerFunc {
+func CreateOrderItem(s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var order models.Order
 		var orderItemPack OrderItemPack
@@ -50,17 +73,55 @@ func CreateOrderItem() gin.HandlerFunc {
 
 		if err := c.BindJSON(&orderItemPack); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
 
 		order.Order_Date, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		order.Table_ID = orderItemPack.Table_ID
 
-		orderItemsToBeInserted := []interface{}{}
-		orderId := OrderItemCreator(order)
+		if orderItemPack.Order_ID != nil {
+			existing, err := s.Orders.Get(ctx, *orderItemPack.Order_ID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Order was not found"})
+				return
+			}
+
+			if !existing.ItemsEditable() {
+				c.JSON(http.StatusConflict, gin.H{"error": "Items cannot be added once an order is " + existing.CurrentStatus()})
+				return
+			}
+			order = existing
+		} else {
+			// Check the table before anything is written, so a bad
+			// table_id cannot leave an order behind.
+			if orderItemPack.Table_ID == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "A new order needs a table_id"})
+				return
+			}
 
-		for _, orderItem := range orderItemPack.Order_Items {
-			orderItem.Order_ID = &orderId
+			if _, err := s.Tables.Get(ctx, *orderItemPack.Table_ID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Table was not found"})
+				return
+			}
+		}
 
+		for _, declaration := range orderItemPack.Allergies {
+			if err := validate.Struct(declaration); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		order.Allergies = append(order.Allergies, orderItemPack.Allergies...)
+
+		orderItemsToBeInserted := []models.OrderItem{}
+		blocked := []models.AllergyConflict{}
+		menus := map[string]models.Menu{}
+		rules, ok := loadPriceRules(ctx, s, c)
+		if !ok {
+			return
+		}
+
+		for _, orderItem := range orderItemPack.Order_Items {
 			validationErr := validate.Struct(orderItem)
 			if validationErr != nil {
 				c.JSON(http.StatusBadRequest, gin.H{"error": validationErr.Error()})
@@ -71,31 +132,124 @@ func CreateOrderItem() gin.HandlerFunc {
 			orderItem.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 			orderItem.Order_Item_ID = orderItem.ID.Hex()
-			num := toFixed(*orderItem.Unit_Price, 2)
-			orderItem.Unit_Price = &num
+			status := models.ItemQueued
+			orderItem.Status = &status
+
+			// Items are always charged at the food's price when ordered, after
+			// any price rule in force, not at whatever the client sent.
+			food, err := s.Foods.Get(ctx, *orderItem.Food_ID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Food " + *orderItem.Food_ID + " was not found"})
+				return
+			}
+
+			if !foodAvailable(c, food) {
+				return
+			}
+
+			if !menuServing(ctx, s, c, menus, food, orderItem.Created_At) {
+				return
+			}
+			priceItem(&orderItem, rules, menus, food, orderItem.Created_At)
+
+			orderItem.Modifiers, err = food.SelectModifiers(orderItem.Modifiers)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": *food.Name + ": " + err.Error()})
+				return
+			}
+
+			conflicts := order.AllergyConflicts(orderItem, food)
+			var severe bool
+			if orderItem.Allergen_Warnings, severe = allergyWarnings(conflicts); severe {
+				blocked = append(blocked, conflicts...)
+			}
 			orderItemsToBeInserted = append(orderItemsToBeInserted, orderItem)
 		}
-		// order.Order_Date, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		insertedOrderItems, insertEr := orderItemCollection.InsertMany(ctx, orderItemsToBeInserted)
-		if insertEr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Order item was not created!"})
+
+		if len(blocked) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Items conflict with a severe allergy declared for the table", "conflicts": blocked})
+			return
+		}
+
+		// The order, its allergies, the items and the stock they take all
+		// go in together, so a failure part way leaves none of them.
+		var inserted []models.OrderItem
+		failure := "Order item was not created!"
+		err := s.WithTransaction(ctx, func(ctx context.Context) error {
+			inserted = append([]models.OrderItem{}, orderItemsToBeInserted...)
+
+			orderId := order.Order_ID
+			if orderItemPack.Order_ID == nil {
+				var err error
+				if orderId, err = OrderItemCreator(ctx, s, order, c.GetString("userId")); err != nil {
+					failure = "Order was not created!"
+					return err
+				}
+			} else {
+				// Read the order again so the items are not added to
+				// an order the kitchen has since finished, and the
+				// allergies are added to it as it is now.
+				current, err := s.Orders.Get(ctx, orderId)
+				if err != nil {
+					return err
+				}
+
+				if !current.ItemsEditable() {
+					return orderChanged("Items cannot be added once an order is " + current.CurrentStatus())
+				}
+
+				if len(orderItemPack.Allergies) > 0 {
+					current.Allergies = append(current.Allergies, orderItemPack.Allergies...)
+					current.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+					if err := s.Orders.Update(ctx, current); err != nil {
+						failure = "Could not record the allergies on the order!"
+						return err
+					}
+				}
+			}
+
+			for i := range inserted {
+				inserted[i].Order_ID = &orderId
+				if err := takeStock(ctx, s, inv, &inserted[i], inventory.DepleteOrdered); err != nil {
+					failure = "Could not take the item's ingredients out of stock!"
+					return err
+				}
+
+				if err := s.OrderItems.Create(ctx, inserted[i]); err != nil {
+					failure = "Order item was not created!"
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			var changed orderChanged
+			if errors.As(err, &changed) {
+				c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, insertedOrderItems)
+		orderItemsToBeInserted = inserted
+		for _, orderItem := range orderItemsToBeInserted {
+			publishTicket(ctx, s, hub, kitchen.ItemAdded, orderItem)
+		}
 
+		c.JSON(http.StatusAccepted, orderItemsToBeInserted)
 	}
 }
 
-func GetOrderItem() gin.HandlerFunc {
+func GetOrderItem(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var orderItem models.OrderItem
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		orderItemId := c.Param("order_item_id")
 
-		err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
-
+		orderItem, err := s.OrderItems.Get(ctx, orderItemId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot find that order item"})
 			return
@@ -104,75 +258,180 @@ func GetOrderItem() gin.HandlerFunc {
 	}
 }
 
-func UpdateOrderItem() gin.HandlerFunc {
+func UpdateOrderItem(s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var orderItem models.OrderItem
-		var updateObj primitive.D
+		var input models.OrderItem
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		if err := c.BindJSON(&orderItem); err != nil {
+		if err := c.BindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		orderItemId := c.Param("order_item_id")
-		filter := bson.M{"order_item_id": orderItemId}
-
-		if orderItem.Quantity != nil {
-			updateObj = append(updateObj, bson.E{Key: "quantity", Value: &orderItem.Quantity})
+		orderItem, err := s.OrderItems.Get(ctx, orderItemId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find order item with that id"})
+			return
 		}
 
-		if orderItem.Unit_Price != nil {
-			updateObj = append(updateObj, bson.E{Key: "unit_price", Value: &orderItem.Unit_Price})
+		if !orderItemEditable(ctx, s, orderItem, c) {
+			return
 		}
 
-		if orderItem.Food_ID != nil {
-			updateObj = append(updateObj, bson.E{Key: "food_id", Value: &orderItem.Food_ID})
+		if input.Quantity != nil {
+			if *input.Quantity < 1 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be at least 1"})
+				return
+			}
+
+			orderItem.Quantity = input.Quantity
 		}
 
-		updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updated_at})
+		// A new food or new choices are checked against the food's modifier
+		// groups; changing the food drops choices made for the old one.
+		if input.Food_ID != nil || input.Modifiers != nil {
+			foodId := *orderItem.Food_ID
+			if input.Food_ID != nil {
+				foodId = *input.Food_ID
+			}
+
+			food, err := s.Foods.Get(ctx, foodId)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Food was not found"})
+				return
+			}
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+			modifiers := input.Modifiers
+			if modifiers == nil && foodId == *orderItem.Food_ID {
+				modifiers = orderItem.Modifiers
+			}
+
+			orderItem.Modifiers, err = food.SelectModifiers(modifiers)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if orderItem.Order_ID != nil {
+				order, err := s.Orders.Get(ctx, *orderItem.Order_ID)
+				if err != nil && !errors.Is(err, store.ErrNotFound) {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the order for that item"})
+					return
+				}
+
+				conflicts := order.AllergyConflicts(orderItem, food)
+				var severe bool
+				if orderItem.Allergen_Warnings, severe = allergyWarnings(conflicts); severe {
+					c.JSON(http.StatusConflict, gin.H{"error": "The item conflicts with a severe allergy declared for the table", "conflicts": conflicts})
+					return
+				}
+			}
+
+			if foodId != *orderItem.Food_ID {
+				if !foodAvailable(c, food) {
+					return
+				}
+
+				menus := map[string]models.Menu{}
+				if !menuServing(ctx, s, c, menus, food, time.Now()) {
+					return
+				}
+
+				rules, ok := loadPriceRules(ctx, s, c)
+				if !ok {
+					return
+				}
+
+				orderItem.Food_ID = &foodId
+				priceItem(&orderItem, rules, menus, food, time.Now())
+			}
 		}
 
-		result, err := orderCollection.UpdateOne(ctx, filter, bson.D{{Key: "set", Value: updateObj}}, &opt)
-		if err != nil {
+		if input.Quantity != nil || input.Food_ID != nil {
+			if err := retakeStock(ctx, s, inv, &orderItem); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the item's ingredients in stock!"})
+				return
+			}
+		}
+
+		orderItem.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.OrderItems.Update(ctx, orderItem); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update order item!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
-
+		publishTicket(ctx, s, hub, kitchen.ItemModified, orderItem)
+		c.JSON(http.StatusAccepted, orderItem)
 	}
 }
 
-func DeleteOrderItem() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		var orderItem models.OrderItem
-		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
-		defer cancel()
-		orderItemId := c.Param("order_item_id")
+// foodAvailable writes a 409 and returns false when the food is 86'd.
+func foodAvailable(c *gin.Context, food models.Food) bool {
+	if food.IsAvailable() {
+		return true
+	}
 
-		err := orderItemCollection.FindOne(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find order item with that id"})
-			return
+	reason := models.UnavailableManual
+	if food.Unavailable_Reason != nil {
+		reason = *food.Unavailable_Reason
+	}
+
+	c.JSON(http.StatusConflict, gin.H{"error": *food.Name + " is not available", "food_id": food.Food_ID, "reason": reason})
+	return false
+}
+
+// allergyWarnings lists the allergens an item conflicts with and reports
+// whether any of the declarations is severe.
+func allergyWarnings(conflicts []models.AllergyConflict) ([]string, bool) {
+	warnings := []string{}
+	severe := false
+	seen := map[string]bool{}
+	for _, conflict := range conflicts {
+		severe = severe || conflict.Severe
+		if !seen[conflict.Allergen] {
+			seen[conflict.Allergen] = true
+			warnings = append(warnings, conflict.Allergen)
 		}
+	}
+
+	return warnings, severe
+}
+
+// orderItemEditable writes a 409 and returns false when the item's order has
+// moved past PREPARING.
+func orderItemEditable(ctx context.Context, s *store.Store, orderItem models.OrderItem, c *gin.Context) bool {
+	if orderItem.Order_ID == nil {
+		return true
+	}
+
+	order, err := s.Orders.Get(ctx, *orderItem.Order_ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return true
+	}
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the order for that item"})
+		return false
+	}
 
-		result := orderItemCollection.FindOneAndDelete(ctx, bson.M{"order_item_id": orderItemId}).Decode(&orderItem)
-		c.JSON(http.StatusAccepted, result)
+	if !order.ItemsEditable() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Items cannot be changed once an order is " + order.CurrentStatus()})
+		return false
 	}
+
+	return true
 }
 
-func GetOrderItemsByOrder() gin.HandlerFunc {
+func GetOrderItemsByOrder(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
 		orderId := c.Param("order_id")
 
-		allOrderItems, err := ItemsByOrder(orderId)
+		allOrderItems, err := ItemsByOrder(ctx, s, engine, orderId)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while listing order item by order id"})
 			return
@@ -182,54 +441,112 @@ func GetOrderItemsByOrder() gin.HandlerFunc {
 	}
 }
 
-func ItemsByOrder(id string) (OrderItems []primitive.M, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
-	defer cancel()
-
-	matchStage := bson.D{{Key: "$match", Value: bson.D{{Key: "order_id", Value: id}}}}
-	lookupFoodStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "food"}, {Key: "localField", Value: "food_id"}, {Key: "foreignField", Value: "food_id"}, {Key: "as", Value: "food"}}}}
-	unwindFoodStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$food"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-
-	lookupOrderStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "order"}, {Key: "localField", Value: "order_id"}, {Key: "foreignField", Value: "order_id"}, {Key: "as", Value: "order"}}}}
-	unwindOrderStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$order"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-
-	lookupTableStage := bson.D{{Key: "$lookup", Value: bson.D{{Key: "from", Value: "table"}, {Key: "localField", Value: "order.table_id"}, {Key: "foreignField", Value: "table_id"}, {Key: "as", Value: "table"}}}}
-	unwindTableStage := bson.D{{Key: "$unwind", Value: bson.D{{Key: "path", Value: "$table"}, {Key: "preserveNullAndEmptyArrays", Value: true}}}}
-
-	projectStage := bson.D{{
-		Key: "$project", Value: bson.D{
-			{Key: "id", Value: 0},
-			{Key: "amount", Value: "$food.price"},
-			{Key: "total_count", Value: 1},
-			{Key: "food_name", Value: "$food.name"},
-			{Key: "food_image", Value: "$food.food_image"},
-			{Key: "table_number", Value: "$table.table_number"},
-			{Key: "table_id", Value: "$table.table_id"},
-			{Key: "order_id", Value: "$order.order_id"},
-			{Key: "price", Value: "$food.price"},
-			{Key: "quantity", Value: 1},
-		},
-	}}
-
-	groupStage := bson.D{{Key: "$group", Value: bson.D{{Key: "_id", Value: bson.D{{Key: "order_id", Value: "$order.order_id"}, {Key: "table_id", Value: "$table.table_id"}, {Key: "table_number", Value: "$table.table_number"}}}, {Key: "payment_due", Value: bson.D{{Key: "$sum", Value: "$amount"}}}, {Key: "total_count", Value: bson.D{{Key: "$sum", Value: 1}}}, {Key: "order_items", Value: bson.D{{Key: "$push", Value: "$$ROOT"}}}}}}
-
-	projectStage2 := bson.D{{
-		Key: "$project", Value: bson.D{
-			{Key: "id", Value: 0},
-			{Key: "payment_due", Value: 1},
-			{Key: "total_count", Value: 1},
-			{Key: "table_number", Value: "$_id.table_number"},
-			{Key: "order_items", Value: 1},
-		},
-	}}
-
-	result, err := orderCollection.Aggregate(ctx, mongo.Pipeline{matchStage, lookupFoodStage, unwindFoodStage, lookupOrderStage, unwindOrderStage, lookupTableStage, unwindTableStage, projectStage, groupStage, projectStage2})
+// ItemsByOrder joins an order's items with their food, menu and table and
+// prices them, taking off the order's promotions. Cancelled items are listed
+// but not charged.
+func ItemsByOrder(ctx context.Context, s *store.Store, engine *pricing.Engine, id string) (OrderItemsSummary, error) {
+	var summary OrderItemsSummary
+
+	order, err := s.Orders.Get(ctx, id)
 	if err != nil {
-		panic(err.Error())
+		return summary, err
 	}
 
-	if err = result.All(ctx, &OrderItems); err != nil {
-		log.Fatal(err.Error())
+	var table models.Table
+	if order.Table_ID != nil {
+		table, err = s.Tables.Get(ctx, *order.Table_ID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return summary, err
+		}
+	}
+
+	orderItems, err := s.OrderItems.ListByOrder(ctx, id)
+	if err != nil {
+		return summary, err
 	}
-	return OrderItems, err
+
+	menus := map[string]models.Menu{}
+	lines := []pricing.Line{}
+	summary.Table_Number = table.Table_Number
+	summary.Order_Items = []OrderItemView{}
+	for _, orderItem := range orderItems {
+		var food models.Food
+		if orderItem.Food_ID != nil {
+			food, err = s.Foods.Get(ctx, *orderItem.Food_ID)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				return summary, err
+			}
+		}
+
+		var menu models.Menu
+		if food.Menu_ID != nil {
+			var ok bool
+			if menu, ok = menus[*food.Menu_ID]; !ok {
+				menu, err = s.Menus.Get(ctx, *food.Menu_ID)
+				if err != nil && !errors.Is(err, store.ErrNotFound) {
+					return summary, err
+				}
+				menus[*food.Menu_ID] = menu
+			}
+		}
+
+		quantity := 1
+		if orderItem.Quantity != nil {
+			quantity = *orderItem.Quantity
+		}
+
+		price := orderItem.UnitPrice(food.Price)
+
+		view := OrderItemView{
+			Order_Item_ID: orderItem.Order_Item_ID,
+			Food_Name:     food.Name,
+			Food_Image:    food.Food_Image,
+			Table_Number:  table.Table_Number,
+			Table_ID:      table.Table_ID,
+			Order_ID:      order.Order_ID,
+			Price:         &price,
+			Quantity:      orderItem.Quantity,
+			Modifiers:     orderItem.Modifiers,
+			Price_Rule:    orderItem.Price_Rule,
+			Status:        orderItem.CurrentStatus(),
+			Adjustment:    orderItem.Adjustment,
+		}
+		summary.Total_Count++
+
+		if orderItem.Billable() {
+			view.Amount = price.Mul(int64(quantity))
+			name := ""
+			if food.Name != nil {
+				name = *food.Name
+			}
+			lines = append(lines, pricing.Line{
+				Reference:  orderItem.Order_Item_ID,
+				Food_ID:    food.Food_ID,
+				Name:       name,
+				Category:   menu.Category,
+				Unit_Price: price,
+				Quantity:   quantity,
+			})
+		}
+
+		summary.Order_Items = append(summary.Order_Items, view)
+	}
+
+	guests := 0
+	if table.Number_Of_Guests != nil {
+		guests = *table.Number_Of_Guests
+	}
+
+	promotions, err := orderPromotions(ctx, s, order)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.Breakdown, err = engine.Price(lines, guests, promotions...)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.Payment_Due = summary.Breakdown.Total
+	return summary, nil
 }
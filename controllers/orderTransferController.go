@@ -0,0 +1,752 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+)
+
+// orderChanged is returned from inside a transaction when an order or its
+// bill moved on after the request was checked. It reads as the 409 message.
+type orderChanged string
+
+func (e orderChanged) Error() string {
+	return string(e)
+}
+
+type SplitOrderRequest struct {
+	Orders []struct {
+		Order_Item_IDs []string `json:"order_item_ids" validate:"required,min=1,dive,required"`
+		// Table_ID moves the new order to another table; it stays at the
+		// original order's table otherwise.
+		Table_ID *string `json:"table_id"`
+	} `json:"orders" validate:"required,min=1,dive"`
+}
+
+// MergeOrders moves every item on the given orders onto this order's check
+// and closes them as MERGED, for when two tables are pushed together. Their
+// promotions come along too where they combine with this order's.
+func MergeOrders(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Order_IDs []string `json:"order_ids" validate:"required,min=1,dive,required"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		target, ok := loadOpenOrder(ctx, s, c, c.Param("order_id"))
+		if !ok {
+			return
+		}
+
+		sources := []models.Order{}
+		seen := map[string]bool{target.Order_ID: true}
+		for _, orderId := range input.Order_IDs {
+			if seen[orderId] {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Each order can only be merged once, and not into itself"})
+				return
+			}
+			seen[orderId] = true
+
+			source, ok := loadOpenOrder(ctx, s, c, orderId)
+			if !ok {
+				return
+			}
+			sources = append(sources, source)
+		}
+
+		ids := append([]string{target.Order_ID}, input.Order_IDs...)
+		if _, ok := unpaidBills(ctx, s, c, ids...); !ok {
+			return
+		}
+
+		by := c.GetString("userId")
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var moved []models.OrderItem
+		err := s.WithTransaction(ctx, func(ctx context.Context) error {
+			// Read the orders and bills again so nothing written since
+			// the checks above is lost or merged past a payment.
+			orders, bills, err := reloadOrders(ctx, s, ids...)
+			if err != nil {
+				return err
+			}
+			target, sources = orders[0], orders[1:]
+			moved = []models.OrderItem{}
+
+			existing, err := orderPromotions(ctx, s, target)
+			if err != nil {
+				return err
+			}
+
+			for _, source := range sources {
+				orderItems, err := s.OrderItems.ListByOrder(ctx, source.Order_ID)
+				if err != nil {
+					return err
+				}
+
+				items, err := moveOrderItems(ctx, s, orderItems, target.Order_ID, now)
+				if err != nil {
+					return err
+				}
+				moved = append(moved, items...)
+
+				if existing, err = carryPromotions(ctx, s, &target, existing, source, now); err != nil {
+					return err
+				}
+				source.Promotions = nil
+
+				if err := source.MergeInto(target.Order_ID, by, now); err != nil {
+					return err
+				}
+
+				if err := s.Orders.Update(ctx, source); err != nil {
+					return err
+				}
+
+				if err := rebill(ctx, s, bills[source.Order_ID], true, now); err != nil {
+					return err
+				}
+			}
+
+			target.Updated_At = now
+			if err := s.Orders.Update(ctx, target); err != nil {
+				return err
+			}
+
+			return rebill(ctx, s, bills[target.Order_ID], false, now)
+		})
+		var changed orderChanged
+		if errors.As(err, &changed) {
+			c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not merge the orders!"})
+			return
+		}
+
+		for _, source := range sources {
+			if source.Table_ID != nil && (target.Table_ID == nil || *source.Table_ID != *target.Table_ID) {
+				releaseTable(ctx, s, *source.Table_ID, now)
+			}
+		}
+		republishTickets(ctx, s, hub, moved)
+
+		c.JSON(http.StatusAccepted, target)
+	}
+}
+
+// SplitOrder moves groups of items off an order onto new orders, one per
+// group, so a table can pay as separate checks or part of it can move.
+func SplitOrder(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input SplitOrderRequest
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		source, ok := loadOpenOrder(ctx, s, c, c.Param("order_id"))
+		if !ok {
+			return
+		}
+
+		orderItems, err := s.OrderItems.ListByOrder(ctx, source.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while listing the order's items"})
+			return
+		}
+
+		groups := [][]models.OrderItem{}
+		taken := map[string]bool{}
+		for _, group := range input.Orders {
+			items, ok := pickOrderItems(c, orderItems, group.Order_Item_IDs, taken)
+			if !ok {
+				return
+			}
+			groups = append(groups, items)
+
+			if group.Table_ID != nil {
+				if _, err := s.Tables.Get(ctx, *group.Table_ID); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Table does not exist"})
+					return
+				}
+			}
+		}
+
+		if len(taken) == len(orderItems) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "At least one item has to stay on the order"})
+			return
+		}
+
+		if _, ok := unpaidBills(ctx, s, c, source.Order_ID); !ok {
+			return
+		}
+
+		by := c.GetString("userId")
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var orders []models.Order
+		var moved []models.OrderItem
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			reloaded, bills, err := reloadOrders(ctx, s, source.Order_ID)
+			if err != nil {
+				return err
+			}
+			source = reloaded[0]
+			orders, moved = []models.Order{}, []models.OrderItem{}
+
+			remaining, err := s.OrderItems.ListByOrder(ctx, source.Order_ID)
+			if err != nil {
+				return err
+			}
+
+			for i, group := range input.Orders {
+				items, err := stillOnOrder(remaining, source.Order_ID, groups[i])
+				if err != nil {
+					return err
+				}
+
+				tableId := source.Table_ID
+				if group.Table_ID != nil {
+					tableId = group.Table_ID
+				}
+
+				order := splitOffOrder(source, tableId, by)
+				if err := s.Orders.Create(ctx, order); err != nil {
+					return err
+				}
+				orders = append(orders, order)
+
+				items, err = moveOrderItems(ctx, s, items, order.Order_ID, now)
+				if err != nil {
+					return err
+				}
+				moved = append(moved, items...)
+			}
+
+			if len(moved) == len(remaining) {
+				return orderChanged("At least one item has to stay on the order")
+			}
+
+			source.Updated_At = now
+			if err := s.Orders.Update(ctx, source); err != nil {
+				return err
+			}
+
+			return rebill(ctx, s, bills[source.Order_ID], false, now)
+		})
+		var changed orderChanged
+		if errors.As(err, &changed) {
+			c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not split the order!"})
+			return
+		}
+
+		republishTickets(ctx, s, hub, moved)
+
+		c.JSON(http.StatusAccepted, gin.H{"order": source, "orders": orders})
+	}
+}
+
+// MoveOrderItems moves some items from this order onto another open order.
+func MoveOrderItems(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			To_Order_ID    string   `json:"to_order_id" validate:"required"`
+			Order_Item_IDs []string `json:"order_item_ids" validate:"required,min=1,dive,required"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		source, ok := loadOpenOrder(ctx, s, c, c.Param("order_id"))
+		if !ok {
+			return
+		}
+
+		if input.To_Order_ID == source.Order_ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Items cannot be moved onto the order they are on"})
+			return
+		}
+
+		target, ok := loadOpenOrder(ctx, s, c, input.To_Order_ID)
+		if !ok {
+			return
+		}
+
+		orderItems, err := s.OrderItems.ListByOrder(ctx, source.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while listing the order's items"})
+			return
+		}
+
+		items, ok := pickOrderItems(c, orderItems, input.Order_Item_IDs, map[string]bool{})
+		if !ok {
+			return
+		}
+
+		if _, ok := unpaidBills(ctx, s, c, source.Order_ID, target.Order_ID); !ok {
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var moved []models.OrderItem
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			orders, bills, err := reloadOrders(ctx, s, source.Order_ID, target.Order_ID)
+			if err != nil {
+				return err
+			}
+
+			remaining, err := s.OrderItems.ListByOrder(ctx, source.Order_ID)
+			if err != nil {
+				return err
+			}
+
+			items, err := stillOnOrder(remaining, source.Order_ID, items)
+			if err != nil {
+				return err
+			}
+
+			if moved, err = moveOrderItems(ctx, s, items, target.Order_ID, now); err != nil {
+				return err
+			}
+
+			for _, order := range orders {
+				order.Updated_At = now
+				if err := s.Orders.Update(ctx, order); err != nil {
+					return err
+				}
+
+				if err := rebill(ctx, s, bills[order.Order_ID], false, now); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		var changed orderChanged
+		if errors.As(err, &changed) {
+			c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not move the items!"})
+			return
+		}
+
+		republishTickets(ctx, s, hub, moved)
+
+		c.JSON(http.StatusAccepted, moved)
+	}
+}
+
+// TransferTable hands every open order at a table to another server, moves
+// the party to another free table, or both.
+func TransferTable(s *store.Store, hub *kitchen.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Server_ID   *string `json:"server_id"`
+			To_Table_ID *string `json:"to_table_id"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if input.Server_ID == nil && input.To_Table_ID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Give a server_id, a to_table_id or both"})
+			return
+		}
+
+		table, err := s.Tables.Get(ctx, c.Param("table_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that table"})
+			return
+		}
+
+		openOrders, err := s.Orders.ListByStatus(ctx, openOrderStatuses...)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while listing open orders"})
+			return
+		}
+
+		orders := ordersAtTable(openOrders, table.Table_ID)
+		if len(orders) == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "There are no open orders at that table"})
+			return
+		}
+
+		if input.Server_ID != nil {
+			server, err := s.Users.Get(ctx, *input.Server_ID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find that server"})
+				return
+			}
+
+			switch server.UserRole() {
+			case models.RoleServer, models.RoleManager, models.RoleAdmin:
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Tables can only be handed to servers and managers"})
+				return
+			}
+		}
+
+		if input.To_Table_ID != nil {
+			if *input.To_Table_ID == table.Table_ID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "The party is already at that table"})
+				return
+			}
+
+			if _, err := s.Tables.Get(ctx, *input.To_Table_ID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Table does not exist"})
+				return
+			}
+
+			if len(ordersAtTable(openOrders, *input.To_Table_ID)) > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "That table is taken; merge the orders instead"})
+				return
+			}
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			// Read the orders again, and check the new table is still
+			// free, so nothing written since the checks above is lost.
+			// Holding the new table makes two parties moving onto it
+			// conflict.
+			openOrders, err := s.Orders.ListByStatus(ctx, openOrderStatuses...)
+			if err != nil {
+				return err
+			}
+
+			orders = ordersAtTable(openOrders, table.Table_ID)
+			if len(orders) == 0 {
+				return orderChanged("There are no open orders at that table")
+			}
+
+			if input.To_Table_ID != nil {
+				if len(ordersAtTable(openOrders, *input.To_Table_ID)) > 0 {
+					return orderChanged("That table is taken; merge the orders instead")
+				}
+
+				if err := s.Tables.Hold(ctx, *input.To_Table_ID); err != nil {
+					return err
+				}
+			}
+
+			for i := range orders {
+				if input.Server_ID != nil {
+					orders[i].Server_ID = input.Server_ID
+				}
+				if input.To_Table_ID != nil {
+					orders[i].Table_ID = input.To_Table_ID
+				}
+				orders[i].Updated_At = now
+
+				if err := s.Orders.Update(ctx, orders[i]); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		var changed orderChanged
+		if errors.As(err, &changed) {
+			c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not transfer the table!"})
+			return
+		}
+
+		if input.To_Table_ID != nil {
+			releaseTable(ctx, s, table.Table_ID, now)
+
+			for _, order := range orders {
+				orderItems, err := s.OrderItems.ListByOrder(ctx, order.Order_ID)
+				if err == nil {
+					republishTickets(ctx, s, hub, orderItems)
+				}
+			}
+		}
+
+		c.JSON(http.StatusAccepted, orders)
+	}
+}
+
+// loadOpenOrder writes a 404 or 409 and returns false unless the order
+// exists and still has its table.
+func loadOpenOrder(ctx context.Context, s *store.Store, c *gin.Context, orderId string) (models.Order, bool) {
+	order, err := s.Orders.Get(ctx, orderId)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find order " + orderId})
+		return order, false
+	}
+
+	if !order.Open() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Order " + orderId + " is " + order.CurrentStatus()})
+		return order, false
+	}
+
+	return order, true
+}
+
+// unpaidBills loads the invoices raised for the orders, keyed by order. Once
+// money has been taken against a bill its items are fixed, so any payment
+// writes a 409 and returns false.
+func unpaidBills(ctx context.Context, s *store.Store, c *gin.Context, orderIds ...string) (map[string]*models.Invoice, bool) {
+	bills := map[string]*models.Invoice{}
+	for _, orderId := range orderIds {
+		invoice, err := s.Invoices.GetByOrder(ctx, orderId)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the bill for order " + orderId})
+			return nil, false
+		}
+
+		if len(invoice.Payments) > 0 || invoice.Closed() {
+			c.JSON(http.StatusConflict, gin.H{"error": "The bill for order " + orderId + " has already been paid against"})
+			return nil, false
+		}
+
+		bills[orderId] = &invoice
+	}
+
+	return bills, true
+}
+
+// reloadOrders reads the orders and their bills again inside a transaction,
+// so that what gets written back is current. It fails with orderChanged if
+// an order has closed or its bill has been paid against since the request
+// was checked.
+func reloadOrders(ctx context.Context, s *store.Store, orderIds ...string) ([]models.Order, map[string]*models.Invoice, error) {
+	orders := []models.Order{}
+	bills := map[string]*models.Invoice{}
+	for _, orderId := range orderIds {
+		order, err := s.Orders.Get(ctx, orderId)
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, orderChanged("Order " + orderId + " no longer exists")
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !order.Open() {
+			return nil, nil, orderChanged("Order " + orderId + " is " + order.CurrentStatus())
+		}
+		orders = append(orders, order)
+
+		invoice, err := s.Invoices.GetByOrder(ctx, orderId)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(invoice.Payments) > 0 || invoice.Closed() {
+			return nil, nil, orderChanged("The bill for order " + orderId + " has already been paid against")
+		}
+		bills[orderId] = &invoice
+	}
+
+	return orders, bills, nil
+}
+
+// rebill brings a bill in line after its order's items changed: a merged
+// order's bill is dropped, and any split of the bill no longer adds up so is
+// cleared. The bill is written even when nothing else changes so that a
+// payment taken on it meanwhile conflicts with the transaction.
+func rebill(ctx context.Context, s *store.Store, invoice *models.Invoice, merged bool, at time.Time) error {
+	if invoice == nil {
+		return nil
+	}
+
+	if merged {
+		return s.Invoices.Delete(ctx, invoice.Invoice_ID)
+	}
+
+	invoice.Splits = []models.BillSplit{}
+	invoice.Updated_At = at
+	return s.Invoices.Update(ctx, *invoice)
+}
+
+// pickOrderItems finds the requested items among orderItems, writing a 400
+// and returning false if one is not there or was already taken.
+func pickOrderItems(c *gin.Context, orderItems []models.OrderItem, ids []string, taken map[string]bool) ([]models.OrderItem, bool) {
+	byId := map[string]models.OrderItem{}
+	for _, orderItem := range orderItems {
+		byId[orderItem.Order_Item_ID] = orderItem
+	}
+
+	picked := []models.OrderItem{}
+	for _, id := range ids {
+		orderItem, ok := byId[id]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Order item " + id + " is not on this order"})
+			return nil, false
+		}
+
+		if taken[id] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Order item " + id + " was given more than once"})
+			return nil, false
+		}
+		taken[id] = true
+
+		picked = append(picked, orderItem)
+	}
+
+	return picked, true
+}
+
+// stillOnOrder finds picked again among the items now on the order, failing
+// with orderChanged if one has left it since it was picked.
+func stillOnOrder(orderItems []models.OrderItem, orderId string, picked []models.OrderItem) ([]models.OrderItem, error) {
+	byId := map[string]models.OrderItem{}
+	for _, orderItem := range orderItems {
+		byId[orderItem.Order_Item_ID] = orderItem
+	}
+
+	current := []models.OrderItem{}
+	for _, orderItem := range picked {
+		fresh, ok := byId[orderItem.Order_Item_ID]
+		if !ok {
+			return nil, orderChanged("Order item " + orderItem.Order_Item_ID + " is no longer on order " + orderId)
+		}
+		current = append(current, fresh)
+	}
+
+	return current, nil
+}
+
+// carryPromotions moves the promotions on a merged order onto the order it
+// merged into, where their uses stay counted. One the target already has,
+// or that does not combine with the target's, is dropped and its use given
+// back. It returns the target's promotions as they now stand.
+func carryPromotions(ctx context.Context, s *store.Store, target *models.Order, existing []models.Promotion, source models.Order, at time.Time) ([]models.Promotion, error) {
+	for _, applied := range source.Promotions {
+		promotion, err := s.Promotions.Get(ctx, applied.Promotion_ID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !target.HasPromotion(promotion.Promotion_ID) && promotionStacks(promotion, existing) == nil {
+			target.Promotions = append(target.Promotions, applied)
+			existing = append(existing, promotion)
+			continue
+		}
+
+		if promotion.Uses > 0 {
+			promotion.Uses--
+		}
+		promotion.Updated_At = at
+		if err := s.Promotions.Update(ctx, promotion); err != nil {
+			return nil, err
+		}
+	}
+
+	return existing, nil
+}
+
+func moveOrderItems(ctx context.Context, s *store.Store, orderItems []models.OrderItem, orderId string, at time.Time) ([]models.OrderItem, error) {
+	moved := []models.OrderItem{}
+	for _, orderItem := range orderItems {
+		orderItem.Order_ID = &orderId
+		orderItem.Updated_At = at
+		if err := s.OrderItems.Update(ctx, orderItem); err != nil {
+			return nil, err
+		}
+
+		moved = append(moved, orderItem)
+	}
+
+	return moved, nil
+}
+
+// splitOffOrder starts a new order for items split off source. It takes over
+// source's server and picks up where the kitchen is with source. Promotions
+// stay on the check they were applied to, so the new order starts without
+// any.
+func splitOffOrder(source models.Order, tableId *string, by string) models.Order {
+	order := models.Order{Order_Date: source.Order_Date, Table_ID: tableId, Server_ID: source.Server_ID}
+	placeOrder(&order, by)
+
+	if status := source.CurrentStatus(); status != models.OrderPlaced {
+		order.Status = &status
+		order.Status_History = append(order.Status_History, models.OrderTransition{
+			From: models.OrderPlaced, To: status, At: order.Created_At, By: by, Reason: "split from " + source.Order_ID,
+		})
+	}
+
+	return order
+}
+
+func ordersAtTable(orders []models.Order, tableId string) []models.Order {
+	atTable := []models.Order{}
+	for _, order := range orders {
+		if order.Table_ID != nil && *order.Table_ID == tableId {
+			atTable = append(atTable, order)
+		}
+	}
+
+	return atTable
+}
+
+// republishTickets tells the kitchen about items that changed order or
+// table while it still has them.
+func republishTickets(ctx context.Context, s *store.Store, hub *kitchen.Hub, orderItems []models.OrderItem) {
+	for _, orderItem := range orderItems {
+		switch orderItem.CurrentStatus() {
+		case models.ItemServed, models.ItemCancelled:
+			continue
+		}
+
+		publishTicket(ctx, s, hub, kitchen.ItemModified, orderItem)
+	}
+}
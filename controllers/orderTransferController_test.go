@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+func TestMergeOrders(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	env.router.POST("/orders/:order_id/merge", MergeOrders(env.s, kitchen.NewHub()))
+
+	// A takeaway order has no table to merge the source's into.
+	target, bill := env.newOrder(t, nil)
+	env.addItem(t, target.Order_ID, "6.00", 1)
+	moved := env.addItem(t, env.order.Order_ID, "4.00", 2)
+
+	body := map[string][]string{"order_ids": {env.order.Order_ID}}
+	if code := env.do(t, "POST", "/orders/"+target.Order_ID+"/merge", body, nil); code != http.StatusAccepted {
+		t.Fatalf("got %d, want %d", code, http.StatusAccepted)
+	}
+
+	source, err := env.s.Orders.Get(ctx, env.order.Order_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *source.Status != models.OrderMerged || source.Merged_Into == nil || *source.Merged_Into != target.Order_ID {
+		t.Errorf("the source order is %s into %v, want %s into %s", *source.Status, source.Merged_Into, models.OrderMerged, target.Order_ID)
+	}
+
+	orderItem, err := env.s.OrderItems.Get(ctx, moved.Order_Item_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *orderItem.Order_ID != target.Order_ID {
+		t.Errorf("the item is on order %s, want it moved to %s", *orderItem.Order_ID, target.Order_ID)
+	}
+
+	summary, err := ItemsByOrder(ctx, env.s, env.engine, bill.Order_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Payment_Due.Amount != 1400 {
+		t.Errorf("the merged bill is %v, want 14.00", summary.Payment_Due)
+	}
+
+	if code := env.do(t, "POST", "/orders/"+target.Order_ID+"/merge", body, nil); code == http.StatusAccepted {
+		t.Error("merging an order that was already merged was accepted")
+	}
+}
@@ -0,0 +1,618 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/payments"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// errPaymentRefused is returned from inside the transaction when the
+// invoice no longer takes the payment.
+var errPaymentRefused = errors.New("payment refused")
+
+type SplitView struct {
+	models.BillSplit
+	Amount_Paid models.Money `json:"amount_paid"`
+	Balance_Due models.Money `json:"balance_due"`
+}
+
+type SplitRequest struct {
+	Mode   string     `json:"mode" validate:"required,eq=EVEN|eq=SEAT|eq=ITEM"`
+	Ways   int        `json:"ways" validate:"omitempty,min=2,max=50"`
+	Groups [][]string `json:"groups"`
+}
+
+type PaymentRequest struct {
+	Amount    *models.Money `json:"amount" validate:"required"`
+	Tender    string        `json:"tender" validate:"required,eq=CASH|eq=CARD|eq=VOUCHER|eq=MOBILE"`
+	Split_ID  string        `json:"split_id"`
+	Reference string        `json:"reference"`
+	// Source is the card token charged through the payment provider.
+	Source string `json:"source"`
+}
+
+// SplitInvoice divides the invoice total into shares that can be paid
+// separately. Shares by seat or by item carry their proportion of tax and
+// service charge, and always add up to the invoice total.
+func SplitInvoice(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input SplitRequest
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		invoice, err := s.Invoices.Get(ctx, c.Param("invoice_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find invoice with that id"})
+			return
+		}
+
+		for _, payment := range invoice.Payments {
+			if payment.Split_ID != "" {
+				c.JSON(http.StatusConflict, gin.H{"error": "The invoice cannot be re-split once a share has been paid"})
+				return
+			}
+		}
+
+		summary, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while pricing the invoice"})
+			return
+		}
+
+		// Only what is still owed gets split; earlier whole-bill payments
+		// come off the top.
+		if _, err := buildSplits(ctx, s, input, invoice, summary, balanceDue(summary.Payment_Due, invoice.AmountPaid(""))); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			// Read the invoice again so a share paid, or an item added,
+			// since the checks above is not split over or written over.
+			var err error
+			if invoice, err = s.Invoices.Get(ctx, invoice.Invoice_ID); err != nil {
+				return err
+			}
+
+			for _, payment := range invoice.Payments {
+				if payment.Split_ID != "" {
+					return orderChanged("The invoice cannot be re-split once a share has been paid")
+				}
+			}
+
+			if summary, err = ItemsByOrder(ctx, s, engine, invoice.Order_ID); err != nil {
+				return err
+			}
+
+			splits, err := buildSplits(ctx, s, input, invoice, summary, balanceDue(summary.Payment_Due, invoice.AmountPaid("")))
+			if err != nil {
+				return orderChanged("The invoice changed while it was being split: " + err.Error())
+			}
+
+			invoice.Splits = splits
+			invoice.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+			return s.Invoices.Update(ctx, invoice)
+		})
+		if err != nil {
+			var changed orderChanged
+			if errors.As(err, &changed) {
+				c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update invoice!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, splitViews(invoice))
+	}
+}
+
+// AddPayment records a tender against the invoice, or against one of its
+// splits, and marks the invoice PAID once nothing is left owing. Cash may
+// exceed what is due, with the difference handed back as change; other
+// tenders must not. Card payments are charged through the provider first and
+// only recorded once captured.
+func AddPayment(s *store.Store, engine *pricing.Engine, provider payments.PaymentProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input PaymentRequest
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if input.Tender == models.TenderCard && input.Source == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A card payment needs a source"})
+			return
+		}
+
+		if input.Amount.Amount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A payment must be for a positive amount"})
+			return
+		}
+
+		invoice, err := s.Invoices.Get(ctx, c.Param("invoice_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find invoice with that id"})
+			return
+		}
+
+		if invoice.Closed() {
+			c.JSON(http.StatusConflict, gin.H{"error": "The invoice is " + *invoice.Payment_Status})
+			return
+		}
+
+		summary, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while pricing the invoice"})
+			return
+		}
+
+		if !input.Amount.SameCurrency(summary.Payment_Due) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The invoice is billed in " + summary.Payment_Due.Currency})
+			return
+		}
+
+		due, ok := amountDue(invoice, summary.Payment_Due, input.Split_ID)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that split on the invoice"})
+			return
+		}
+
+		if due.Amount <= 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Nothing is left to pay"})
+			return
+		}
+
+		payment, err := newPayment(input, due, c.GetString("userId"))
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		var transaction models.PaymentTransaction
+		if input.Tender == models.TenderCard {
+			transaction, err = chargeCard(ctx, s, provider, invoice.Invoice_ID, &payment, input.Source)
+			if errors.Is(err, payments.ErrDeclined) {
+				c.JSON(http.StatusPaymentRequired, gin.H{"error": "The card was declined", "reason": transaction.Failure_Reason, "transaction_id": transaction.Transaction_ID})
+				return
+			}
+
+			if err != nil {
+				c.JSON(http.StatusBadGateway, gin.H{"error": "The card could not be charged", "transaction_id": transaction.Transaction_ID})
+				return
+			}
+		}
+
+		var reason error
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			// Read the invoice again so payments taken since the checks
+			// above count towards what is due and are not written over.
+			var err error
+			if invoice, err = s.Invoices.Get(ctx, invoice.Invoice_ID); err != nil {
+				return err
+			}
+
+			if summary, err = ItemsByOrder(ctx, s, engine, invoice.Order_ID); err != nil {
+				return err
+			}
+
+			if reason = fitPayment(&payment, invoice, summary.Payment_Due, input.Split_ID); reason != nil {
+				return errPaymentRefused
+			}
+
+			// The provider may already have reported the capture as
+			// failed, in which case there is nothing to record.
+			if payment.Transaction_ID != "" {
+				current, err := s.Transactions.Get(ctx, payment.Transaction_ID)
+				if err != nil {
+					return err
+				}
+
+				if current.Status != models.TransactionCaptured {
+					reason = errors.New("the card payment was reported as " + strings.ToLower(current.Status))
+					return errPaymentRefused
+				}
+			}
+
+			invoice.Payments = append(invoice.Payments, payment)
+			settleInvoice(&invoice, summary.Payment_Due)
+			return s.Invoices.Update(ctx, invoice)
+		})
+		if err != nil {
+			// The card has been charged for a payment that was not
+			// recorded, so give the money back.
+			var refundErr error
+			if payment.Transaction_ID != "" {
+				refundErr = giveBackCharge(ctx, s, provider, &transaction, payment.Payment_ID)
+			}
+
+			if refundErr != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"error":          "The card was charged but the payment could not be recorded or refunded",
+					"refund_error":   refundErr.Error(),
+					"transaction_id": transaction.Transaction_ID,
+				})
+				return
+			}
+
+			if errors.Is(err, errPaymentRefused) {
+				c.JSON(http.StatusConflict, gin.H{"error": reason.Error()})
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not record the payment!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"payment":        payment,
+			"payment_status": invoice.Payment_Status,
+			"amount_paid":    invoice.AmountPaid(""),
+			"balance_due":    balanceDue(summary.Payment_Due, invoice.AmountPaid("")),
+		})
+	}
+}
+
+// chargeCard authorizes and captures payment.Amount on source, keeping a
+// transaction record of each step. A hold that cannot be captured is voided.
+func chargeCard(ctx context.Context, s *store.Store, provider payments.PaymentProvider, invoiceId string, payment *models.Payment, source string) (models.PaymentTransaction, error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	transaction := models.PaymentTransaction{
+		ID:         primitive.NewObjectID(),
+		Invoice_ID: invoiceId,
+		Payment_ID: payment.Payment_ID,
+		Provider:   provider.Name(),
+		Amount:     payment.Amount,
+		Refunded:   models.NewMoney(0, payment.Amount.Currency),
+		Events:     []models.TransactionEvent{},
+		Created_At: now,
+	}
+	transaction.Transaction_ID = transaction.ID.Hex()
+
+	result, err := provider.Authorize(ctx, source, payment.Amount)
+	transaction.Provider_Reference = result.Reference
+	if err != nil {
+		status := result.Status
+		if status == "" {
+			status = models.TransactionFailed
+		}
+		reason := result.Failure_Reason
+		if reason == "" {
+			reason = err.Error()
+		}
+
+		transaction.Record(models.EventAuthorize, status, payment.Amount, reason, now)
+		if createErr := s.Transactions.Create(ctx, transaction); createErr != nil {
+			return transaction, createErr
+		}
+		return transaction, err
+	}
+
+	transaction.Record(models.EventAuthorize, models.TransactionAuthorized, payment.Amount, "", now)
+	if err := s.Transactions.Create(ctx, transaction); err != nil {
+		provider.Void(ctx, result.Reference)
+		return transaction, err
+	}
+
+	if _, err := provider.Capture(ctx, result.Reference, payment.Amount); err != nil {
+		transaction.Record(models.EventCapture, models.TransactionFailed, payment.Amount, err.Error(), now)
+		if _, voidErr := provider.Void(ctx, result.Reference); voidErr == nil {
+			transaction.Record(models.EventVoid, models.TransactionVoided, payment.Amount, "", now)
+		}
+		s.Transactions.Update(ctx, transaction)
+		return transaction, err
+	}
+
+	transaction.Record(models.EventCapture, models.TransactionCaptured, payment.Amount, "", now)
+	if err := s.Transactions.Update(ctx, transaction); err != nil {
+		return transaction, err
+	}
+
+	payment.Transaction_ID = transaction.Transaction_ID
+	payment.Reference = result.Reference
+	return transaction, nil
+}
+
+// refundCharge gives amount back on a captured card transaction. The
+// provider makes the refund at most once per idempotency key.
+func refundCharge(ctx context.Context, s *store.Store, provider payments.PaymentProvider, transaction *models.PaymentTransaction, amount models.Money, idempotencyKey string) error {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	if _, err := provider.Refund(ctx, transaction.Provider_Reference, amount, idempotencyKey); err != nil {
+		return err
+	}
+
+	transaction.Refunded = transaction.Refunded.Add(amount)
+	status := models.TransactionCaptured
+	if transaction.Refunded.Amount >= transaction.Amount.Amount {
+		status = models.TransactionRefunded
+	}
+	transaction.Record(models.EventRefund, status, amount, "", now)
+
+	return s.Transactions.Update(ctx, *transaction)
+}
+
+// giveBackCharge refunds a captured charge whose payment never made it onto
+// the invoice. If the refund fails the transaction is left CAPTURED with the
+// failure recorded against it, so it shows up for reconciliation as money
+// taken that no invoice accounts for.
+func giveBackCharge(ctx context.Context, s *store.Store, provider payments.PaymentProvider, transaction *models.PaymentTransaction, paymentId string) error {
+	err := refundCharge(ctx, s, provider, transaction, transaction.Amount, paymentId)
+	if err == nil {
+		return nil
+	}
+
+	log.Printf("payments: refunding transaction %s for unrecorded payment %s failed: %v", transaction.Transaction_ID, paymentId, err)
+
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	current, getErr := s.Transactions.Get(ctx, transaction.Transaction_ID)
+	if getErr == nil {
+		*transaction = current
+	}
+	transaction.Record(models.EventRefund, transaction.Status, transaction.Amount, "payment "+paymentId+" was not recorded and the refund failed: "+err.Error(), now)
+	if updateErr := s.Transactions.Update(ctx, *transaction); updateErr != nil {
+		log.Printf("payments: marking transaction %s for reconciliation failed: %v", transaction.Transaction_ID, updateErr)
+	}
+
+	return err
+}
+
+// amountDue is what is still owed on the invoice, or on the split when one
+// is given and less is owed on it. It returns false if there is no such
+// split.
+func amountDue(invoice models.Invoice, total models.Money, splitId string) (models.Money, bool) {
+	due := balanceDue(total, invoice.AmountPaid(""))
+	if splitId == "" {
+		return due, true
+	}
+
+	split, ok := invoice.Split(splitId)
+	if !ok {
+		return due, false
+	}
+
+	if splitDue := balanceDue(split.Amount, invoice.AmountPaid(split.Split_ID)); splitDue.Amount < due.Amount {
+		due = splitDue
+	}
+
+	return due, true
+}
+
+// fitPayment checks a payment against the invoice as it is now. Cash
+// change is worked out again if less is due than when the payment was
+// taken; other tenders are refused.
+func fitPayment(payment *models.Payment, invoice models.Invoice, total models.Money, splitId string) error {
+	if invoice.Closed() {
+		return errors.New("the invoice is " + *invoice.Payment_Status)
+	}
+
+	due, ok := amountDue(invoice, total, splitId)
+	if !ok {
+		return errors.New("the split being paid is no longer on the invoice")
+	}
+
+	if due.Amount <= 0 {
+		return errors.New("nothing is left to pay")
+	}
+
+	if payment.Amount.Amount > due.Amount {
+		if payment.Tender != models.TenderCash {
+			return fmt.Errorf("only %s is due now; %s payments cannot exceed it", due.Decimal(), payment.Tender)
+		}
+
+		payment.Amount = due
+		payment.Change_Given = payment.Tendered.Sub(due)
+	}
+
+	return nil
+}
+
+func newPayment(input PaymentRequest, due models.Money, receivedBy string) (models.Payment, error) {
+	now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	payment := models.Payment{
+		Payment_ID:   primitive.NewObjectID().Hex(),
+		Tender:       input.Tender,
+		Amount:       *input.Amount,
+		Tendered:     *input.Amount,
+		Change_Given: models.NewMoney(0, due.Currency),
+		Split_ID:     input.Split_ID,
+		Reference:    input.Reference,
+		Received_By:  receivedBy,
+		Created_At:   now,
+	}
+
+	if input.Amount.Amount > due.Amount {
+		if input.Tender != models.TenderCash {
+			return payment, fmt.Errorf("only %s is due; %s payments cannot exceed it", due.Decimal(), input.Tender)
+		}
+
+		payment.Amount = due
+		payment.Change_Given = input.Amount.Sub(due)
+	}
+
+	return payment, nil
+}
+
+// settleInvoice brings the status and method in line with the payments.
+func settleInvoice(invoice *models.Invoice, total models.Money) {
+	status := invoice.DerivedStatus(total)
+	invoice.Payment_Status = &status
+
+	tenders := map[string]bool{}
+	for _, payment := range invoice.Payments {
+		tenders[payment.Tender] = true
+	}
+
+	if len(tenders) > 1 {
+		method := "SPLIT"
+		invoice.Payment_Method = &method
+	} else if len(invoice.Payments) > 0 {
+		method := invoice.Payments[0].Tender
+		invoice.Payment_Method = &method
+	}
+
+	invoice.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+}
+
+func balanceDue(total, paid models.Money) models.Money {
+	balance := total.Sub(paid)
+	if balance.Amount < 0 {
+		balance.Amount = 0
+	}
+
+	return balance
+}
+
+func splitViews(invoice models.Invoice) []SplitView {
+	views := []SplitView{}
+	for _, split := range invoice.Splits {
+		paid := invoice.AmountPaid(split.Split_ID)
+		views = append(views, SplitView{BillSplit: split, Amount_Paid: paid, Balance_Due: balanceDue(split.Amount, paid)})
+	}
+
+	return views
+}
+
+func buildSplits(ctx context.Context, s *store.Store, input SplitRequest, invoice models.Invoice, summary OrderItemsSummary, owed models.Money) ([]models.BillSplit, error) {
+	gross := map[string]int64{}
+	for _, line := range summary.Breakdown.Lines {
+		gross[line.Reference] = line.Charged().Amount
+	}
+
+	var splits []models.BillSplit
+	var weights []int64
+	switch input.Mode {
+	case models.SplitEven:
+		if input.Ways < 2 {
+			return nil, fmt.Errorf("an even split needs ways of at least 2")
+		}
+
+		for i := 1; i <= input.Ways; i++ {
+			splits = append(splits, models.BillSplit{Label: fmt.Sprintf("Guest %d of %d", i, input.Ways)})
+			weights = append(weights, 1)
+		}
+	case models.SplitSeat:
+		orderItems, err := s.OrderItems.ListByOrder(ctx, invoice.Order_ID)
+		if err != nil {
+			return nil, err
+		}
+
+		bySeat := map[int][]string{}
+		for _, orderItem := range orderItems {
+			if _, charged := gross[orderItem.Order_Item_ID]; !charged {
+				continue
+			}
+
+			seat := 0
+			if orderItem.Seat != nil {
+				seat = *orderItem.Seat
+			}
+			bySeat[seat] = append(bySeat[seat], orderItem.Order_Item_ID)
+		}
+
+		if len(bySeat) < 2 {
+			return nil, fmt.Errorf("the items are not spread across more than one seat")
+		}
+
+		seats := make([]int, 0, len(bySeat))
+		for seat := range bySeat {
+			seats = append(seats, seat)
+		}
+		sort.Ints(seats)
+
+		for _, seat := range seats {
+			split := models.BillSplit{Label: "Shared", Order_Item_IDs: bySeat[seat]}
+			if seat > 0 {
+				seat := seat
+				split.Seat = &seat
+				split.Label = "Seat " + strconv.Itoa(seat)
+			}
+			splits = append(splits, split)
+			weights = append(weights, sumGross(gross, bySeat[seat]))
+		}
+	case models.SplitItem:
+		assigned := map[string]bool{}
+		for i, group := range input.Groups {
+			if len(group) == 0 {
+				return nil, fmt.Errorf("group %d has no items", i+1)
+			}
+
+			for _, orderItemId := range group {
+				if _, ok := gross[orderItemId]; !ok {
+					return nil, fmt.Errorf("order item %s is not charged on this invoice", orderItemId)
+				}
+				if assigned[orderItemId] {
+					return nil, fmt.Errorf("order item %s is in more than one group", orderItemId)
+				}
+				assigned[orderItemId] = true
+			}
+
+			splits = append(splits, models.BillSplit{Label: fmt.Sprintf("Group %d", i+1), Order_Item_IDs: group})
+			weights = append(weights, sumGross(gross, group))
+		}
+
+		var rest []string
+		for _, line := range summary.Breakdown.Lines {
+			if !assigned[line.Reference] {
+				rest = append(rest, line.Reference)
+			}
+		}
+
+		if len(rest) > 0 {
+			splits = append(splits, models.BillSplit{Label: "Remaining items", Order_Item_IDs: rest})
+			weights = append(weights, sumGross(gross, rest))
+		}
+
+		if len(splits) < 2 {
+			return nil, fmt.Errorf("an item split needs at least two groups")
+		}
+	}
+
+	for i, amount := range pricing.Allocate(owed, weights) {
+		splits[i].Split_ID = primitive.NewObjectID().Hex()
+		splits[i].Mode = input.Mode
+		splits[i].Amount = amount
+	}
+
+	return splits, nil
+}
+
+func sumGross(gross map[string]int64, orderItemIds []string) int64 {
+	total := int64(0)
+	for _, orderItemId := range orderItemIds {
+		total += gross[orderItemId]
+	}
+
+	return total
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/payments"
+)
+
+func TestSplitInvoice(t *testing.T) {
+	env := newTestEnv(t)
+	env.router.POST("/invoices/:invoice_id/splits", SplitInvoice(env.s, env.engine))
+	env.router.POST("/invoices/:invoice_id/payments", AddPayment(env.s, env.engine, payments.NewSimulator("test", "")))
+
+	env.addItem(t, env.order.Order_ID, "10.00", 1)
+	env.addItem(t, env.order.Order_ID, "2.50", 2)
+	path := "/invoices/" + env.invoice.Invoice_ID
+
+	var splits []SplitView
+	if code := env.do(t, "POST", path+"/splits", SplitRequest{Mode: models.SplitEven, Ways: 3}, &splits); code != http.StatusAccepted {
+		t.Fatalf("splitting got %d, want %d", code, http.StatusAccepted)
+	}
+
+	if len(splits) != 3 {
+		t.Fatalf("got %d shares, want 3", len(splits))
+	}
+	var total int64
+	for _, split := range splits {
+		total += split.Amount.Amount
+	}
+	if total != 1500 {
+		t.Errorf("the shares add up to %d, want the invoice total 1500", total)
+	}
+
+	payment := PaymentRequest{Amount: &splits[0].Amount, Tender: models.TenderCash, Split_ID: splits[0].Split_ID}
+	if code := env.do(t, "POST", path+"/payments", payment, nil); code != http.StatusAccepted {
+		t.Fatalf("paying a share got %d, want %d", code, http.StatusAccepted)
+	}
+
+	if code := env.do(t, "POST", path+"/splits", SplitRequest{Mode: models.SplitEven, Ways: 2}, nil); code != http.StatusConflict {
+		t.Errorf("re-splitting after a share was paid got %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestAddCardPayment(t *testing.T) {
+	env := newTestEnv(t)
+	env.router.POST("/invoices/:invoice_id/payments", AddPayment(env.s, env.engine, payments.NewSimulator("test", "")))
+
+	env.addItem(t, env.order.Order_ID, "12.00", 1)
+	amount := models.NewMoney(1200, models.DefaultCurrency)
+
+	payment := PaymentRequest{Amount: &amount, Tender: models.TenderCard, Source: payments.CardVisa}
+	if code := env.do(t, "POST", "/invoices/"+env.invoice.Invoice_ID+"/payments", payment, nil); code != http.StatusAccepted {
+		t.Fatalf("got %d, want %d", code, http.StatusAccepted)
+	}
+
+	invoice := env.getInvoice(t, env.invoice.Invoice_ID)
+	if *invoice.Payment_Status != models.PaymentPaid {
+		t.Errorf("the invoice is %s, want %s", *invoice.Payment_Status, models.PaymentPaid)
+	}
+	if len(invoice.Payments) != 1 || invoice.Payments[0].Transaction_ID == "" {
+		t.Errorf("got payments %+v, want one with its card transaction", invoice.Payments)
+	}
+}
+
+// TestAddPaymentRace checks that payments made at once for the whole bill
+// are not all taken.
+func TestAddPaymentRace(t *testing.T) {
+	env := newTestEnv(t)
+	env.router.POST("/invoices/:invoice_id/payments", AddPayment(env.s, env.engine, payments.NewSimulator("test", "")))
+
+	env.addItem(t, env.order.Order_ID, "8.00", 1)
+	amount := models.NewMoney(800, models.DefaultCurrency)
+
+	codes := env.race(t, 5, "POST", "/invoices/"+env.invoice.Invoice_ID+"/payments", func(i int) interface{} {
+		return PaymentRequest{Amount: &amount, Tender: models.TenderVoucher}
+	})
+	if codes[http.StatusAccepted] != 1 {
+		t.Errorf("got %v, want exactly one payment accepted", codes)
+	}
+
+	invoice := env.getInvoice(t, env.invoice.Invoice_ID)
+	if len(invoice.Payments) != 1 {
+		t.Errorf("got %d payments on the invoice, want 1", len(invoice.Payments))
+	}
+}
@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func GetPriceRules(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		rules, err := s.PriceRules.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the price rules!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, rules)
+	}
+}
+
+func CreatePriceRule(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rule models.PriceRule
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := rule.Check(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if rule.Active == nil {
+			active := true
+			rule.Active = &active
+		}
+
+		rule.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		rule.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		rule.ID = primitive.NewObjectID()
+		rule.Rule_ID = rule.ID.Hex()
+
+		if err := s.PriceRules.Create(ctx, rule); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Price rule was not created!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, rule)
+	}
+}
+
+func GetPriceRule(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		rule, err := s.PriceRules.Get(ctx, c.Param("rule_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that price rule"})
+			return
+		}
+
+		c.JSON(http.StatusOK, rule)
+	}
+}
+
+// UpdatePriceRule changes a rule. Items already ordered keep the price they
+// were ordered at.
+func UpdatePriceRule(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.PriceRule
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rule, err := s.PriceRules.Get(ctx, c.Param("rule_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find price rule with that id"})
+			return
+		}
+
+		if input.Name != nil {
+			rule.Name = input.Name
+		}
+
+		if input.Type != nil {
+			rule.Type = input.Type
+		}
+
+		if input.Percent != nil {
+			rule.Percent = input.Percent
+		}
+
+		if input.Amount != nil {
+			rule.Amount = input.Amount
+		}
+
+		if input.Food_IDs != nil {
+			rule.Food_IDs = input.Food_IDs
+		}
+
+		if input.Menu_IDs != nil {
+			rule.Menu_IDs = input.Menu_IDs
+		}
+
+		if input.Categories != nil {
+			rule.Categories = input.Categories
+		}
+
+		if input.Starts_At != nil {
+			rule.Starts_At = input.Starts_At
+		}
+
+		if input.Ends_At != nil {
+			rule.Ends_At = input.Ends_At
+		}
+
+		if input.Schedule != nil {
+			rule.Schedule = input.Schedule
+		}
+
+		if input.Priority != nil {
+			rule.Priority = input.Priority
+		}
+
+		if input.Active != nil {
+			rule.Active = input.Active
+		}
+
+		if err := validate.Struct(rule); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := rule.Check(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rule.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.PriceRules.Update(ctx, rule); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update price rule!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, rule)
+	}
+}
+
+func DeletePriceRule(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		ruleId := c.Param("rule_id")
+
+		rule, err := s.PriceRules.Get(ctx, ruleId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find price rule with that id"})
+			return
+		}
+
+		if err := s.PriceRules.Delete(ctx, ruleId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete price rule!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, rule)
+	}
+}
+
+// priceItem sets an item's unit price from its food and the price rules in
+// force at the given time, recording the rule that applied. The food's menu
+// must already be in menus, as menuServing leaves it.
+func priceItem(orderItem *models.OrderItem, rules []models.PriceRule, menus map[string]models.Menu, food models.Food, at time.Time) {
+	orderItem.Unit_Price = food.Price
+	orderItem.Price_Rule = nil
+	if food.Price == nil {
+		return
+	}
+
+	var menu models.Menu
+	if food.Menu_ID != nil {
+		menu = menus[*food.Menu_ID]
+	}
+
+	price, applied := models.RulePrice(rules, food, menu, at)
+	if applied != nil {
+		orderItem.Unit_Price = &price
+		orderItem.Price_Rule = applied
+	}
+}
+
+// loadPriceRules writes a 500 and returns false when the rules cannot be
+// read.
+func loadPriceRules(ctx context.Context, s *store.Store, c *gin.Context) ([]models.PriceRule, bool) {
+	rules, err := s.PriceRules.List(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the price rules"})
+		return nil, false
+	}
+
+	return rules, true
+}
@@ -0,0 +1,496 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errPromotionNotUsable = errors.New("promotion cannot be used")
+
+// PromotionRequest applies a promotion to an order, by coupon code or, for
+// staff, by id.
+type PromotionRequest struct {
+	Code         *string `json:"code"`
+	Promotion_ID *string `json:"promotion_id"`
+}
+
+func GetPromotions(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		promotions, err := s.Promotions.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the promotions!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, promotions)
+	}
+}
+
+func CreatePromotion(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var promotion models.Promotion
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&promotion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(promotion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := promotion.Check(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !codeFree(ctx, s, c, &promotion) {
+			return
+		}
+
+		if promotion.Active == nil {
+			active := true
+			promotion.Active = &active
+		}
+
+		promotion.Uses = 0
+		promotion.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		promotion.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		promotion.ID = primitive.NewObjectID()
+		promotion.Promotion_ID = promotion.ID.Hex()
+
+		if err := s.Promotions.Create(ctx, promotion); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Promotion was not created!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, promotion)
+	}
+}
+
+func GetPromotion(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		promotion, err := s.Promotions.Get(ctx, c.Param("promotion_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that promotion"})
+			return
+		}
+
+		c.JSON(http.StatusOK, promotion)
+	}
+}
+
+// UpdatePromotion changes a promotion. Bills not yet paid are repriced with
+// the new terms; Uses is kept by the server.
+func UpdatePromotion(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.Promotion
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		promotion, err := s.Promotions.Get(ctx, c.Param("promotion_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find promotion with that id"})
+			return
+		}
+
+		if input.Name != nil {
+			promotion.Name = input.Name
+		}
+
+		if input.Code != nil {
+			promotion.Code = input.Code
+		}
+
+		if input.Type != nil {
+			promotion.Type = input.Type
+		}
+
+		if input.Percent != nil {
+			promotion.Percent = input.Percent
+		}
+
+		if input.Amount != nil {
+			promotion.Amount = input.Amount
+		}
+
+		if input.Buy_Quantity != nil {
+			promotion.Buy_Quantity = input.Buy_Quantity
+		}
+
+		if input.Get_Quantity != nil {
+			promotion.Get_Quantity = input.Get_Quantity
+		}
+
+		if input.Get_Percent != nil {
+			promotion.Get_Percent = input.Get_Percent
+		}
+
+		if input.Food_IDs != nil {
+			promotion.Food_IDs = input.Food_IDs
+		}
+
+		if input.Categories != nil {
+			promotion.Categories = input.Categories
+		}
+
+		if input.Min_Spend != nil {
+			promotion.Min_Spend = input.Min_Spend
+		}
+
+		if input.Starts_At != nil {
+			promotion.Starts_At = input.Starts_At
+		}
+
+		if input.Ends_At != nil {
+			promotion.Ends_At = input.Ends_At
+		}
+
+		if input.Max_Uses != nil {
+			promotion.Max_Uses = input.Max_Uses
+		}
+
+		if input.Stackable != nil {
+			promotion.Stackable = input.Stackable
+		}
+
+		if input.Active != nil {
+			promotion.Active = input.Active
+		}
+
+		if err := validate.Struct(promotion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := promotion.Check(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if input.Code != nil && !codeFree(ctx, s, c, &promotion) {
+			return
+		}
+
+		promotion.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Promotions.Update(ctx, promotion); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update promotion!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, promotion)
+	}
+}
+
+// DeletePromotion removes a promotion. Orders it was applied to no longer
+// get the discount, so used promotions should be deactivated instead.
+func DeletePromotion(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		promotionId := c.Param("promotion_id")
+
+		promotion, err := s.Promotions.Get(ctx, promotionId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find promotion with that id"})
+			return
+		}
+
+		if promotion.Uses > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The promotion has been used; deactivate it instead"})
+			return
+		}
+
+		if err := s.Promotions.Delete(ctx, promotionId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete promotion!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, promotion)
+	}
+}
+
+// ApplyPromotion puts a promotion on an order whose bill has not been paid
+// against, using up one of its uses, and returns the repriced bill.
+func ApplyPromotion(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input PromotionRequest
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if (input.Code == nil) == (input.Promotion_ID == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Give either a code or a promotion_id"})
+			return
+		}
+
+		order, ok := loadOpenOrder(ctx, s, c, c.Param("order_id"))
+		if !ok {
+			return
+		}
+
+		if _, ok := unpaidBills(ctx, s, c, order.Order_ID); !ok {
+			return
+		}
+
+		var promotion models.Promotion
+		var err error
+		if input.Code != nil {
+			promotion, err = s.Promotions.GetByCode(ctx, models.NormalizeCode(*input.Code))
+		} else {
+			promotion, err = s.Promotions.Get(ctx, *input.Promotion_ID)
+		}
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No promotion matches that code"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		var reason error
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			// Read the order, its bill and the promotion again so that
+			// nothing written since the checks above is lost, and two
+			// tables cannot both take the promotion's last use.
+			orders, bills, err := reloadOrders(ctx, s, order.Order_ID)
+			if err != nil {
+				return err
+			}
+			order = orders[0]
+
+			promotion, err := s.Promotions.Get(ctx, promotion.Promotion_ID)
+			if err != nil {
+				return err
+			}
+
+			existing, err := orderPromotions(ctx, s, order)
+			if err != nil {
+				return err
+			}
+
+			if reason = promotionFits(order, promotion, existing, now); reason != nil {
+				return errPromotionNotUsable
+			}
+
+			promotion.Uses++
+			promotion.Updated_At = now
+			if err := s.Promotions.Update(ctx, promotion); err != nil {
+				return err
+			}
+
+			applied := models.AppliedPromotion{Promotion_ID: promotion.Promotion_ID, Applied_By: c.GetString("userId"), Applied_At: now}
+			if input.Code != nil && promotion.Code != nil {
+				applied.Code = *promotion.Code
+			}
+			order.Promotions = append(order.Promotions, applied)
+			order.Updated_At = now
+			if err := s.Orders.Update(ctx, order); err != nil {
+				return err
+			}
+
+			return rebill(ctx, s, bills[order.Order_ID], false, now)
+		})
+		var changed orderChanged
+		if errors.As(err, &changed) {
+			c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+			return
+		}
+		if errors.Is(err, errPromotionNotUsable) {
+			c.JSON(http.StatusConflict, gin.H{"error": reason.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not apply the promotion!"})
+			return
+		}
+
+		repriced(ctx, s, c, engine, order)
+	}
+}
+
+// RemovePromotion takes a promotion off an order and gives back its use.
+func RemovePromotion(s *store.Store, engine *pricing.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		promotionId := c.Param("promotion_id")
+
+		order, ok := loadOpenOrder(ctx, s, c, c.Param("order_id"))
+		if !ok {
+			return
+		}
+
+		if !order.HasPromotion(promotionId) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "That promotion is not on the order"})
+			return
+		}
+
+		if _, ok := unpaidBills(ctx, s, c, order.Order_ID); !ok {
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		err := s.WithTransaction(ctx, func(ctx context.Context) error {
+			orders, bills, err := reloadOrders(ctx, s, order.Order_ID)
+			if err != nil {
+				return err
+			}
+			order = orders[0]
+
+			if !order.HasPromotion(promotionId) {
+				return orderChanged("That promotion is no longer on the order")
+			}
+
+			kept := []models.AppliedPromotion{}
+			for _, applied := range order.Promotions {
+				if applied.Promotion_ID != promotionId {
+					kept = append(kept, applied)
+				}
+			}
+			order.Promotions = kept
+			order.Updated_At = now
+			if err := s.Orders.Update(ctx, order); err != nil {
+				return err
+			}
+
+			promotion, err := s.Promotions.Get(ctx, promotionId)
+			if errors.Is(err, store.ErrNotFound) {
+				return rebill(ctx, s, bills[order.Order_ID], false, now)
+			}
+			if err != nil {
+				return err
+			}
+
+			if promotion.Uses > 0 {
+				promotion.Uses--
+			}
+			promotion.Updated_At = now
+			if err := s.Promotions.Update(ctx, promotion); err != nil {
+				return err
+			}
+
+			return rebill(ctx, s, bills[order.Order_ID], false, now)
+		})
+		var changed orderChanged
+		if errors.As(err, &changed) {
+			c.JSON(http.StatusConflict, gin.H{"error": changed.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not remove the promotion!"})
+			return
+		}
+
+		repriced(ctx, s, c, engine, order)
+	}
+}
+
+// promotionFits says why promotion cannot go on an order that already has
+// the existing promotions, or returns nil when it can.
+func promotionFits(order models.Order, promotion models.Promotion, existing []models.Promotion, at time.Time) error {
+	if order.HasPromotion(promotion.Promotion_ID) {
+		return errors.New("that promotion is already on the order")
+	}
+
+	if err := promotion.Usable(at); err != nil {
+		return err
+	}
+
+	return promotionStacks(promotion, existing)
+}
+
+// promotionStacks says why promotion cannot be combined with the existing
+// promotions, or returns nil when it can.
+func promotionStacks(promotion models.Promotion, existing []models.Promotion) error {
+	for _, other := range existing {
+		if !promotion.IsStackable() || !other.IsStackable() {
+			return errors.New("that promotion cannot be combined with " + *other.Name)
+		}
+	}
+
+	return nil
+}
+
+// orderPromotions loads the promotions applied to an order, in the order
+// they were applied. Promotions deleted since are left out.
+func orderPromotions(ctx context.Context, s *store.Store, order models.Order) ([]models.Promotion, error) {
+	promotions := []models.Promotion{}
+	for _, applied := range order.Promotions {
+		promotion, err := s.Promotions.Get(ctx, applied.Promotion_ID)
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		promotions = append(promotions, promotion)
+	}
+
+	return promotions, nil
+}
+
+// codeFree normalizes the promotion's code and writes a 409 and returns
+// false when another promotion already has it.
+func codeFree(ctx context.Context, s *store.Store, c *gin.Context, promotion *models.Promotion) bool {
+	if promotion.Code == nil {
+		return true
+	}
+
+	code := models.NormalizeCode(*promotion.Code)
+	if code == "" {
+		promotion.Code = nil
+		return true
+	}
+	promotion.Code = &code
+
+	other, err := s.Promotions.GetByCode(ctx, code)
+	if err == nil && other.Promotion_ID != promotion.Promotion_ID {
+		c.JSON(http.StatusConflict, gin.H{"error": "Another promotion already uses the code " + code})
+		return false
+	}
+
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check the promotion code"})
+		return false
+	}
+
+	return true
+}
+
+// repriced writes the order with its bill as it now stands.
+func repriced(ctx context.Context, s *store.Store, c *gin.Context, engine *pricing.Engine, order models.Order) {
+	summary, err := ItemsByOrder(ctx, s, engine, order.Order_ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while pricing the order"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"order": order, "payment_due": summary.Payment_Due, "breakdown": summary.Breakdown})
+}
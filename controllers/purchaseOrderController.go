@@ -0,0 +1,456 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errDeliveryRefused = errors.New("delivery refused")
+
+// ReorderSuggestion is how much of an ingredient to order to be back at par
+// once the supplier delivers, given how fast it has been used lately.
+type ReorderSuggestion struct {
+	Ingredient_ID  string        `json:"ingredient_id"`
+	Name           string        `json:"name"`
+	Unit           string        `json:"unit"`
+	Supplier_ID    *string       `json:"supplier_id"`
+	On_Hand        float64       `json:"on_hand"`
+	On_Order       float64       `json:"on_order"`
+	Par_Level      float64       `json:"par_level"`
+	Daily_Usage    float64       `json:"daily_usage"`
+	Lead_Time_Days int           `json:"lead_time_days"`
+	Quantity       float64       `json:"quantity"`
+	Estimated_Cost *models.Money `json:"estimated_cost"`
+}
+
+// GetPurchaseOrders lists purchase orders, newest first, optionally for one
+// ?supplier_id or ?ingredient_id and in the given ?status list.
+func GetPurchaseOrders(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		filter := store.PurchaseOrderFilter{Supplier_ID: c.Query("supplier_id"), Ingredient_ID: c.Query("ingredient_id")}
+		for _, status := range queryList(c, "status") {
+			if err := validate.Var(status, "oneof=DRAFT ORDERED PARTIALLY_RECEIVED RECEIVED CANCELLED"); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": status + " is not a purchase order status"})
+				return
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+
+		orders, err := s.PurchaseOrders.List(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the purchase orders!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, orders)
+	}
+}
+
+// CreatePurchaseOrder starts a draft order; it goes to the supplier once
+// moved to ORDERED.
+func CreatePurchaseOrder(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var order models.PurchaseOrder
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&order); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !purchaseOrderValid(ctx, s, c, &order) {
+			return
+		}
+
+		order.Status = models.PurchaseDraft
+		order.Deliveries = []models.Delivery{}
+		order.Ordered_At = nil
+		order.Created_By = c.GetString("userId")
+		order.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		order.ID = primitive.NewObjectID()
+		order.Purchase_Order_ID = order.ID.Hex()
+
+		if err := s.PurchaseOrders.Create(ctx, order); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Purchase order was not created!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, order)
+	}
+}
+
+func GetPurchaseOrder(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		order, err := s.PurchaseOrders.Get(ctx, c.Param("purchase_order_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that purchase order"})
+			return
+		}
+
+		c.JSON(http.StatusOK, order)
+	}
+}
+
+// UpdatePurchaseOrder changes a draft. Once ordered, what was agreed with
+// the supplier is kept as it was.
+func UpdatePurchaseOrder(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.PurchaseOrder
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		order, err := s.PurchaseOrders.Get(ctx, c.Param("purchase_order_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find purchase order with that id"})
+			return
+		}
+
+		if !order.Editable() {
+			c.JSON(http.StatusConflict, gin.H{"error": "A purchase order cannot be changed once it is " + order.Status})
+			return
+		}
+
+		if input.Supplier_ID != nil {
+			order.Supplier_ID = input.Supplier_ID
+		}
+
+		if input.Lines != nil {
+			order.Lines = input.Lines
+		}
+
+		if input.Expected_At != nil {
+			order.Expected_At = input.Expected_At
+		}
+
+		if !purchaseOrderValid(ctx, s, c, &order) {
+			return
+		}
+
+		order.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.PurchaseOrders.Update(ctx, order); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update purchase order!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, order)
+	}
+}
+
+// DeletePurchaseOrder removes an order nothing was delivered against.
+func DeletePurchaseOrder(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		purchaseOrderId := c.Param("purchase_order_id")
+
+		order, err := s.PurchaseOrders.Get(ctx, purchaseOrderId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find purchase order with that id"})
+			return
+		}
+
+		if order.Status != models.PurchaseDraft && order.Status != models.PurchaseCancelled {
+			c.JSON(http.StatusConflict, gin.H{"error": "Only draft or cancelled purchase orders can be deleted"})
+			return
+		}
+
+		if err := s.PurchaseOrders.Delete(ctx, purchaseOrderId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete purchase order!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, order)
+	}
+}
+
+// TransitionPurchaseOrder sends a draft to the supplier, cancels an order
+// nothing has arrived on, or closes a part delivered order short.
+func TransitionPurchaseOrder(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Status *string `json:"status" validate:"required,eq=ORDERED|eq=RECEIVED|eq=CANCELLED"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		order, err := s.PurchaseOrders.Get(ctx, c.Param("purchase_order_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find purchase order with that id"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := order.MoveTo(*input.Status, now); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := s.PurchaseOrders.Update(ctx, order); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update purchase order!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, order)
+	}
+}
+
+// ReceiveDelivery books stock that arrived against a purchase order into
+// inventory and records what it cost.
+func ReceiveDelivery(s *store.Store, inv *inventory.Inventory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var delivery models.Delivery
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&delivery); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(delivery); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		delivery.Received_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		delivery.Received_By = c.GetString("userId")
+		delivery.Delivery_ID = primitive.NewObjectID().Hex()
+
+		var order models.PurchaseOrder
+		var reason error
+		err := s.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			if order, err = s.PurchaseOrders.Get(ctx, c.Param("purchase_order_id")); err != nil {
+				return err
+			}
+
+			if reason = order.Receive(&delivery); reason != nil {
+				return errDeliveryRefused
+			}
+
+			if err := s.PurchaseOrders.Update(ctx, order); err != nil {
+				return err
+			}
+
+			for _, line := range delivery.Lines {
+				if _, err := moveStock(ctx, s, inv, line.Ingredient_ID, line.Quantity); err != nil {
+					return err
+				}
+
+				cost := models.StockCost{Cost: *line.Cost, Quantity: line.Quantity}
+				if err := s.Ingredients.SetCost(ctx, line.Ingredient_ID, cost, delivery.Received_At); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if errors.Is(err, errDeliveryRefused) {
+			c.JSON(http.StatusConflict, gin.H{"error": reason.Error()})
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that purchase order or one of its ingredients"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not receive the delivery!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, order)
+	}
+}
+
+// GetReorderSuggestions suggests what to order for ingredients with a par
+// level: enough to be back at par after the supplier's lead time at the
+// rate used over the last ?days (14 by default), less what is already on
+// order. ?supplier_id narrows it to one supplier's ingredients.
+func GetReorderSuggestions(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		days := 14
+		if c.Query("days") != "" {
+			var err error
+			if days, err = strconv.Atoi(c.Query("days")); err != nil || days < 1 || days > 90 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "days must be a whole number from 1 to 90"})
+				return
+			}
+		}
+
+		ingredients, err := s.Ingredients.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the ingredients!"})
+			return
+		}
+
+		used, err := stockUsedSince(ctx, s, time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while working out recent usage!"})
+			return
+		}
+
+		open, err := s.PurchaseOrders.List(ctx, store.PurchaseOrderFilter{Statuses: []string{models.PurchaseOrdered, models.PurchasePartial}})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing open purchase orders!"})
+			return
+		}
+
+		suppliers := map[string]models.Supplier{}
+		suggestions := []ReorderSuggestion{}
+		for _, ingredient := range ingredients {
+			if ingredient.Par_Level == nil {
+				continue
+			}
+
+			if supplierId := c.Query("supplier_id"); supplierId != "" && (ingredient.Supplier_ID == nil || *ingredient.Supplier_ID != supplierId) {
+				continue
+			}
+
+			suggestion := ReorderSuggestion{
+				Ingredient_ID: ingredient.Ingredient_ID,
+				Name:          *ingredient.Name,
+				Unit:          *ingredient.Unit,
+				Supplier_ID:   ingredient.Supplier_ID,
+				On_Hand:       ingredient.On_Hand,
+				Par_Level:     *ingredient.Par_Level,
+				Daily_Usage:   used[ingredient.Ingredient_ID] / float64(days),
+			}
+
+			for _, order := range open {
+				suggestion.On_Order += order.Outstanding(ingredient.Ingredient_ID)
+			}
+
+			if ingredient.Supplier_ID != nil {
+				supplier, ok := suppliers[*ingredient.Supplier_ID]
+				if !ok {
+					supplier, err = s.Suppliers.Get(ctx, *ingredient.Supplier_ID)
+					if err != nil && !errors.Is(err, store.ErrNotFound) {
+						c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not load the supplier for " + *ingredient.Name})
+						return
+					}
+					suppliers[*ingredient.Supplier_ID] = supplier
+				}
+				suggestion.Lead_Time_Days = supplier.LeadTime()
+			}
+
+			suggestion.Quantity = suggestion.Par_Level + suggestion.Daily_Usage*float64(suggestion.Lead_Time_Days) - suggestion.On_Hand - suggestion.On_Order
+			if suggestion.Quantity <= 0 {
+				continue
+			}
+
+			if ingredient.Last_Cost != nil {
+				cost := ingredient.Last_Cost.Of(suggestion.Quantity)
+				suggestion.Estimated_Cost = &cost
+			}
+
+			suggestions = append(suggestions, suggestion)
+		}
+
+		sort.SliceStable(suggestions, func(i, j int) bool {
+			return supplierKey(suggestions[i]) < supplierKey(suggestions[j])
+		})
+
+		c.JSON(http.StatusOK, suggestions)
+	}
+}
+
+// purchaseOrderValid writes a 400 and returns false unless the order is
+// well formed, from a known supplier and for ingredients that are kept.
+func purchaseOrderValid(ctx context.Context, s *store.Store, c *gin.Context, order *models.PurchaseOrder) bool {
+	if err := validate.Struct(order); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if err := order.Check(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	if !supplierKnown(ctx, s, c, *order.Supplier_ID) {
+		return false
+	}
+
+	recipe := []models.RecipeLine{}
+	for i := range order.Lines {
+		order.Lines[i].Received = 0
+		recipe = append(recipe, models.RecipeLine{Ingredient_ID: order.Lines[i].Ingredient_ID, Quantity: order.Lines[i].Quantity})
+	}
+
+	return recipeStocked(ctx, s, c, recipe)
+}
+
+// stockUsedSince adds up what ordered items and waste have taken from stock
+// since.
+func stockUsedSince(ctx context.Context, s *store.Store, since time.Time) (map[string]float64, error) {
+	orderItems, err := s.OrderItems.ListDepleted(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]float64{}
+	for _, orderItem := range orderItems {
+		for _, line := range orderItem.Stock_Used {
+			used[line.Ingredient_ID] += line.Quantity
+		}
+	}
+
+	entries, err := s.Waste.List(ctx, store.WasteFilter{From: since})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		for _, line := range entry.Stock_Used {
+			used[line.Ingredient_ID] += line.Quantity
+		}
+	}
+
+	return used, nil
+}
+
+// supplierKey groups suggestions by supplier, those with none last.
+func supplierKey(suggestion ReorderSuggestion) string {
+	if suggestion.Supplier_ID == nil {
+		return "~"
+	}
+
+	return *suggestion.Supplier_ID
+}
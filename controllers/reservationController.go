@@ -0,0 +1,386 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/booking"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errNoTableFree = errors.New("no table is free for that party at that time")
+var errTableTaken = errors.New("that table is already booked at that time")
+var errTableTooSmall = errors.New("that table does not seat the party")
+
+// alternativeSlots is how many other start times a full booking suggests.
+const alternativeSlots = 3
+
+func GetReservations(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		day, next, err := planner.Day(c.Query("date"), time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reservations, err := s.Reservations.Between(ctx, day, next)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching reservations!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, reservations)
+	}
+}
+
+func GetReservation(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		reservation, err := s.Reservations.Get(ctx, c.Param("reservation_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that reservation"})
+			return
+		}
+
+		c.JSON(http.StatusOK, reservation)
+	}
+}
+
+// CreateReservation books a table for the party. A table_id may be asked
+// for; otherwise the smallest free table that seats the party is taken.
+func CreateReservation(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var reservation models.Reservation
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&reservation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(reservation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := planner.CheckStart(*reservation.Start_Time, time.Now()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reservation.ID = primitive.NewObjectID()
+		reservation.Reservation_ID = reservation.ID.Hex()
+		reservation.End_Time = planner.End(*reservation.Start_Time, *reservation.Party_Size)
+		reservation.Status = models.ReservationBooked
+		reservation.Booked_By = c.GetString("userId")
+		reservation.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		reservation.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		requested := reservation.Table_ID
+		err := s.WithTransaction(ctx, func(ctx context.Context) error {
+			table, err := assignTable(ctx, s, planner, reservation, requested, true)
+			if err != nil {
+				return err
+			}
+
+			if err := s.Tables.Hold(ctx, table.Table_ID); err != nil {
+				return err
+			}
+
+			reservation.Table_ID = table.Table_ID
+			return s.Reservations.Create(ctx, reservation)
+		})
+		if err != nil {
+			bookingFailed(ctx, s, planner, c, reservation, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, reservation)
+	}
+}
+
+// UpdateReservation changes a booking. The party keeps its table when the
+// table still works for the new size and time, and moves otherwise.
+func UpdateReservation(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.Reservation
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reservation, err := s.Reservations.Get(ctx, c.Param("reservation_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that reservation"})
+			return
+		}
+
+		if reservation.Status != models.ReservationBooked {
+			c.JSON(http.StatusConflict, gin.H{"error": "A reservation that is " + reservation.Status + " cannot be changed"})
+			return
+		}
+
+		if input.Guest_Name != nil {
+			reservation.Guest_Name = input.Guest_Name
+		}
+
+		if input.Guest_Phone != nil {
+			reservation.Guest_Phone = input.Guest_Phone
+		}
+
+		if input.Guest_Email != nil {
+			reservation.Guest_Email = input.Guest_Email
+		}
+
+		if input.Notes != nil {
+			reservation.Notes = input.Notes
+		}
+
+		if input.Party_Size != nil {
+			reservation.Party_Size = input.Party_Size
+		}
+
+		if input.Start_Time != nil {
+			if err := planner.CheckStart(*input.Start_Time, time.Now()); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			reservation.Start_Time = input.Start_Time
+		}
+
+		if err := validate.Struct(reservation); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		reservation.End_Time = planner.End(*reservation.Start_Time, *reservation.Party_Size)
+		reservation.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		preferred, strict := reservation.Table_ID, false
+		if input.Table_ID != "" {
+			preferred, strict = input.Table_ID, true
+		}
+
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			table, err := assignTable(ctx, s, planner, reservation, preferred, strict)
+			if err != nil {
+				return err
+			}
+
+			if err := s.Tables.Hold(ctx, table.Table_ID); err != nil {
+				return err
+			}
+
+			reservation.Table_ID = table.Table_ID
+			return s.Reservations.Update(ctx, reservation)
+		})
+		if err != nil {
+			bookingFailed(ctx, s, planner, c, reservation, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, reservation)
+	}
+}
+
+func CancelReservation(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return moveReservation(s, planner, models.ReservationCancelled)
+}
+
+func SeatReservation(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return moveReservation(s, planner, models.ReservationSeated)
+}
+
+// MarkNoShow releases the table of a party that did not turn up. It is only
+// allowed once the no-show grace period after the booked time has passed.
+func MarkNoShow(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return moveReservation(s, planner, models.ReservationNoShow)
+}
+
+func moveReservation(s *store.Store, planner *booking.Planner, status string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		reservation, err := s.Reservations.Get(ctx, c.Param("reservation_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that reservation"})
+			return
+		}
+
+		if status == models.ReservationNoShow && time.Now().Before(planner.NoShowAfter(reservation)) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The party is not late enough to be a no-show", "no_show_after": planner.NoShowAfter(reservation)})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := reservation.MoveTo(status, now); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := s.Reservations.Update(ctx, reservation); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the reservation!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, reservation)
+	}
+}
+
+// GetTableAvailability lists the start times on a day at which a party of
+// the given size can be seated.
+func GetTableAvailability(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		party, err := strconv.Atoi(c.Query("party"))
+		if err != nil || party < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "party must be a number of guests"})
+			return
+		}
+
+		day, _, err := planner.Day(c.Query("date"), time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		slots, err := availableSlots(ctx, s, planner, party, day)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while checking availability"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"date":         day.Format("2006-01-02"),
+			"party_size":   party,
+			"turn_minutes": int(planner.TurnTime(party).Minutes()),
+			"slots":        slots,
+		})
+	}
+}
+
+// assignTable picks the table for reservation. The preferred table is kept
+// if it is free; when strict, it must be. It runs in the transaction that
+// saves the booking, which holds the table it picked so that a concurrent
+// booking of the same table conflicts.
+func assignTable(ctx context.Context, s *store.Store, planner *booking.Planner, reservation models.Reservation, preferred string, strict bool) (models.Table, error) {
+	tables, err := s.Tables.List(ctx)
+	if err != nil {
+		return models.Table{}, err
+	}
+
+	from, to := planner.Window(*reservation.Start_Time, *reservation.Party_Size)
+	booked, err := s.Reservations.Between(ctx, from, to)
+	if err != nil {
+		return models.Table{}, err
+	}
+
+	free := planner.Free(tables, booked, *reservation.Party_Size, *reservation.Start_Time, reservation.Reservation_ID)
+	if preferred != "" {
+		for _, table := range free {
+			if table.Table_ID == preferred {
+				return table, nil
+			}
+		}
+
+		if strict {
+			table, err := s.Tables.Get(ctx, preferred)
+			if err != nil {
+				return table, err
+			}
+
+			if table.Seats() < *reservation.Party_Size {
+				return table, errTableTooSmall
+			}
+			return table, errTableTaken
+		}
+	}
+
+	if len(free) == 0 {
+		return models.Table{}, errNoTableFree
+	}
+
+	return free[0], nil
+}
+
+func bookingFailed(ctx context.Context, s *store.Store, planner *booking.Planner, c *gin.Context, reservation models.Reservation, err error) {
+	switch {
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find that table"})
+	case errors.Is(err, errTableTooSmall):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errNoTableFree), errors.Is(err, errTableTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "alternatives": alternatives(ctx, s, planner, reservation)})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "The reservation could not be saved!"})
+	}
+}
+
+// alternatives suggests the free start times closest to the one asked for
+// on the same day.
+func alternatives(ctx context.Context, s *store.Store, planner *booking.Planner, reservation models.Reservation) []time.Time {
+	start := *reservation.Start_Time
+	day, _, _ := planner.Day(start.In(planner.Location()).Format("2006-01-02"), time.Now())
+
+	slots, err := availableSlots(ctx, s, planner, *reservation.Party_Size, day)
+	if err != nil {
+		return []time.Time{}
+	}
+
+	free := []time.Time{}
+	for _, slot := range slots {
+		if slot.Available {
+			free = append(free, slot.Start)
+		}
+	}
+
+	distance := func(at time.Time) time.Duration {
+		if at.Before(start) {
+			return start.Sub(at)
+		}
+		return at.Sub(start)
+	}
+	sort.SliceStable(free, func(i, j int) bool { return distance(free[i]) < distance(free[j]) })
+
+	if len(free) > alternativeSlots {
+		free = free[:alternativeSlots]
+	}
+	sort.Slice(free, func(i, j int) bool { return free[i].Before(free[j]) })
+
+	return free
+}
+
+func availableSlots(ctx context.Context, s *store.Store, planner *booking.Planner, party int, day time.Time) ([]booking.Slot, error) {
+	tables, err := s.Tables.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Bookings from the evening before or running past midnight can still
+	// hold tables during the day.
+	booked, err := s.Reservations.Between(ctx, day.AddDate(0, 0, -1), day.AddDate(0, 0, 2))
+	if err != nil {
+		return nil, err
+	}
+
+	return planner.Availability(tables, booked, party, day, time.Now()), nil
+}
@@ -0,0 +1,49 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/booking"
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+func newReservation(name string, start time.Time, tableId string) models.Reservation {
+	phone, size := "+15550100", 2
+	return models.Reservation{Guest_Name: &name, Guest_Phone: &phone, Party_Size: &size, Start_Time: &start, Table_ID: tableId}
+}
+
+// TestCreateReservationRace checks that parties booking the same table for
+// the same time at once do not both get it.
+func TestCreateReservationRace(t *testing.T) {
+	env := newTestEnv(t)
+	planner, err := booking.NewPlanner(booking.DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.router.POST("/reservations", CreateReservation(env.s, planner))
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+	start := time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 19, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		tableId string
+	}{
+		{name: "the table asked for", tableId: env.table.Table_ID},
+		{name: "any free table"},
+	}
+
+	for i, tt := range tests {
+		// Each case books a different evening so they do not clash.
+		start := start.AddDate(0, 0, i)
+		codes := env.race(t, 5, "POST", "/reservations", func(i int) interface{} {
+			return newReservation("Guest "+strconv.Itoa(i), start, tt.tableId)
+		})
+		if codes[http.StatusAccepted] != 1 || codes[http.StatusConflict] != 4 {
+			t.Errorf("%s: got %v, want one booking accepted and the rest refused", tt.name, codes)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func GetSuppliers(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		allSuppliers, err := s.Suppliers.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the suppliers!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, allSuppliers)
+	}
+}
+
+func CreateSupplier(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var supplier models.Supplier
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&supplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(supplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		supplier.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		supplier.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		supplier.ID = primitive.NewObjectID()
+		supplier.Supplier_ID = supplier.ID.Hex()
+
+		if err := s.Suppliers.Create(ctx, supplier); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Supplier was not created!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, supplier)
+	}
+}
+
+func GetSupplier(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		supplier, err := s.Suppliers.Get(ctx, c.Param("supplier_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that supplier"})
+			return
+		}
+
+		c.JSON(http.StatusOK, supplier)
+	}
+}
+
+func UpdateSupplier(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.Supplier
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		supplier, err := s.Suppliers.Get(ctx, c.Param("supplier_id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find supplier with that id"})
+			return
+		}
+
+		if input.Name != nil {
+			supplier.Name = input.Name
+		}
+
+		if input.Contact_Name != nil {
+			supplier.Contact_Name = input.Contact_Name
+		}
+
+		if input.Email != nil {
+			supplier.Email = input.Email
+		}
+
+		if input.Phone != nil {
+			supplier.Phone = input.Phone
+		}
+
+		if input.Lead_Time_Days != nil {
+			supplier.Lead_Time_Days = input.Lead_Time_Days
+		}
+
+		if err := validate.Struct(supplier); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		supplier.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Suppliers.Update(ctx, supplier); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update supplier!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, supplier)
+	}
+}
+
+// DeleteSupplier removes a supplier that has never been sent a purchase
+// order; those orders are the record of what was bought from them.
+func DeleteSupplier(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		supplierId := c.Param("supplier_id")
+
+		supplier, err := s.Suppliers.Get(ctx, supplierId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find supplier with that id"})
+			return
+		}
+
+		orders, err := s.PurchaseOrders.List(ctx, store.PurchaseOrderFilter{Supplier_ID: supplierId})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check the supplier's purchase orders"})
+			return
+		}
+
+		if len(orders) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The supplier has purchase orders"})
+			return
+		}
+
+		if err := s.Suppliers.Delete(ctx, supplierId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete supplier!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, supplier)
+	}
+}
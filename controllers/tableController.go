@@ -2,41 +2,32 @@ package controllers
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jamesconfy/restaurant-management/database"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/jamesconfy/restaurant-management/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var tableCollection *mongo.Collection = database.OpenCollection(database.Client, "table")
-
-func GetTables() gin.HandlerFunc {
+func GetTables(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var allTables []bson.M
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		result, err := tableCollection.Find(context.TODO(), bson.M{})
+		allTables, err := s.Tables.List(ctx)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching tables!"})
 			return
 		}
 
-		if err = result.All(ctx, &allTables); err != nil {
-			log.Fatal(err.Error())
-		}
 		c.JSON(http.StatusOK, allTables)
 	}
 }
 
-func CreateTable() gin.HandlerFunc {
+func CreateTable(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var table models.Table
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
@@ -53,29 +44,34 @@ func CreateTable() gin.HandlerFunc {
 			return
 		}
 
+		if err := table.CheckCapacity(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		table.Dirty_Since = nil
 		table.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		table.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
 		table.ID = primitive.NewObjectID()
 		table.Table_ID = table.ID.Hex()
 
-		result, insertEr := tableCollection.InsertOne(ctx, &table)
-		if insertEr != nil {
+		if err := s.Tables.Create(ctx, table); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Table was not created!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
+		c.JSON(http.StatusAccepted, table)
 	}
 }
 
-func GetTable() gin.HandlerFunc {
+func GetTable(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var table models.Table
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		tableId := c.Param("table_id")
 
-		if err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table); err != nil {
+		table, err := s.Tables.Get(ctx, tableId)
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"message": "Cannot find that table"})
 			return
 		}
@@ -84,61 +80,82 @@ func GetTable() gin.HandlerFunc {
 	}
 }
 
-func UpdateTable() gin.HandlerFunc {
+func UpdateTable(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var table models.Table
-		var updateObj primitive.D
+		var input models.Table
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
-		if err := c.BindJSON(&table); err != nil {
+		if err := c.BindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
 		tableId := c.Param("table_id")
-		filter := bson.M{"table_id": tableId}
+		table, err := s.Tables.Get(ctx, tableId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find table with that id"})
+			return
+		}
 
-		if table.Number_Of_Guests != nil {
-			updateObj = append(updateObj, bson.E{Key: "number_of_guests", Value: &table.Number_Of_Guests})
+		if input.Number_Of_Guests != nil {
+			table.Number_Of_Guests = input.Number_Of_Guests
 		}
 
-		if table.Table_Number != nil {
-			updateObj = append(updateObj, bson.E{Key: "number_of_guests", Value: &table.Table_Number})
+		if input.Table_Number != nil {
+			table.Table_Number = input.Table_Number
 		}
 
-		updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-		updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updated_at})
+		if input.Section != nil {
+			table.Section = input.Section
+		}
 
-		upsert := true
-		opt := options.UpdateOptions{
-			Upsert: &upsert,
+		if input.Layout != nil {
+			table.Layout = input.Layout
 		}
 
-		result, err := tableCollection.UpdateOne(ctx, filter, bson.D{{Key: "set", Value: updateObj}}, &opt)
-		if err != nil {
+		if input.Min_Guests != nil {
+			table.Min_Guests = input.Min_Guests
+		}
+
+		if err := validate.Struct(table); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := table.CheckCapacity(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		table.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Tables.Update(ctx, table); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update table!"})
 			return
 		}
 
-		c.JSON(http.StatusAccepted, result)
+		c.JSON(http.StatusAccepted, table)
 	}
 }
 
-func DeleteTable() gin.HandlerFunc {
+func DeleteTable(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var table models.Table
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 		tableId := c.Param("table_id")
 
-		err := tableCollection.FindOne(ctx, bson.M{"table_id": tableId}).Decode(&table)
+		table, err := s.Tables.Get(ctx, tableId)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find table with that id"})
 			return
 		}
 
-		result := tableCollection.FindOneAndDelete(ctx, bson.M{"table_id": tableId}).Decode(&table)
-		c.JSON(http.StatusAccepted, result)
+		if err := s.Tables.Delete(ctx, tableId); err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete table!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, table)
 	}
 }
@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/payments"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
+)
+
+func GetInvoiceTransactions(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		invoiceId := c.Param("invoice_id")
+		if _, err := s.Invoices.Get(ctx, invoiceId); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find invoice with that id"})
+			return
+		}
+
+		transactions, err := s.Transactions.ListByInvoice(ctx, invoiceId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching payment transactions!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, transactions)
+	}
+}
+
+// PaymentWebhook applies what the provider reports about a transaction. A
+// confirmation marks the transaction reconciled; a capture that failed after
+// the fact takes its payment back off the invoice.
+func PaymentWebhook(s *store.Store, engine *pricing.Engine, provider payments.PaymentProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if c.Param("provider") != provider.Name() {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment provider"})
+			return
+		}
+
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		event, err := provider.VerifyWebhook(c.Request.Header, body)
+		if errors.Is(err, payments.ErrInvalidSignature) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid webhook signature"})
+			return
+		}
+
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		transaction, err := s.Transactions.GetByReference(ctx, provider.Name(), event.Reference)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No transaction has that reference"})
+			return
+		}
+
+		if transaction.SeenEvent(event.Event_ID) {
+			c.JSON(http.StatusOK, gin.H{"message": "Event already processed"})
+			return
+		}
+
+		status, ok := webhookStatus(event.Type, transaction.Status)
+		if !ok {
+			c.JSON(http.StatusOK, gin.H{"message": "Event ignored"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		recorded := models.TransactionEvent{Kind: models.EventWebhook, Status: status, Amount: event.Amount, Event_ID: event.Event_ID, Reason: event.Reason, At: now}
+
+		// The status only moves on from what was read above, so a webhook
+		// delivered twice, or racing a change made here, is applied once.
+		var applied bool
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			if applied, err = s.Transactions.ApplyWebhook(ctx, transaction.Transaction_ID, transaction.Status, recorded); err != nil || !applied {
+				return err
+			}
+
+			if status == models.TransactionFailed && transaction.Status != models.TransactionFailed {
+				return reversePayment(ctx, s, engine, transaction)
+			}
+
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not apply the event"})
+			return
+		}
+
+		if !applied {
+			if current, err := s.Transactions.Get(ctx, transaction.Transaction_ID); err == nil && current.SeenEvent(event.Event_ID) {
+				c.JSON(http.StatusOK, gin.H{"message": "Event already processed"})
+				return
+			}
+
+			c.JSON(http.StatusConflict, gin.H{"error": "The transaction changed while the event was applied; send it again"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Event processed"})
+	}
+}
+
+// webhookStatus is the status a transaction moves to when the provider
+// reports eventType about it while it is in current. It returns false for
+// events that do not apply in that status, such as a capture confirmation
+// for a payment already known to have failed.
+func webhookStatus(eventType, current string) (string, bool) {
+	switch eventType {
+	case payments.EventCaptured:
+		switch current {
+		case models.TransactionAuthorized:
+			return models.TransactionCaptured, true
+		case models.TransactionCaptured, models.TransactionRefunded:
+			return current, true
+		}
+	case payments.EventRefunded:
+		switch current {
+		case models.TransactionCaptured, models.TransactionRefunded:
+			return current, true
+		}
+	case payments.EventVoided:
+		switch current {
+		case models.TransactionAuthorized, models.TransactionVoided, models.TransactionFailed:
+			return models.TransactionVoided, true
+		}
+	case payments.EventFailed:
+		switch current {
+		case models.TransactionAuthorized, models.TransactionCaptured, models.TransactionFailed:
+			return models.TransactionFailed, true
+		}
+	}
+
+	return current, false
+}
+
+// reversePayment takes the payment behind transaction off its invoice.
+func reversePayment(ctx context.Context, s *store.Store, engine *pricing.Engine, transaction models.PaymentTransaction) error {
+	invoice, err := s.Invoices.Get(ctx, transaction.Invoice_ID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	kept := []models.Payment{}
+	for _, payment := range invoice.Payments {
+		if payment.Transaction_ID != transaction.Transaction_ID {
+			kept = append(kept, payment)
+		}
+	}
+
+	if len(kept) == len(invoice.Payments) {
+		return nil
+	}
+
+	summary, err := ItemsByOrder(ctx, s, engine, invoice.Order_ID)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("payments: %s reported transaction %s as failed; removing it from invoice %s", transaction.Provider, transaction.Transaction_ID, invoice.Invoice_ID)
+	invoice.Payments = kept
+	settleInvoice(&invoice, summary.Payment_Due)
+
+	return s.Invoices.Update(ctx, invoice)
+}
@@ -2,26 +2,22 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jamesconfy/restaurant-management/database"
 	helper "github.com/jamesconfy/restaurant-management/helpers"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
+	"github.com/jamesconfy/restaurant-management/store"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
 
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
-
-func GetUsers() gin.HandlerFunc {
+func GetUsers(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var allUsers []bson.M
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
@@ -36,42 +32,25 @@ func GetUsers() gin.HandlerFunc {
 		}
 
 		startIndex := (page - 1) * recordPerPage
-		// startIndex, err := strconv.Atoi(c.Query("startIndex"))
-		// if err != nil {
-
-		// }
 
-		matchStage := bson.D{{Key: "$match", Value: bson.D{{}}}}
-		projectStage := bson.D{
-			{Key: "$project", Value: bson.D{
-				{Key: "_id", Value: 0},
-				{Key: "total_count", Value: 1},
-				{Key: "user_items", Value: bson.D{{Key: "$slice", Value: []interface{}{"$data", startIndex, recordPerPage}}}},
-			}}}
-
-		result, err := userCollection.Aggregate(ctx, mongo.Pipeline{matchStage, projectStage})
+		users, total, err := s.Users.List(ctx, startIndex, recordPerPage)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while getting all users"})
 			return
 		}
 
-		if err := result.All(ctx, &allUsers); err != nil {
-			log.Fatal(err)
-		}
-
-		c.JSON(http.StatusOK, allUsers)
+		c.JSON(http.StatusOK, gin.H{"total_count": total, "user_items": users})
 	}
 }
 
-func GetUser() gin.HandlerFunc {
+func GetUser(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var user models.User
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
 		userId := c.Param("user_id")
 
-		err := userCollection.FindOne(ctx, bson.M{"user_id": userId}).Decode(&user)
+		user, err := s.Users.Get(ctx, userId)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find that user"})
 			return
@@ -81,7 +60,7 @@ func GetUser() gin.HandlerFunc {
 	}
 }
 
-func Register() gin.HandlerFunc {
+func Register(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var user models.User
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
@@ -97,7 +76,7 @@ func Register() gin.HandlerFunc {
 			return
 		}
 
-		count, err := userCollection.CountDocuments(ctx, bson.M{"email": user.Email})
+		count, err := s.Users.CountByEmail(ctx, *user.Email)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -108,7 +87,7 @@ func Register() gin.HandlerFunc {
 			return
 		}
 
-		count, err = userCollection.CountDocuments(ctx, bson.M{"phone": user.Phone})
+		count, err = s.Users.CountByPhone(ctx, *user.Phone)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -119,6 +98,11 @@ func Register() gin.HandlerFunc {
 			return
 		}
 
+		// Everyone starts as a customer until an admin assigns them a role.
+		// The first admin is created by BootstrapAdmin.
+		role := models.RoleCustomer
+		user.Role = &role
+
 		password := HashPassword(*user.Password)
 		user.Password = &password
 		user.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
@@ -126,25 +110,87 @@ func Register() gin.HandlerFunc {
 		user.ID = primitive.NewObjectID()
 		user.User_ID = user.ID.Hex()
 
-		token, refreshToken, _ := helper.GenerateAllToken(*user.Email, user.User_ID, *user.First_Name, *user.Last_Name)
+		token, refreshToken, _ := helper.GenerateAllToken(*user.Email, *user.First_Name, *user.Last_Name, user.User_ID, role)
 		user.Token = &token
 		user.Refresh_Token = &refreshToken
 
-		result, err := userCollection.InsertOne(ctx, &user)
-		if err != nil {
+		if err := s.Users.Create(ctx, user); err != nil {
+			if errors.Is(err, store.ErrDuplicate) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Email already exists!"})
+				return
+			}
+
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "User not created!"})
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		c.JSON(http.StatusOK, user)
+
+	}
+}
+
+// BootstrapAdmin creates the admin account for email at startup, unless an
+// account with that email exists already. Creating it is a single insert
+// against a unique email, so servers starting together cannot create it
+// twice. With no email given it only warns when there is no admin at all.
+func BootstrapAdmin(ctx context.Context, s *store.Store, email, password string) error {
+	if email == "" {
+		admins, err := s.Users.CountByRole(ctx, models.RoleAdmin)
+		if err != nil {
+			return err
+		}
+
+		if admins == 0 {
+			log.Printf("users: there is no admin account; set ADMIN_EMAIL and ADMIN_PASSWORD to create one")
+		}
+		return nil
+	}
+
+	if len(password) < 6 {
+		return errors.New("users: ADMIN_PASSWORD must be at least 6 characters")
+	}
+
+	var created bool
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		created = false
+		count, err := s.Users.CountByEmail(ctx, email)
+		if err != nil || count > 0 {
+			return err
+		}
+
+		firstName, lastName, role := "Admin", "Admin", models.RoleAdmin
+		hashed := HashPassword(password)
+		user := models.User{
+			First_Name: &firstName,
+			Last_Name:  &lastName,
+			Password:   &hashed,
+			Email:      &email,
+			Role:       &role,
+		}
+		user.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		user.Updated_At = user.Created_At
+		user.ID = primitive.NewObjectID()
+		user.User_ID = user.ID.Hex()
 
+		if err := s.Users.Create(ctx, user); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if errors.Is(err, store.ErrDuplicate) {
+		return nil
+	}
+
+	if created && err == nil {
+		log.Printf("users: created the admin account %s", email)
 	}
+	return err
 }
 
-func Login() gin.HandlerFunc {
+func Login(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var user models.User
-		var foundUser models.User
 		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 		defer cancel()
 
@@ -153,7 +199,12 @@ func Login() gin.HandlerFunc {
 			return
 		}
 
-		err := userCollection.FindOne(ctx, bson.M{"email": user.Email}).Decode(&foundUser)
+		if user.Email == nil || user.Password == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Email and password are required"})
+			return
+		}
+
+		foundUser, err := s.Users.GetByEmail(ctx, *user.Email)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "We can't seem to find that user in our database!"})
 			return
@@ -164,17 +215,166 @@ func Login() gin.HandlerFunc {
 			return
 		}
 
-		token, refreshToken, _ := helper.GenerateAllToken(*foundUser.Email, foundUser.User_ID, *foundUser.First_Name, *foundUser.Last_Name)
-		err = helper.UpdateToken(token, refreshToken, foundUser.User_ID)
+		token, refreshToken, _ := helper.GenerateAllToken(*foundUser.Email, *foundUser.First_Name, *foundUser.Last_Name, foundUser.User_ID, foundUser.UserRole())
+		err = helper.UpdateToken(s.Users, token, refreshToken, foundUser.User_ID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
 			return
 		}
 
+		foundUser.Token = &token
+		foundUser.Refresh_Token = &refreshToken
+
 		c.JSON(http.StatusOK, foundUser)
 	}
 }
 
+func RefreshToken(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Refresh_Token string `json:"refresh_token" validate:"required"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		claims, err := helper.ValidateToken(ctx, s.Revocations, input.Refresh_Token)
+		if err != nil || claims.Token_Type != models.RefreshToken {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+
+		user, err := s.Users.Get(ctx, claims.User_ID)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+
+		// Only the refresh token stored on the user may be exchanged.
+		if user.Refresh_Token == nil || *user.Refresh_Token != input.Refresh_Token {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+			return
+		}
+
+		// Spending the refresh token fails for every request but the first,
+		// so two refreshes racing with the same token cannot both succeed.
+		if err := helper.SpendToken(ctx, s.Revocations, input.Refresh_Token); err != nil {
+			if errors.Is(err, store.ErrDuplicate) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+				return
+			}
+
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+			return
+		}
+
+		if user.Token != nil {
+			if err := helper.RevokeToken(ctx, s.Revocations, *user.Token); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+				return
+			}
+		}
+
+		token, refreshToken, err := helper.GenerateAllToken(*user.Email, *user.First_Name, *user.Last_Name, user.User_ID, user.UserRole())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+			return
+		}
+
+		if err := helper.UpdateToken(s.Users, token, refreshToken, user.User_ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"token": token, "refresh_token": refreshToken})
+	}
+}
+
+func Logout(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := helper.RevokeToken(ctx, s.Revocations, c.GetString("token")); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+			return
+		}
+
+		user, err := s.Users.Get(ctx, c.GetString("userId"))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+			return
+		}
+
+		if user.Refresh_Token != nil {
+			if err := helper.RevokeToken(ctx, s.Revocations, *user.Refresh_Token); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+				return
+			}
+		}
+
+		if user.Token != nil {
+			if err := helper.RevokeToken(ctx, s.Revocations, *user.Token); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+				return
+			}
+		}
+
+		if err := helper.UpdateToken(s.Users, "", "", user.User_ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+	}
+}
+
+func UpdateUserRole(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Role *string `json:"role" validate:"required,eq=ADMIN|eq=MANAGER|eq=SERVER|eq=KITCHEN|eq=CASHIER|eq=CUSTOMER"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		userId := c.Param("user_id")
+		user, err := s.Users.Get(ctx, userId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find that user"})
+			return
+		}
+
+		user.Role = input.Role
+		user.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Users.Update(ctx, user); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update user role!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, user)
+	}
+}
+
 func HashPassword(password string) string {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
 	if err != nil {
@@ -0,0 +1,455 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/booking"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"github.com/jamesconfy/restaurant-management/waitlist"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// recentOrders is how many closed orders the typical sitting is taken from.
+const recentOrders = 50
+
+// WaitlistView is a waiting party with its place in the queue and a fresh
+// estimate of the wait.
+type WaitlistView struct {
+	models.WaitlistEntry
+	Position          int  `json:"position"`
+	Estimated_Minutes *int `json:"estimated_minutes"`
+}
+
+// GetWaitlist lists the parties still waiting, first come first, each with
+// a wait worked out from the tables as they are now.
+func GetWaitlist(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		entries, err := s.Waitlist.Waiting(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching the waitlist!"})
+			return
+		}
+
+		views := []WaitlistView{}
+		ahead := []int{}
+		for i, entry := range entries {
+			view := WaitlistView{WaitlistEntry: entry, Position: i + 1}
+			if entry.Status == models.WaitlistWaiting {
+				minutes, ok, err := quoteWait(ctx, s, planner, *entry.Party_Size, ahead, time.Now())
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while estimating waits"})
+					return
+				}
+				if ok {
+					view.Estimated_Minutes = &minutes
+				}
+			}
+
+			ahead = append(ahead, *entry.Party_Size)
+			views = append(views, view)
+		}
+
+		c.JSON(http.StatusOK, views)
+	}
+}
+
+// QuoteWait tells a party how long they would wait if they joined now.
+func QuoteWait(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		party, err := strconv.Atoi(c.Query("party"))
+		if err != nil || party < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "party must be a number of guests"})
+			return
+		}
+
+		ahead, err := partiesAhead(ctx, s)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching the waitlist!"})
+			return
+		}
+
+		minutes, ok, err := quoteWait(ctx, s, planner, party, ahead, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while estimating the wait"})
+			return
+		}
+
+		if !ok {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("No table seats a party of %d", party)})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"party_size": party, "parties_ahead": len(ahead), "estimated_minutes": minutes})
+	}
+}
+
+func AddToWaitlist(s *store.Store, planner *booking.Planner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var entry models.WaitlistEntry
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ahead, err := partiesAhead(ctx, s)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching the waitlist!"})
+			return
+		}
+
+		minutes, ok, err := quoteWait(ctx, s, planner, *entry.Party_Size, ahead, time.Now())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occured while estimating the wait"})
+			return
+		}
+
+		if !ok {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("No table seats a party of %d", *entry.Party_Size)})
+			return
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.Entry_ID = entry.ID.Hex()
+		entry.Status = models.WaitlistWaiting
+		entry.Quoted_Minutes = minutes
+		entry.Offered_Table_ID = ""
+		entry.Order_ID = ""
+		entry.Notified_At = nil
+		entry.Seated_At = nil
+		entry.Created_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+
+		if err := s.Waitlist.Create(ctx, entry); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not add the party to the waitlist!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, WaitlistView{WaitlistEntry: entry, Position: len(ahead) + 1, Estimated_Minutes: &minutes})
+	}
+}
+
+func GetWaitlistEntry(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		entry, err := s.Waitlist.Get(ctx, c.Param("entry_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that waitlist entry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+func UpdateWaitlistEntry(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input models.WaitlistEntry
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		entry, err := s.Waitlist.Get(ctx, c.Param("entry_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that waitlist entry"})
+			return
+		}
+
+		if !entry.Waiting() {
+			c.JSON(http.StatusConflict, gin.H{"error": "A party that has " + entry.Status + " cannot be changed"})
+			return
+		}
+
+		if input.Party_Name != nil {
+			entry.Party_Name = input.Party_Name
+		}
+
+		if input.Party_Size != nil {
+			entry.Party_Size = input.Party_Size
+		}
+
+		if input.Phone != nil {
+			entry.Phone = input.Phone
+		}
+
+		if input.Notes != nil {
+			entry.Notes = input.Notes
+		}
+
+		if err := validate.Struct(entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		entry.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := s.Waitlist.Update(ctx, entry); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the waitlist entry!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, entry)
+	}
+}
+
+// NotifyWaitlistEntry lets the host tell a party by hand that a table is
+// ready, for when a table frees up without its order being closed.
+func NotifyWaitlistEntry(s *store.Store, notifier waitlist.Notifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input struct {
+			Table_ID string `json:"table_id"`
+			Message  string `json:"message"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		entry, err := s.Waitlist.Get(ctx, c.Param("entry_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that waitlist entry"})
+			return
+		}
+
+		if input.Table_ID != "" {
+			if _, err := s.Tables.Get(ctx, input.Table_ID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot find that table"})
+				return
+			}
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := entry.Notified(input.Table_ID, now); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		message := input.Message
+		if message == "" {
+			message = tableReadyMessage(entry)
+		}
+
+		if err := notifier.Notify(ctx, entry, message); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "The party could not be notified"})
+			return
+		}
+
+		if err := s.Waitlist.Update(ctx, entry); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the waitlist entry!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, entry)
+	}
+}
+
+func LeaveWaitlist(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		entry, err := s.Waitlist.Get(ctx, c.Param("entry_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that waitlist entry"})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if err := entry.Leave(now); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := s.Waitlist.Update(ctx, entry); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not update the waitlist entry!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, entry)
+	}
+}
+
+// offerFreedTable notifies the longest-waiting party that fits once the
+// last open order on tableId is done with it.
+func offerFreedTable(ctx context.Context, s *store.Store, notifier waitlist.Notifier, tableId string) {
+	openOrders, err := s.Orders.ListByStatus(ctx, openOrderStatuses...)
+	if err != nil {
+		log.Printf("waitlist: listing open orders: %v", err)
+		return
+	}
+
+	for _, order := range openOrders {
+		if order.Table_ID != nil && *order.Table_ID == tableId {
+			return
+		}
+	}
+
+	table, err := s.Tables.Get(ctx, tableId)
+	if err != nil {
+		return
+	}
+
+	entries, err := s.Waitlist.Waiting(ctx)
+	if err != nil {
+		log.Printf("waitlist: listing waiting parties: %v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.Status != models.WaitlistWaiting || *entry.Party_Size > table.Seats() {
+			continue
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		entry.Notified(tableId, now)
+		if err := notifier.Notify(ctx, entry, tableReadyMessage(entry)); err != nil {
+			log.Printf("waitlist: notifying %s: %v", entry.Entry_ID, err)
+			return
+		}
+
+		if err := s.Waitlist.Update(ctx, entry); err != nil {
+			log.Printf("waitlist: updating %s: %v", entry.Entry_ID, err)
+		}
+		return
+	}
+}
+
+// seatWaitlistEntry converts the waiting party into the order just placed
+// for them. It fails with orderChanged if the party is no longer waiting,
+// e.g. because another order seated them first.
+func seatWaitlistEntry(ctx context.Context, s *store.Store, entryId string, order models.Order) error {
+	_, err := s.Waitlist.Seat(ctx, entryId, order.Order_ID, *order.Table_ID, order.Created_At)
+	if errors.Is(err, store.ErrNotFound) {
+		return orderChanged("The party is no longer waiting")
+	}
+
+	return err
+}
+
+var openOrderStatuses = []string{models.OrderPlaced, models.OrderAccepted, models.OrderPreparing, models.OrderReady, models.OrderServed}
+
+func partiesAhead(ctx context.Context, s *store.Store) ([]int, error) {
+	entries, err := s.Waitlist.Waiting(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ahead := []int{}
+	for _, entry := range entries {
+		ahead = append(ahead, *entry.Party_Size)
+	}
+
+	return ahead, nil
+}
+
+// quoteWait estimates in minutes how long a party of size waits behind the
+// parties ahead. The length of a sitting comes from recently closed orders,
+// falling back to the booking turn time.
+func quoteWait(ctx context.Context, s *store.Store, planner *booking.Planner, size int, ahead []int, now time.Time) (int, bool, error) {
+	closed, err := s.Orders.RecentlyClosed(ctx, recentOrders)
+	if err != nil {
+		return 0, false, err
+	}
+	sitting := waitlist.TypicalSitting(closed, planner.TurnTime(size))
+
+	tables, err := tableOccupancy(ctx, s, sitting, now)
+	if err != nil {
+		return 0, false, err
+	}
+
+	wait, ok := waitlist.Estimate(tables, ahead, size, sitting, now)
+	return int(wait.Minutes()), ok, nil
+}
+
+// tableOccupancy works out when each table is expected to be free: tables
+// with open orders or seated bookings after a typical sitting, and tables
+// booked soon not until that booking is over.
+func tableOccupancy(ctx context.Context, s *store.Store, sitting time.Duration, now time.Time) ([]waitlist.Occupancy, error) {
+	tables, err := s.Tables.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	openOrders, err := s.Orders.ListByStatus(ctx, openOrderStatuses...)
+	if err != nil {
+		return nil, err
+	}
+
+	reservations, err := s.Reservations.Between(ctx, now.AddDate(0, 0, -1), now.Add(sitting))
+	if err != nil {
+		return nil, err
+	}
+
+	since := map[string]time.Time{}
+	taken := func(tableId string, at time.Time) {
+		if first, ok := since[tableId]; !ok || at.Before(first) {
+			since[tableId] = at
+		}
+	}
+
+	for _, order := range openOrders {
+		if order.Table_ID != nil {
+			taken(*order.Table_ID, order.Created_At)
+		}
+	}
+
+	heldUntil := map[string]time.Time{}
+	for _, reservation := range reservations {
+		switch reservation.Status {
+		case models.ReservationSeated:
+			taken(reservation.Table_ID, *reservation.Start_Time)
+		case models.ReservationBooked:
+			if reservation.End_Time.After(heldUntil[reservation.Table_ID]) {
+				heldUntil[reservation.Table_ID] = reservation.End_Time
+			}
+		}
+	}
+
+	occupancy := []waitlist.Occupancy{}
+	for _, table := range tables {
+		freeAt := now
+		if at, ok := since[table.Table_ID]; ok {
+			freeAt = waitlist.FreeAt(at, sitting, now)
+		}
+		if held := heldUntil[table.Table_ID]; held.After(freeAt) {
+			freeAt = held
+		}
+
+		occupancy = append(occupancy, waitlist.Occupancy{Table_ID: table.Table_ID, Seats: table.Seats(), Free_At: freeAt})
+	}
+
+	return occupancy, nil
+}
+
+func tableReadyMessage(entry models.WaitlistEntry) string {
+	return fmt.Sprintf("Hi %s, your table for %d is ready. Please come to the host stand.", *entry.Party_Name, *entry.Party_Size)
+}
@@ -0,0 +1,330 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WasteTotal is what was thrown away for one reason.
+type WasteTotal struct {
+	Reason  string       `json:"reason"`
+	Entries int          `json:"entries"`
+	Cost    models.Money `json:"cost"`
+}
+
+type WasteDay struct {
+	Day     string       `json:"day"`
+	Entries int          `json:"entries"`
+	Cost    models.Money `json:"cost"`
+	Reasons []WasteTotal `json:"reasons"`
+}
+
+// WasteReport is the cost of waste over [From, To), costliest reasons first.
+// Uncosted counts entries that were only partly priced.
+type WasteReport struct {
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Entries  int          `json:"entries"`
+	Cost     models.Money `json:"cost"`
+	Uncosted int          `json:"uncosted"`
+	Reasons  []WasteTotal `json:"reasons"`
+	Days     []WasteDay   `json:"days"`
+}
+
+func GetWaste(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		filter, ok := wasteFilter(c)
+		if !ok {
+			return
+		}
+
+		entries, err := s.Waste.List(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the waste log!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"total_count": len(entries), "waste": entries})
+	}
+}
+
+// LogWaste records stock thrown away, takes it out of inventory and prices
+// it at what it last cost.
+func LogWaste(s *store.Store, inv *inventory.Inventory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var entry models.WasteEntry
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		if err := c.BindJSON(&entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := validate.Struct(entry); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := entry.Check(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		now, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+		if entry.Wasted_At.IsZero() {
+			entry.Wasted_At = now
+		}
+		if entry.Wasted_At.After(now) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Waste cannot be logged ahead of time"})
+			return
+		}
+
+		// An ingredient is wasted as it is; a food wastes what its recipe
+		// takes.
+		wasted := []models.RecipeLine{}
+		if entry.Ingredient_ID != nil {
+			if _, err := s.Ingredients.Get(ctx, *entry.Ingredient_ID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Ingredient " + *entry.Ingredient_ID + " was not found"})
+				return
+			}
+			wasted = append(wasted, models.RecipeLine{Ingredient_ID: *entry.Ingredient_ID, Quantity: *entry.Quantity})
+		} else {
+			food, err := s.Foods.Get(ctx, *entry.Food_ID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Food " + *entry.Food_ID + " was not found"})
+				return
+			}
+			for _, line := range food.Recipe {
+				wasted = append(wasted, models.RecipeLine{Ingredient_ID: line.Ingredient_ID, Quantity: line.Quantity * *entry.Quantity})
+			}
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.Waste_ID = entry.ID.Hex()
+		entry.Logged_By = c.GetString("userId")
+		entry.Logged_By_Name = strings.TrimSpace(c.GetString("first_name") + " " + c.GetString("last_name"))
+		entry.Created_At = now
+
+		err := s.WithTransaction(ctx, func(ctx context.Context) error {
+			entry.Stock_Used = []models.RecipeLine{}
+			entry.Cost = models.NewMoney(0, "")
+			entry.Uncosted = len(wasted) == 0
+
+			for _, line := range wasted {
+				ingredient, err := moveStock(ctx, s, inv, line.Ingredient_ID, -line.Quantity)
+				if errors.Is(err, store.ErrNotFound) {
+					continue
+				}
+				if err != nil {
+					return err
+				}
+
+				entry.Stock_Used = append(entry.Stock_Used, line)
+				if ingredient.Last_Cost == nil {
+					entry.Uncosted = true
+					continue
+				}
+				entry.Cost = entry.Cost.Add(ingredient.Last_Cost.Of(line.Quantity))
+			}
+
+			return s.Waste.Create(ctx, entry)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Waste was not logged!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, entry)
+	}
+}
+
+func GetWasteEntry(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		entry, err := s.Waste.Get(ctx, c.Param("waste_id"))
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Cannot find that waste entry"})
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// DeleteWaste takes back an entry logged by mistake, returning its stock.
+func DeleteWaste(s *store.Store, inv *inventory.Inventory) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+		wasteId := c.Param("waste_id")
+
+		entry, err := s.Waste.Get(ctx, wasteId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Cannot find waste entry with that id"})
+			return
+		}
+
+		err = s.WithTransaction(ctx, func(ctx context.Context) error {
+			if err := s.Waste.Delete(ctx, wasteId); err != nil {
+				return err
+			}
+
+			for _, line := range entry.Stock_Used {
+				if _, err := moveStock(ctx, s, inv, line.Ingredient_ID, line.Quantity); err != nil && !errors.Is(err, store.ErrNotFound) {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not delete waste entry!"})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, entry)
+	}
+}
+
+// GetWasteReport adds up the cost of waste by day and reason. It covers the
+// last seven days unless ?from and ?to say otherwise, and takes the same
+// filters as the waste log.
+func GetWasteReport(s *store.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
+		defer cancel()
+
+		filter, ok := wasteFilter(c)
+		if !ok {
+			return
+		}
+
+		if filter.To.IsZero() {
+			today := time.Now().UTC().Truncate(24 * time.Hour)
+			filter.To = today.AddDate(0, 0, 1)
+		}
+		if filter.From.IsZero() {
+			filter.From = filter.To.AddDate(0, 0, -7)
+		}
+
+		if !filter.From.Before(filter.To) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+			return
+		}
+
+		entries, err := s.Waste.List(ctx, filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error occurred while listing the waste log!"})
+			return
+		}
+
+		c.JSON(http.StatusOK, wasteReport(entries, filter.From, filter.To))
+	}
+}
+
+// wasteReport totals entries, which come earliest first.
+func wasteReport(entries []models.WasteEntry, from, to time.Time) WasteReport {
+	report := WasteReport{From: from, To: to, Cost: models.NewMoney(0, ""), Reasons: []WasteTotal{}, Days: []WasteDay{}}
+	reasons := map[string]*WasteTotal{}
+	days := map[string]map[string]*WasteTotal{}
+
+	for _, entry := range entries {
+		report.Entries++
+		report.Cost = report.Cost.Add(entry.Cost)
+		if entry.Uncosted {
+			report.Uncosted++
+		}
+
+		day := entry.Day()
+		if days[day] == nil {
+			days[day] = map[string]*WasteTotal{}
+			report.Days = append(report.Days, WasteDay{Day: day, Cost: models.NewMoney(0, "")})
+		}
+		last := &report.Days[len(report.Days)-1]
+		last.Entries++
+		last.Cost = last.Cost.Add(entry.Cost)
+
+		addWaste(reasons, *entry.Reason, entry.Cost)
+		addWaste(days[day], *entry.Reason, entry.Cost)
+	}
+
+	report.Reasons = sortedWaste(reasons)
+	for i := range report.Days {
+		report.Days[i].Reasons = sortedWaste(days[report.Days[i].Day])
+	}
+
+	return report
+}
+
+func addWaste(totals map[string]*WasteTotal, reason string, cost models.Money) {
+	total, ok := totals[reason]
+	if !ok {
+		total = &WasteTotal{Reason: reason, Cost: models.NewMoney(0, "")}
+		totals[reason] = total
+	}
+
+	total.Entries++
+	total.Cost = total.Cost.Add(cost)
+}
+
+// sortedWaste lists totals costliest first.
+func sortedWaste(totals map[string]*WasteTotal) []WasteTotal {
+	sorted := []WasteTotal{}
+	for _, total := range totals {
+		sorted = append(sorted, *total)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Cost.Amount != sorted[j].Cost.Amount {
+			return sorted[i].Cost.Amount > sorted[j].Cost.Amount
+		}
+		return sorted[i].Reason < sorted[j].Reason
+	})
+	return sorted
+}
+
+// wasteFilter reads the waste log filters from the query, writing a 400
+// and returning false when one is malformed.
+func wasteFilter(c *gin.Context) (store.WasteFilter, bool) {
+	filter := store.WasteFilter{
+		Ingredient_ID: c.Query("ingredient_id"),
+		Food_ID:       c.Query("food_id"),
+		Reason:        strings.ToUpper(c.Query("reason")),
+	}
+
+	if filter.Reason != "" {
+		if err := validate.Var(filter.Reason, "oneof=SPOILED EXPIRED OVERPRODUCED DROPPED RETURNED PREP_WASTE OTHER"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": filter.Reason + " is not a waste reason"})
+			return filter, false
+		}
+	}
+
+	var err error
+	if filter.From, err = parseDateParam(c.Query("from")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date or RFC 3339 time"})
+		return filter, false
+	}
+
+	if filter.To, err = parseDateParam(c.Query("to")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date or RFC 3339 time"})
+		return filter, false
+	}
+
+	return filter, true
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Config describes how to reach MongoDB. Values are resolved in increasing
+// order of precedence: defaults, the env file, the process environment and
+// finally command line flags.
+type Config struct {
+	URI                    string
+	Database               string
+	MinPoolSize            uint64
+	MaxPoolSize            uint64
+	ConnectTimeout         time.Duration
+	ServerSelectionTimeout time.Duration
+	TLS                    bool
+	TLSCAFile              string
+	TLSInsecure            bool
+	ConnectRetries         int
+	RetryBackoff           time.Duration
+}
+
+func DefaultConfig() Config {
+	return Config{
+		URI:                    "mongodb://localhost:27017",
+		Database:               "restaurant",
+		MaxPoolSize:            100,
+		ConnectTimeout:         10 * time.Second,
+		ServerSelectionTimeout: 10 * time.Second,
+		ConnectRetries:         5,
+		RetryBackoff:           time.Second,
+	}
+}
+
+// LoadConfig reads the env file (ENV_FILE, ".env" by default) into the
+// process environment and builds a Config from it. A missing default env
+// file is not an error; a missing file that was asked for explicitly is.
+func LoadConfig() (Config, error) {
+	cfg := DefaultConfig()
+
+	envFile, explicit := os.LookupEnv("ENV_FILE")
+	if !explicit {
+		envFile = ".env"
+	}
+
+	if err := godotenv.Load(envFile); err != nil {
+		if explicit || !errors.Is(err, fs.ErrNotExist) {
+			return cfg, fmt.Errorf("database: loading env file %s: %w", envFile, err)
+		}
+	}
+
+	var err error
+	if v := os.Getenv("MONGO_URI"); v != "" {
+		cfg.URI = v
+	}
+
+	if v := os.Getenv("MONGO_DATABASE"); v != "" {
+		cfg.Database = v
+	}
+
+	if v := os.Getenv("MONGO_MIN_POOL_SIZE"); v != "" {
+		if cfg.MinPoolSize, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_MIN_POOL_SIZE: %w", err)
+		}
+	}
+
+	if v := os.Getenv("MONGO_MAX_POOL_SIZE"); v != "" {
+		if cfg.MaxPoolSize, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_MAX_POOL_SIZE: %w", err)
+		}
+	}
+
+	if v := os.Getenv("MONGO_CONNECT_TIMEOUT"); v != "" {
+		if cfg.ConnectTimeout, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_CONNECT_TIMEOUT: %w", err)
+		}
+	}
+
+	if v := os.Getenv("MONGO_SERVER_SELECTION_TIMEOUT"); v != "" {
+		if cfg.ServerSelectionTimeout, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_SERVER_SELECTION_TIMEOUT: %w", err)
+		}
+	}
+
+	if v := os.Getenv("MONGO_TLS"); v != "" {
+		if cfg.TLS, err = strconv.ParseBool(v); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_TLS: %w", err)
+		}
+	}
+
+	if v := os.Getenv("MONGO_TLS_CA_FILE"); v != "" {
+		cfg.TLSCAFile = v
+	}
+
+	if v := os.Getenv("MONGO_TLS_INSECURE"); v != "" {
+		if cfg.TLSInsecure, err = strconv.ParseBool(v); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_TLS_INSECURE: %w", err)
+		}
+	}
+
+	if v := os.Getenv("MONGO_CONNECT_RETRIES"); v != "" {
+		if cfg.ConnectRetries, err = strconv.Atoi(v); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_CONNECT_RETRIES: %w", err)
+		}
+	}
+
+	if v := os.Getenv("MONGO_RETRY_BACKOFF"); v != "" {
+		if cfg.RetryBackoff, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("database: MONGO_RETRY_BACKOFF: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
+
+// RegisterFlags binds the config to fs using the already loaded values as
+// defaults, so flags only override what was explicitly passed.
+func (cfg *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.URI, "mongo-uri", cfg.URI, "MongoDB connection string")
+	fs.StringVar(&cfg.Database, "mongo-database", cfg.Database, "MongoDB database name")
+	fs.Uint64Var(&cfg.MinPoolSize, "mongo-min-pool-size", cfg.MinPoolSize, "minimum connections kept in the pool")
+	fs.Uint64Var(&cfg.MaxPoolSize, "mongo-max-pool-size", cfg.MaxPoolSize, "maximum connections in the pool")
+	fs.DurationVar(&cfg.ConnectTimeout, "mongo-connect-timeout", cfg.ConnectTimeout, "timeout for each connection attempt")
+	fs.DurationVar(&cfg.ServerSelectionTimeout, "mongo-server-selection-timeout", cfg.ServerSelectionTimeout, "timeout for selecting a server")
+	fs.BoolVar(&cfg.TLS, "mongo-tls", cfg.TLS, "connect over TLS")
+	fs.StringVar(&cfg.TLSCAFile, "mongo-tls-ca-file", cfg.TLSCAFile, "PEM file with the CA used to verify the server")
+	fs.BoolVar(&cfg.TLSInsecure, "mongo-tls-insecure", cfg.TLSInsecure, "skip TLS certificate verification")
+	fs.IntVar(&cfg.ConnectRetries, "mongo-connect-retries", cfg.ConnectRetries, "connection attempts before giving up")
+	fs.DurationVar(&cfg.RetryBackoff, "mongo-retry-backoff", cfg.RetryBackoff, "initial wait between connection attempts")
+}
+
+func (cfg Config) Validate() error {
+	if cfg.URI == "" {
+		return errors.New("database: a MongoDB URI is required")
+	}
+
+	if cfg.Database == "" {
+		return errors.New("database: a database name is required")
+	}
+
+	if cfg.MaxPoolSize != 0 && cfg.MinPoolSize > cfg.MaxPoolSize {
+		return fmt.Errorf("database: min pool size %d is larger than max pool size %d", cfg.MinPoolSize, cfg.MaxPoolSize)
+	}
+
+	if cfg.ConnectRetries < 1 {
+		return errors.New("database: connect retries must be at least 1")
+	}
+
+	return nil
+}
+
+// RedactedURI is the URI with any password masked, safe for logs and errors.
+func (cfg Config) RedactedURI() string {
+	u, err := url.Parse(cfg.URI)
+	if err != nil {
+		return "<invalid uri>"
+	}
+
+	return u.Redacted()
+}
@@ -2,49 +2,107 @@ package database
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"log"
 	"os"
 	"time"
 
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
-func DBInstance() *mongo.Client {
-	err := godotenv.Load()
-	if err != nil {
-		log.Fatalf("err loading: %v", err)
+const maxRetryBackoff = 30 * time.Second
+
+// Connect opens a client for cfg and pings the primary before returning it.
+// Failed attempts are retried with exponential backoff up to
+// cfg.ConnectRetries times.
+func Connect(ctx context.Context, cfg Config) (*mongo.Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
-	MONGO_PASSWORD := os.Getenv("MONGO_PASSWORD")
-	MongoDB := fmt.Sprintf(`mongodb+srv://Everybody:%v@cluster0.wijun.mongodb.net/?retryWrites=true&w=majority`, MONGO_PASSWORD)
-	fmt.Println(MongoDB)
-	client, err := mongo.NewClient(options.Client().ApplyURI(MongoDB))
+	opts, err := clientOptions(cfg)
 	if err != nil {
-		log.Fatal(err)
-		panic(err.Error())
+		return nil, err
+	}
+
+	var lastErr error
+	backoff := cfg.RetryBackoff
+	for attempt := 1; attempt <= cfg.ConnectRetries; attempt++ {
+		client, err := connectOnce(ctx, cfg, opts)
+		if err == nil {
+			return client, nil
+		}
+
+		lastErr = err
+		if attempt == cfg.ConnectRetries {
+			break
+		}
+
+		log.Printf("database: attempt %d/%d to reach %s failed: %v (retrying in %s)", attempt, cfg.ConnectRetries, cfg.RedactedURI(), err, backoff)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("database: connecting to %s: %w", cfg.RedactedURI(), ctx.Err())
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxRetryBackoff {
+			backoff = maxRetryBackoff
+		}
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
 
+	return nil, fmt.Errorf("database: could not connect to %s after %d attempts: %w", cfg.RedactedURI(), cfg.ConnectRetries, lastErr)
+}
+
+func connectOnce(ctx context.Context, cfg Config, opts *options.ClientOptions) (*mongo.Client, error) {
+	attemptCtx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
 	defer cancel()
 
-	err = client.Connect(ctx)
+	client, err := mongo.Connect(attemptCtx, opts)
 	if err != nil {
-		log.Fatal(err)
-		panic(err.Error())
+		return nil, err
 	}
 
-	fmt.Println(client)
+	if err := client.Ping(attemptCtx, readpref.Primary()); err != nil {
+		client.Disconnect(context.Background())
+		return nil, err
+	}
 
-	return client
+	return client, nil
 }
 
-var Client *mongo.Client = DBInstance()
+func clientOptions(cfg Config) (*options.ClientOptions, error) {
+	opts := options.Client().
+		ApplyURI(cfg.URI).
+		SetMinPoolSize(cfg.MinPoolSize).
+		SetMaxPoolSize(cfg.MaxPoolSize).
+		SetConnectTimeout(cfg.ConnectTimeout).
+		SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+
+	if cfg.TLS || cfg.TLSCAFile != "" || cfg.TLSInsecure {
+		tlsConfig := &tls.Config{InsecureSkipVerify: cfg.TLSInsecure}
+		if cfg.TLSCAFile != "" {
+			pem, err := os.ReadFile(cfg.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("database: reading TLS CA file: %w", err)
+			}
 
-func OpenCollection(client *mongo.Client, collection_name string) *mongo.Collection {
-	collection := client.Database("restaurant").Collection(collection_name)
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("database: no certificates found in %s", cfg.TLSCAFile)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("database: invalid options for %s: %w", cfg.RedactedURI(), err)
+	}
 
-	return collection
+	return opts, nil
 }
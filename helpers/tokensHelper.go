@@ -2,29 +2,52 @@ package helper
 
 import (
 	"context"
-	"log"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt"
-	"github.com/jamesconfy/restaurant-management/database"
 	"github.com/jamesconfy/restaurant-management/models"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
+	"github.com/jamesconfy/restaurant-management/store"
 )
 
-var secret_key string = os.Getenv("SECRET_KEY")
-var userCollection *mongo.Collection = database.OpenCollection(database.Client, "user")
+// secretKey is read on every call rather than at init so values loaded from
+// the env file during startup are picked up.
+func secretKey() []byte {
+	return []byte(os.Getenv("SECRET_KEY"))
+}
+
+func newTokenId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func GenerateAllToken(email, first_name, last_name, userId, role string) (signedToken string, signedRefreshToken string, err error) {
+	tokenId, err := newTokenId()
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshTokenId, err := newTokenId()
+	if err != nil {
+		return "", "", err
+	}
 
-func GenerateAllToken(email, first_name, last_name, userId string) (signedToken string, signedRefreshToken string, err error) {
 	claims := &models.SignedDetails{
 		Email:      email,
 		First_Name: first_name,
 		Last_Name:  last_name,
 		User_ID:    userId,
+		Role:       role,
+		Token_Type: models.AccessToken,
 		StandardClaims: jwt.StandardClaims{
+			Id:        tokenId,
 			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(60)).Unix(),
 		},
 	}
@@ -34,17 +57,20 @@ func GenerateAllToken(email, first_name, last_name, userId string) (signedToken
 		First_Name: first_name,
 		Last_Name:  last_name,
 		User_ID:    userId,
+		Role:       role,
+		Token_Type: models.RefreshToken,
 		StandardClaims: jwt.StandardClaims{
+			Id:        refreshTokenId,
 			ExpiresAt: time.Now().Local().Add(time.Hour * time.Duration(168)).Unix(),
 		},
 	}
 
-	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret_key))
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secretKey())
 	if err != nil {
 		return "", "", err
 	}
 
-	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString([]byte(secret_key))
+	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshClaims).SignedString(secretKey())
 	if err != nil {
 		return "", "", err
 	}
@@ -52,49 +78,124 @@ func GenerateAllToken(email, first_name, last_name, userId string) (signedToken
 	return token, refreshToken, nil
 }
 
-func UpdateToken(signedToken, signedRefreshToken, userId string) error {
-	var updateObj primitive.D
+// approvalTokenLifetime is short: the token is meant to be handed over at
+// the till and used straight away.
+const approvalTokenLifetime = 5 * time.Minute
+
+// GenerateApprovalToken issues a single-use token through which the manager
+// it names approves one adjustment.
+func GenerateApprovalToken(email, first_name, last_name, userId, role string) (signedToken string, expiresAt time.Time, err error) {
+	tokenId, err := newTokenId()
+	if err != nil {
+		return "", expiresAt, err
+	}
+
+	expiresAt = time.Now().Add(approvalTokenLifetime)
+	claims := &models.SignedDetails{
+		Email:      email,
+		First_Name: first_name,
+		Last_Name:  last_name,
+		User_ID:    userId,
+		Role:       role,
+		Token_Type: models.ApprovalToken,
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenId,
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	signedToken, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secretKey())
+	return signedToken, expiresAt, err
+}
+
+func UpdateToken(users store.UserRepository, signedToken, signedRefreshToken, userId string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 1000*time.Second)
 	defer cancel()
 
-	updateObj = append(updateObj, bson.E{Key: "token", Value: signedToken})
-	updateObj = append(updateObj, bson.E{Key: "refresh_token", Value: signedRefreshToken})
+	return users.UpdateTokens(ctx, userId, signedToken, signedRefreshToken)
+}
+
+// ValidateToken checks the signature and expiry of signedToken and rejects
+// it if its id has been revoked. A token without an id cannot be revoked, so
+// it is rejected too.
+func ValidateToken(ctx context.Context, revocations store.RevocationRepository, signedToken string) (claims *models.SignedDetails, err error) {
+	claims, err = parseToken(signedToken)
+	if err != nil {
+		return nil, err
+	}
 
-	updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
-	updateObj = append(updateObj, bson.E{Key: "updated_at", Value: updated_at})
+	if claims.ExpiresAt < time.Now().Local().Unix() {
+		return nil, errors.New("token has expired")
+	}
 
-	upsert := true
-	filter := bson.M{"user_id": userId}
-	opts := options.UpdateOptions{
-		Upsert: &upsert,
+	if claims.Id == "" {
+		return nil, errors.New("token has no id")
 	}
 
-	_, err := userCollection.UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: updateObj}}, &opts)
+	revoked, err := revocations.IsRevoked(ctx, claims.Id)
 	if err != nil {
-		log.Panic(err.Error())
+		return nil, err
+	}
+
+	if revoked {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// RevokeToken adds the token's id to the denylist until the token would have
+// expired anyway. Tokens that are already expired or unparseable are
+// ignored since they cannot be used.
+func RevokeToken(ctx context.Context, revocations store.RevocationRepository, signedToken string) error {
+	claims, err := parseToken(signedToken)
+	if err != nil || claims.Id == "" {
+		return nil
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if expiresAt.Before(time.Now()) {
+		return nil
 	}
 
-	return nil
+	return revocations.Revoke(ctx, claims.Id, claims.User_ID, expiresAt)
 }
 
-func ValidateToken(signedToken string) (claims *models.SignedDetails, err error) {
-	token, err := jwt.ParseWithClaims(
+// SpendToken uses up a single-use token such as an approval token. It fails
+// with store.ErrDuplicate when the token has been used already.
+func SpendToken(ctx context.Context, revocations store.RevocationRepository, signedToken string) error {
+	claims, err := parseToken(signedToken)
+	if err != nil {
+		return err
+	}
+
+	if claims.Id == "" {
+		return errors.New("token has no id")
+	}
+
+	return revocations.Spend(ctx, claims.Id, claims.User_ID, time.Unix(claims.ExpiresAt, 0))
+}
+
+func parseToken(signedToken string) (*models.SignedDetails, error) {
+	parser := jwt.Parser{SkipClaimsValidation: true}
+	token, err := parser.ParseWithClaims(
 		signedToken,
 		&models.SignedDetails{},
 		func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret_key), nil
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("unexpected signing method")
+			}
+			return secretKey(), nil
 		},
 	)
-
-	claims, ok := token.Claims.(*models.SignedDetails)
-	if !ok {
+	if err != nil {
 		return nil, err
 	}
 
-	if claims.ExpiresAt < time.Now().Local().Unix() {
-		return nil, err
+	claims, ok := token.Claims.(*models.SignedDetails)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid token")
 	}
 
-	return claims, err
-
+	return claims, nil
 }
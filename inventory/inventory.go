@@ -0,0 +1,97 @@
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+// Ordered items are taken out of stock either when they are ordered or once
+// the kitchen has served them.
+const (
+	DepleteOrdered = "ORDERED"
+	DepleteServed  = "SERVED"
+)
+
+type Config struct {
+	Deplete_On string
+}
+
+// Event says an ingredient moved to another stock level.
+type Event struct {
+	Level      string            `json:"level"`
+	Previous   string            `json:"previous"`
+	Ingredient models.Ingredient `json:"ingredient"`
+	At         time.Time         `json:"at"`
+}
+
+// Handler reacts to stock events. Handlers run in the request that changed
+// the stock, so they must be quick and must not fail it.
+type Handler func(ctx context.Context, ev Event)
+
+// Inventory holds the stock settings and passes stock events on to whoever
+// subscribed to them.
+type Inventory struct {
+	depleteOn string
+
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func New(cfg Config) (*Inventory, error) {
+	switch cfg.Deplete_On {
+	case "":
+		cfg.Deplete_On = DepleteOrdered
+	case DepleteOrdered, DepleteServed:
+	default:
+		return nil, fmt.Errorf("inventory: unknown depletion point %q", cfg.Deplete_On)
+	}
+
+	return &Inventory{depleteOn: cfg.Deplete_On}, nil
+}
+
+// DepletesOn reports whether items are taken out of stock at stage.
+func (inv *Inventory) DepletesOn(stage string) bool {
+	return inv.depleteOn == stage
+}
+
+func (inv *Inventory) Subscribe(handler Handler) {
+	inv.mu.Lock()
+	defer inv.mu.Unlock()
+
+	inv.handlers = append(inv.handlers, handler)
+}
+
+// Moved publishes an event when a stock change took the ingredient from one
+// level to another.
+func (inv *Inventory) Moved(ctx context.Context, before, after models.Ingredient) {
+	if before.Level() == after.Level() {
+		return
+	}
+
+	ev := Event{Level: after.Level(), Previous: before.Level(), Ingredient: after, At: time.Now()}
+
+	inv.mu.RLock()
+	handlers := append([]Handler(nil), inv.handlers...)
+	inv.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(ctx, ev)
+	}
+}
+
+// LogAlerts writes low and out of stock alerts to the server log.
+func LogAlerts(ctx context.Context, ev Event) {
+	switch ev.Level {
+	case models.StockLow:
+		log.Printf("inventory: %s is running low, %g %s left", *ev.Ingredient.Name, ev.Ingredient.On_Hand, *ev.Ingredient.Unit)
+	case models.StockOut:
+		log.Printf("inventory: %s is out of stock", *ev.Ingredient.Name)
+	case models.StockIn:
+		log.Printf("inventory: %s is back in stock, %g %s on hand", *ev.Ingredient.Name, ev.Ingredient.On_Hand, *ev.Ingredient.Unit)
+	}
+}
@@ -0,0 +1,97 @@
+package kitchen
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	ItemAdded     = "item_added"
+	ItemModified  = "item_modified"
+	ItemCancelled = "item_cancelled"
+	ItemBumped    = "item_bumped"
+)
+
+// Ticket is a single order item as the kitchen screen shows it.
+type Ticket struct {
+	Order_Item_ID string   `json:"order_item_id"`
+	Order_ID      string   `json:"order_id"`
+	Table_ID      string   `json:"table_id"`
+	Table_Number  *int     `json:"table_number"`
+	Food_ID       string   `json:"food_id"`
+	Food_Name     string   `json:"food_name"`
+	Quantity      *int     `json:"quantity"`
+	Modifiers     []string `json:"modifiers"`
+	// Allergies are those declared for the item's seat; Allergy_Alert is
+	// set when the item contains one of them and must be shown prominently.
+	Allergies     []string `json:"allergies"`
+	Allergy_Alert string   `json:"allergy_alert,omitempty"`
+	// Notes are those left on the item and its order, pinned first.
+	Notes      []string  `json:"notes"`
+	Station    string    `json:"station"`
+	Status     string    `json:"status"`
+	Created_At time.Time `json:"created_at"`
+}
+
+type Event struct {
+	Type    string    `json:"type"`
+	Station string    `json:"station"`
+	Ticket  Ticket    `json:"ticket"`
+	At      time.Time `json:"at"`
+}
+
+// subscriberBuffer is how many events a slow screen may fall behind before
+// it starts missing them. Screens resync from the snapshot on reconnect.
+const subscriberBuffer = 64
+
+// Hub fans kitchen events out to every connected screen. A screen may
+// subscribe to a single station or, with an empty station, to all of them.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]string
+}
+
+func NewHub() *Hub {
+	return &Hub{subscribers: map[chan Event]string{}}
+}
+
+// Subscribe returns a channel of events for station and a function that
+// must be called to stop receiving them.
+func (h *Hub) Subscribe(station string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	h.subscribers[ch] = station
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subscribers, ch)
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish never blocks; events for a screen whose buffer is full are dropped.
+func (h *Hub) Publish(ev Event) {
+	if ev.At.IsZero() {
+		ev.At = time.Now()
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch, station := range h.subscribers {
+		if station != "" && station != ev.Station {
+			continue
+		}
+
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
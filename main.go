@@ -1,30 +1,184 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"log"
 	"os"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/jamesconfy/restaurant-management/database"
+	"github.com/jamesconfy/restaurant-management/booking"
+	"github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/database"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/payments"
+	"github.com/jamesconfy/restaurant-management/pricing"
 	"github.com/jamesconfy/restaurant-management/routes"
+	"github.com/jamesconfy/restaurant-management/store"
+	"github.com/jamesconfy/restaurant-management/waitlist"
 )
 
 func main() {
+	dbConfig, err := database.LoadConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	storeDriver := os.Getenv("STORE")
+	if storeDriver == "" {
+		storeDriver = "mongo"
+	}
+
+	pricingConfig := os.Getenv("PRICING_CONFIG")
+	bookingConfig := os.Getenv("BOOKING_CONFIG")
+	if currency := os.Getenv("CURRENCY"); currency != "" {
+		models.DefaultCurrency = currency
+	}
+	migratePrices := false
+
+	paymentConfig := payments.Config{
+		Provider:       os.Getenv("PAYMENT_PROVIDER"),
+		Webhook_Secret: os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		Webhook_URL:    os.Getenv("PAYMENT_WEBHOOK_URL"),
+	}
+
+	waitlistConfig := waitlist.Config{
+		Notifier: os.Getenv("WAITLIST_NOTIFIER"),
+		Path:     os.Getenv("WAITLIST_NOTIFY_FILE"),
+	}
+
+	inventoryConfig := inventory.Config{
+		Deplete_On: os.Getenv("INVENTORY_DEPLETE_ON"),
+	}
+
+	dbConfig.RegisterFlags(flag.CommandLine)
+	flag.StringVar(&pricingConfig, "pricing-config", pricingConfig, "JSON file with tax rates, service charges and rounding")
+	flag.StringVar(&bookingConfig, "booking-config", bookingConfig, "JSON file with seating hours and turn times for reservations")
+	flag.StringVar(&storeDriver, "store", storeDriver, `storage backend, "mongo" or "memory"`)
+	flag.StringVar(&models.DefaultCurrency, "currency", models.DefaultCurrency, "ISO 4217 code for amounts sent without one")
+	flag.StringVar(&paymentConfig.Provider, "payment-provider", paymentConfig.Provider, `card payment provider; only "simulator" for now`)
+	flag.StringVar(&waitlistConfig.Notifier, "waitlist-notifier", waitlistConfig.Notifier, `how waiting parties are told their table is ready, "log" or "file"`)
+	flag.StringVar(&inventoryConfig.Deplete_On, "inventory-deplete-on", inventoryConfig.Deplete_On, `when ordered items leave stock, "ORDERED" or "SERVED"`)
+	flag.BoolVar(&migratePrices, "migrate-prices", false, "convert legacy numeric prices to money documents and exit")
+	flag.Parse()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8000"
 	}
 
+	var s *store.Store
+	switch storeDriver {
+	case "memory":
+		s = store.NewMemoryStore()
+	case "mongo":
+		client, err := database.Connect(context.Background(), dbConfig)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer client.Disconnect(context.Background())
+
+		log.Printf("connected to %s (database %q)", dbConfig.RedactedURI(), dbConfig.Database)
+		if migratePrices {
+			reports, err := store.MigratePrices(context.Background(), client.Database(dbConfig.Database), models.DefaultCurrency)
+			for _, report := range reports {
+				log.Printf("migrated %s.%s: %d scanned, %d repaired, %d to review", report.Collection, report.Field, report.Scanned, report.Repaired, len(report.Review))
+				for _, id := range report.Review {
+					log.Printf("review %s.%s on %s: it cannot be told whether it was stored scaled by 100; set it by hand", report.Collection, report.Field, id)
+				}
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		if err := store.RequireTransactions(context.Background(), client); err != nil {
+			log.Fatal(err)
+		}
+
+		s = store.NewMongoStore(client.Database(dbConfig.Database))
+		if err := store.EnsureIndexes(context.Background(), s); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("unknown store %q", storeDriver)
+	}
+
+	if migratePrices {
+		log.Fatal("-migrate-prices needs the mongo store")
+	}
+
+	pricingCfg, err := pricing.LoadConfig(pricingConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	engine, err := pricing.NewEngine(pricingCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bookingCfg, err := booking.LoadConfig(bookingConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	planner, err := booking.NewPlanner(bookingCfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	provider, err := payments.New(paymentConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	notifier, err := waitlist.NewNotifier(waitlistConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	inv, err := inventory.New(inventoryConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := controllers.BootstrapAdmin(context.Background(), s, os.Getenv("ADMIN_EMAIL"), os.Getenv("ADMIN_PASSWORD")); err != nil {
+		log.Fatal(err)
+	}
+
+	inv.Subscribe(inventory.LogAlerts)
+	inv.Subscribe(controllers.FollowStock(s))
+
+	hub := kitchen.NewHub()
+
 	router := gin.New()
 	router.Use(gin.Logger())
-	routes.UserRoutes(router)
-	// router.Use(middleware.Authentication())
-
-	routes.FoodRoutes(router)
-	routes.MenuRoutes(router)
-	routes.TableRoutes(router)
-	routes.OrderRoutes(router)
-	routes.InvoiceRoutes(router)
-	routes.OrderItemsRoutes(router)
+	routes.AuthRoutes(router, s)
+	routes.PaymentWebhookRoutes(router, s, engine, provider)
+	router.Use(middleware.Authentication(s))
+
+	routes.UserRoutes(router, s)
+	routes.FoodRoutes(router, s)
+	routes.MenuRoutes(router, s)
+	routes.PriceRuleRoutes(router, s)
+	routes.TableRoutes(router, s, notifier)
+	routes.OrderRoutes(router, s, hub, inv, engine)
+	routes.InvoiceRoutes(router, s, engine, provider)
+	routes.OrderItemsRoutes(router, s, hub, inv, engine, provider)
+	routes.AdjustmentRoutes(router, s)
+	routes.PromotionRoutes(router, s, engine)
+	routes.ReservationRoutes(router, s, planner)
+	routes.WaitlistRoutes(router, s, planner, notifier)
+	routes.KitchenRoutes(router, s, hub, inv)
+	routes.NoteRoutes(router, s, hub)
+	routes.IngredientRoutes(router, s, inv)
+	routes.SupplierRoutes(router, s)
+	routes.PurchaseOrderRoutes(router, s, inv)
+	routes.WasteRoutes(router, s, inv)
 
 	router.Run(":" + port)
 
@@ -5,9 +5,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	helper "github.com/jamesconfy/restaurant-management/helpers"
+	"github.com/jamesconfy/restaurant-management/models"
+	"github.com/jamesconfy/restaurant-management/store"
 )
 
-func Authentication() gin.HandlerFunc {
+func Authentication(s *store.Store) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		clientToken := c.Request.Header.Get("token")
 		if clientToken == "" {
@@ -16,7 +18,23 @@ func Authentication() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := helper.ValidateToken(clientToken)
+		claims, err := helper.ValidateToken(c.Request.Context(), s.Revocations, clientToken)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		// Refresh tokens are only good for POST /api/token/refresh, and
+		// approval tokens only for approving an adjustment. A token that
+		// does not say what it is is not accepted either.
+		if claims.Token_Type != models.AccessToken {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		// The role is read from the user rather than the token, so a role
+		// change takes effect on tokens already handed out.
+		user, err := s.Users.Get(c.Request.Context(), claims.User_ID)
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			return
@@ -26,6 +44,8 @@ func Authentication() gin.HandlerFunc {
 		c.Set("userId", claims.User_ID)
 		c.Set("first_name", claims.First_Name)
 		c.Set("last_name", claims.Last_Name)
+		c.Set("role", user.UserRole())
+		c.Set("token", clientToken)
 
 		c.Next()
 	}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+type Permission string
+
+const (
+	ReadFoods  Permission = "food:read"
+	WriteFoods Permission = "food:write"
+	// EightySixFoods lets the kitchen and managers take a dish off when it
+	// cannot be made, and put it back.
+	EightySixFoods Permission = "food:86"
+
+	ReadMenus  Permission = "menu:read"
+	WriteMenus Permission = "menu:write"
+
+	ReadTables  Permission = "table:read"
+	WriteTables Permission = "table:write"
+	// CleanTables lets floor staff mark a table reset after guests leave.
+	CleanTables Permission = "table:clean"
+
+	// CheckAvailability is open to customers so the website can show free
+	// slots; booking itself is done by staff.
+	CheckAvailability Permission = "reservation:availability"
+	ReadReservations  Permission = "reservation:read"
+	WriteReservations Permission = "reservation:write"
+
+	// Orders and their items are never deleted: they are cancelled or voided
+	// with a manager's approval, which leaves an adjustment behind.
+	ReadOrders    Permission = "order:read"
+	WriteOrders   Permission = "order:write"
+	AdvanceOrders Permission = "order:advance"
+
+	ReadInvoices   Permission = "invoice:read"
+	WriteInvoices  Permission = "invoice:write"
+	DeleteInvoices Permission = "invoice:delete"
+
+	// Anyone who takes payments may ask for a refund, void or comp, but only
+	// holders of ApproveAdjustments can approve one.
+	ApproveAdjustments Permission = "adjustment:approve"
+	ReadAdjustments    Permission = "adjustment:read"
+
+	// Staff taking payment can put promotions on a bill; only managers set
+	// them up.
+	ReadPromotions  Permission = "promotion:read"
+	WritePromotions Permission = "promotion:write"
+	ApplyPromotions Permission = "promotion:apply"
+
+	// Anyone on staff can read and leave notes. Notes can only be changed by
+	// their author or someone with ManageNotes.
+	ReadNotes   Permission = "note:read"
+	WriteNotes  Permission = "note:write"
+	ManageNotes Permission = "note:manage"
+
+	// The kitchen keeps the stock counts; managers set up the ingredients.
+	ReadInventory  Permission = "inventory:read"
+	WriteInventory Permission = "inventory:write"
+	CountStock     Permission = "inventory:count"
+	LogWaste       Permission = "inventory:waste"
+
+	ReadUsers   Permission = "user:read"
+	ManageUsers Permission = "user:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	models.RoleManager: {
+		ReadFoods, WriteFoods, EightySixFoods, ReadMenus, WriteMenus, ReadTables, WriteTables, CleanTables,
+		CheckAvailability, ReadReservations, WriteReservations,
+		ReadOrders, WriteOrders, AdvanceOrders,
+		ReadInvoices, WriteInvoices, DeleteInvoices, ApproveAdjustments, ReadAdjustments,
+		ReadPromotions, WritePromotions, ApplyPromotions, ReadNotes, WriteNotes, ManageNotes,
+		ReadInventory, WriteInventory, CountStock, LogWaste,
+		ReadUsers,
+	},
+	models.RoleServer: {
+		ReadFoods, ReadMenus, ReadTables, CleanTables, ReadOrders, WriteOrders, AdvanceOrders,
+		ReadInvoices, WriteInvoices, CheckAvailability, ReadReservations, WriteReservations,
+		ReadPromotions, ApplyPromotions, ReadNotes, WriteNotes,
+	},
+	models.RoleKitchen: {
+		ReadFoods, EightySixFoods, ReadMenus, ReadOrders, AdvanceOrders, CheckAvailability,
+		ReadNotes, WriteNotes, ReadInventory, CountStock, LogWaste,
+	},
+	models.RoleCashier: {
+		ReadFoods, ReadMenus, ReadTables, ReadOrders, ReadInvoices, WriteInvoices,
+		CheckAvailability, ReadReservations, ReadPromotions, ApplyPromotions,
+		ReadNotes, WriteNotes,
+	},
+	models.RoleCustomer: {
+		ReadFoods, ReadMenus, CheckAvailability,
+	},
+}
+
+// HasPermission reports whether role grants perm. Admins are granted
+// everything.
+func HasPermission(role string, perm Permission) bool {
+	if role == models.RoleAdmin {
+		return true
+	}
+
+	for _, granted := range rolePermissions[role] {
+		if granted == perm {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Authorize must run after Authentication, which puts the caller's role on
+// the context.
+func Authorize(perm Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		if role == "" {
+			role = models.RoleCustomer
+		}
+
+		if !HasPermission(role, perm) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "You do not have permission to do that!"})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Adjustment is the audit record of a refund, void or comp. Every one is
+// approved by a manager, either directly or through an approval token.
+type Adjustment struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Adjustment_ID    string             `json:"adjustment_id"`
+	Type             string             `json:"type"`
+	Invoice_ID       string             `json:"invoice_id,omitempty"`
+	Order_ID         string             `json:"order_id,omitempty"`
+	Order_Item_ID    string             `json:"order_item_id,omitempty"`
+	Amount           Money              `json:"amount"`
+	Reason_Code      string             `json:"reason_code"`
+	Reason           string             `json:"reason"`
+	Refunds          []Refund           `json:"refunds,omitempty"`
+	Actor_ID         string             `json:"actor_id"`
+	Actor_Name       string             `json:"actor_name"`
+	Approved_By_ID   string             `json:"approved_by_id"`
+	Approved_By_Name string             `json:"approved_by_name"`
+	Approval_Method  string             `json:"approval_method"`
+	Created_At       time.Time          `json:"created_at"`
+}
+
+const (
+	AdjustmentRefund = "REFUND"
+	AdjustmentVoid   = "VOID"
+	AdjustmentComp   = "COMP"
+)
+
+// How a manager approved an adjustment: by being the one making it, by
+// entering their credentials, or with an approval token they issued.
+const (
+	ApprovedBySelf        = "SELF"
+	ApprovedByCredentials = "CREDENTIALS"
+	ApprovedByToken       = "TOKEN"
+)
+
+// ReasonCodes are the reasons finance reports adjustments under.
+var ReasonCodes = []string{
+	"CUSTOMER_COMPLAINT",
+	"QUALITY_ISSUE",
+	"WRONG_ITEM",
+	"SERVICE_RECOVERY",
+	"ENTERED_IN_ERROR",
+	"STAFF_MEAL",
+	"MANAGER_DISCRETION",
+	"OTHER",
+}
+
+// ItemAdjustment marks an order item as voided, comped or refunded. Voided
+// and comped items are no longer charged; a refunded item is still on the
+// bill but its share has been paid back.
+type ItemAdjustment struct {
+	Adjustment_ID string    `json:"adjustment_id"`
+	Type          string    `json:"type"`
+	Reason_Code   string    `json:"reason_code"`
+	At            time.Time `json:"at"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The 14 allergens EU law requires restaurants to declare.
+const (
+	AllergenCelery      = "CELERY"
+	AllergenGluten      = "GLUTEN"
+	AllergenCrustaceans = "CRUSTACEANS"
+	AllergenEggs        = "EGGS"
+	AllergenFish        = "FISH"
+	AllergenLupin       = "LUPIN"
+	AllergenMilk        = "MILK"
+	AllergenMolluscs    = "MOLLUSCS"
+	AllergenMustard     = "MUSTARD"
+	AllergenTreeNuts    = "TREE_NUTS"
+	AllergenPeanuts     = "PEANUTS"
+	AllergenSesame      = "SESAME"
+	AllergenSoya        = "SOYA"
+	AllergenSulphites   = "SULPHITES"
+)
+
+const (
+	DietVegan      = "VEGAN"
+	DietVegetarian = "VEGETARIAN"
+	DietHalal      = "HALAL"
+	DietGlutenFree = "GLUTEN_FREE"
+)
+
+// AllergyDeclaration is an allergy a guest told us about. Without a seat it
+// covers the whole table. Severe allergies stop conflicting items from being
+// ordered at all; others let them through with a warning.
+type AllergyDeclaration struct {
+	Allergen string `json:"allergen" validate:"required,oneof=CELERY GLUTEN CRUSTACEANS EGGS FISH LUPIN MILK MOLLUSCS MUSTARD TREE_NUTS PEANUTS SESAME SOYA SULPHITES"`
+	Seat     *int   `json:"seat" validate:"omitempty,min=1"`
+	Severe   bool   `json:"severe"`
+}
+
+// AllergyConflict is an ordered item containing something a guest declared.
+type AllergyConflict struct {
+	Food_Name string `json:"food_name"`
+	Allergen  string `json:"allergen"`
+	Seat      *int   `json:"seat,omitempty"`
+	Severe    bool   `json:"severe"`
+}
+
+func (c AllergyConflict) String() string {
+	if c.Seat != nil {
+		return fmt.Sprintf("%s contains %s (seat %d)", c.Food_Name, c.Allergen, *c.Seat)
+	}
+
+	return fmt.Sprintf("%s contains %s", c.Food_Name, c.Allergen)
+}
+
+// ItemAllergens is everything in the food as ordered: its own allergens and
+// those of the modifiers chosen.
+func (f Food) ItemAllergens(modifiers []SelectedModifier) []string {
+	found := map[string]bool{}
+	for _, allergen := range f.Allergens {
+		found[allergen] = true
+	}
+	for _, modifier := range modifiers {
+		for _, allergen := range modifier.Allergens {
+			found[allergen] = true
+		}
+	}
+
+	allergens := []string{}
+	for allergen := range found {
+		allergens = append(allergens, allergen)
+	}
+	sort.Strings(allergens)
+	return allergens
+}
+
+// HasDietaryTags reports whether the food carries every one of tags.
+func (f Food) HasDietaryTags(tags []string) bool {
+	for _, tag := range tags {
+		found := false
+		for _, have := range f.Dietary_Tags {
+			if have == tag {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AllergyConflicts checks an item against the declarations that cover its
+// seat.
+func (o Order) AllergyConflicts(item OrderItem, food Food) []AllergyConflict {
+	name := ""
+	if food.Name != nil {
+		name = *food.Name
+	}
+
+	conflicts := []AllergyConflict{}
+	for _, allergen := range food.ItemAllergens(item.Modifiers) {
+		for _, declaration := range o.Allergies {
+			if declaration.Allergen != allergen || !declaration.Covers(item.Seat) {
+				continue
+			}
+
+			conflicts = append(conflicts, AllergyConflict{Food_Name: name, Allergen: allergen, Seat: declaration.Seat, Severe: declaration.Severe})
+		}
+	}
+
+	return conflicts
+}
+
+// Covers reports whether the declaration applies to an item for seat.
+// Items not ordered for a particular seat could go to anyone.
+func (d AllergyDeclaration) Covers(seat *int) bool {
+	return d.Seat == nil || seat == nil || *d.Seat == *seat
+}
+
+// DeclaredAllergens lists the allergens declared for seat, for the kitchen.
+func (o Order) DeclaredAllergens(seat *int) []string {
+	found := map[string]bool{}
+	for _, declaration := range o.Allergies {
+		if declaration.Covers(seat) {
+			found[declaration.Allergen] = true
+		}
+	}
+
+	allergens := []string{}
+	for allergen := range found {
+		allergens = append(allergens, allergen)
+	}
+	sort.Strings(allergens)
+	return allergens
+}
+
+// AllergyAlert is the line printed across a kitchen ticket for an item that
+// was let through despite a declared allergy.
+func AllergyAlert(warnings []string) string {
+	if len(warnings) == 0 {
+		return ""
+	}
+
+	return "ALLERGY ALERT: contains " + strings.Join(warnings, ", ")
+}
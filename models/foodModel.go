@@ -7,12 +7,60 @@ import (
 )
 
 type Food struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Name       *string            `json:"name" validate:"required,min=2,max=100"`
-	Price      *float64           `json:"price" validate:"required"`
-	Food_Image *string            `json:"food_image" validate:"required"`
-	Food_ID    string             `json:"food_id" validate:"required"`
-	Menu_ID    *string            `json:"menu_id" validate:"required"`
-	Created_At time.Time          `json:"created_at"`
-	Updated_At time.Time          `json:"updated_at"`
+	ID           primitive.ObjectID `bson:"_id"`
+	Name         *string            `json:"name" validate:"required,min=2,max=100"`
+	Price        *Money             `json:"price" validate:"required"`
+	Food_Image   *string            `json:"food_image" validate:"required"`
+	Food_ID      string             `json:"food_id" validate:"required"`
+	Menu_ID      *string            `json:"menu_id" validate:"required"`
+	Station      *string            `json:"station"`
+	Allergens    []string           `json:"allergens" validate:"omitempty,dive,oneof=CELERY GLUTEN CRUSTACEANS EGGS FISH LUPIN MILK MOLLUSCS MUSTARD TREE_NUTS PEANUTS SESAME SOYA SULPHITES"`
+	Dietary_Tags []string           `json:"dietary_tags" validate:"omitempty,dive,oneof=VEGAN VEGETARIAN HALAL GLUTEN_FREE"`
+	// Modifier_Groups are the choices a guest makes when ordering the food.
+	Modifier_Groups []ModifierGroup `json:"modifier_groups" validate:"omitempty,dive"`
+	// Recipe is what one of the food takes from stock.
+	Recipe []RecipeLine `json:"recipe" validate:"omitempty,dive"`
+	// Available is false while the food is 86'd, either by hand or because
+	// an ingredient in its recipe ran out. Foods saved before it was tracked
+	// have none and are available.
+	Available          *bool     `json:"available"`
+	Unavailable_Reason *string   `json:"unavailable_reason"`
+	Created_At         time.Time `json:"created_at"`
+	Updated_At         time.Time `json:"updated_at"`
+}
+
+// Why a food is 86'd. Foods 86'd by hand stay off until someone puts them
+// back; those out of stock come back once their ingredients do.
+const (
+	UnavailableManual     = "MANUAL"
+	UnavailableOutOfStock = "OUT_OF_STOCK"
+)
+
+func (f Food) IsAvailable() bool {
+	return f.Available == nil || *f.Available
+}
+
+// EightySix takes the food off for reason.
+func (f *Food) EightySix(reason string) {
+	available := false
+	f.Available = &available
+	f.Unavailable_Reason = &reason
+}
+
+// Restore puts the food back on.
+func (f *Food) Restore() {
+	available := true
+	f.Available = &available
+	f.Unavailable_Reason = nil
+}
+
+// DefaultStation is where tickets go for foods that have no station set.
+const DefaultStation = "kitchen"
+
+func (f Food) KitchenStation() string {
+	if f.Station == nil || *f.Station == "" {
+		return DefaultStation
+	}
+
+	return *f.Station
 }
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ingredient is something the kitchen keeps in stock. On_Hand is in Unit and
+// may go negative when more is used than was counted in.
+type Ingredient struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Ingredient_ID string             `json:"ingredient_id"`
+	Name          *string            `json:"name" validate:"required,min=2,max=100"`
+	Unit          *string            `json:"unit" validate:"required,eq=G|eq=KG|eq=ML|eq=L|eq=EACH"`
+	On_Hand       float64            `json:"on_hand"`
+	// Low_Stock_Threshold raises a low stock alert once On_Hand falls to it.
+	Low_Stock_Threshold *float64 `json:"low_stock_threshold" validate:"omitempty,gte=0"`
+	// Par_Level is how much should be on hand after a delivery; reorders
+	// are suggested for ingredients that have one.
+	Par_Level *float64 `json:"par_level" validate:"omitempty,gte=0"`
+	// Supplier_ID is who the ingredient is usually ordered from.
+	Supplier_ID *string `json:"supplier_id"`
+	// Last_Cost is what the latest delivery cost.
+	Last_Cost  *StockCost `json:"last_cost"`
+	Created_At time.Time  `json:"created_at"`
+	Updated_At time.Time  `json:"updated_at"`
+}
+
+// StockCost is what was paid for a quantity of an ingredient.
+type StockCost struct {
+	Cost     Money   `json:"cost"`
+	Quantity float64 `json:"quantity"`
+}
+
+// Of prices quantity at the same rate.
+func (c StockCost) Of(quantity float64) Money {
+	if c.Quantity == 0 {
+		return NewMoney(0, c.Cost.Currency)
+	}
+
+	return c.Cost.Scale(quantity / c.Quantity)
+}
+
+const (
+	UnitGram       = "G"
+	UnitKilogram   = "KG"
+	UnitMillilitre = "ML"
+	UnitLitre      = "L"
+	UnitEach       = "EACH"
+)
+
+// Stock levels an ingredient moves between as it is used and restocked.
+const (
+	StockIn  = "IN_STOCK"
+	StockLow = "LOW_STOCK"
+	StockOut = "OUT_OF_STOCK"
+)
+
+func (i Ingredient) Level() string {
+	switch {
+	case i.On_Hand <= 0:
+		return StockOut
+	case i.Low_Stock_Threshold != nil && i.On_Hand <= *i.Low_Stock_Threshold:
+		return StockLow
+	}
+
+	return StockIn
+}
+
+// RecipeLine is how much of an ingredient goes into one of a food, in the
+// ingredient's unit.
+type RecipeLine struct {
+	Ingredient_ID string  `json:"ingredient_id" validate:"required"`
+	Quantity      float64 `json:"quantity" validate:"gt=0"`
+}
+
+// StockNeeded is what making quantity of the food takes out of stock.
+func (f Food) StockNeeded(quantity int) []RecipeLine {
+	needed := []RecipeLine{}
+	for _, line := range f.Recipe {
+		needed = append(needed, RecipeLine{Ingredient_ID: line.Ingredient_ID, Quantity: line.Quantity * float64(quantity)})
+	}
+
+	return needed
+}
+
+func (f Food) UsesIngredient(ingredientId string) bool {
+	for _, line := range f.Recipe {
+		if line.Ingredient_ID == ingredientId {
+			return true
+		}
+	}
+
+	return false
+}
@@ -10,9 +10,164 @@ type Invoice struct {
 	ID               primitive.ObjectID `bson:"_id"`
 	Invoice_ID       string             `json:"invoice_id"`
 	Order_ID         string             `json:"order_id"`
-	Payment_Method   *string            `json:"payment_method" validate:"eq=CASH|eq=CARD|eq="`
-	Payment_Status   *string            `json:"payment_status" validate:"required,eq=PENDING|PAID"`
+	Payment_Method   *string            `json:"payment_method" validate:"omitempty,eq=CASH|eq=CARD|eq=VOUCHER|eq=MOBILE|eq=SPLIT"`
+	Payment_Status   *string            `json:"payment_status" validate:"omitempty,eq=PENDING|eq=PARTIALLY_PAID|eq=PAID|eq=PARTIALLY_REFUNDED|eq=REFUNDED|eq=VOIDED|eq=COMPED"`
 	Payment_Due_Date time.Time          `json:"payment_due_date"`
+	Payments         []Payment          `json:"payments"`
+	Splits           []BillSplit        `json:"splits"`
+	Refunds          []Refund           `json:"refunds"`
 	Created_At       time.Time          `json:"created_at"`
 	Updated_At       time.Time          `json:"updated_at"`
 }
+
+const (
+	PaymentPending       = "PENDING"
+	PaymentPartiallyPaid = "PARTIALLY_PAID"
+	PaymentPaid          = "PAID"
+	// Refunded statuses follow refunds made after payment. Voided and
+	// comped invoices are closed for good and take no further payments.
+	PaymentPartiallyRefunded = "PARTIALLY_REFUNDED"
+	PaymentRefunded          = "REFUNDED"
+	PaymentVoided            = "VOIDED"
+	PaymentComped            = "COMPED"
+)
+
+const (
+	TenderCash    = "CASH"
+	TenderCard    = "CARD"
+	TenderVoucher = "VOUCHER"
+	TenderMobile  = "MOBILE"
+)
+
+// Payment is one tender put towards an invoice. Amount is what was applied
+// to the bill; for cash, Tendered may be more and the difference is change.
+type Payment struct {
+	Payment_ID   string `json:"payment_id"`
+	Tender       string `json:"tender"`
+	Amount       Money  `json:"amount"`
+	Tendered     Money  `json:"tendered"`
+	Change_Given Money  `json:"change_given"`
+	Split_ID     string `json:"split_id,omitempty"`
+	Reference    string `json:"reference,omitempty"`
+	// Transaction_ID links a card payment to its provider transaction.
+	Transaction_ID string    `json:"transaction_id,omitempty"`
+	Received_By    string    `json:"received_by"`
+	Created_At     time.Time `json:"created_at"`
+}
+
+// Refund is money given back against one payment. A refund is PENDING from
+// when it is put on the invoice until the provider has made it; pending
+// refunds count as refunded so the same money cannot be refunded twice.
+// Refunds recorded before they had a status were all completed.
+type Refund struct {
+	Refund_ID      string    `json:"refund_id"`
+	Payment_ID     string    `json:"payment_id"`
+	Tender         string    `json:"tender"`
+	Amount         Money     `json:"amount"`
+	Transaction_ID string    `json:"transaction_id,omitempty"`
+	Adjustment_ID  string    `json:"adjustment_id"`
+	Status         string    `json:"status,omitempty"`
+	Created_At     time.Time `json:"created_at"`
+}
+
+const (
+	RefundPending   = "PENDING"
+	RefundCompleted = "COMPLETED"
+)
+
+func (r Refund) Pending() bool {
+	return r.Status == RefundPending
+}
+
+const (
+	SplitEven = "EVEN"
+	SplitSeat = "SEAT"
+	SplitItem = "ITEM"
+)
+
+// BillSplit is one guest's share of a split invoice.
+type BillSplit struct {
+	Split_ID       string   `json:"split_id"`
+	Label          string   `json:"label"`
+	Mode           string   `json:"mode"`
+	Seat           *int     `json:"seat,omitempty"`
+	Order_Item_IDs []string `json:"order_item_ids,omitempty"`
+	Amount         Money    `json:"amount"`
+}
+
+// AmountPaid adds up every payment, optionally only those against one split.
+func (i Invoice) AmountPaid(splitId string) Money {
+	paid := Money{}
+	for _, payment := range i.Payments {
+		if splitId == "" || payment.Split_ID == splitId {
+			paid = paid.Add(payment.Amount)
+		}
+	}
+
+	return paid
+}
+
+// AmountRefunded adds up refunds, optionally only those against one payment.
+func (i Invoice) AmountRefunded(paymentId string) Money {
+	refunded := Money{}
+	for _, refund := range i.Refunds {
+		if paymentId == "" || refund.Payment_ID == paymentId {
+			refunded = refunded.Add(refund.Amount)
+		}
+	}
+
+	return refunded
+}
+
+// Closed reports whether the invoice was voided or comped.
+func (i Invoice) Closed() bool {
+	return i.Payment_Status != nil && (*i.Payment_Status == PaymentVoided || *i.Payment_Status == PaymentComped)
+}
+
+// Unpaid reports whether the bill is still waiting on the guests.
+func (i Invoice) Unpaid() bool {
+	return i.Payment_Status == nil || *i.Payment_Status == PaymentPending || *i.Payment_Status == PaymentPartiallyPaid
+}
+
+// DerivedStatus is the payment status the invoice should have given its
+// payments and refunds against total.
+func (i Invoice) DerivedStatus(total Money) string {
+	if i.Closed() {
+		return *i.Payment_Status
+	}
+
+	paid := i.AmountPaid("")
+	if refunded := i.AmountRefunded(""); refunded.Amount > 0 {
+		if refunded.Amount >= paid.Amount {
+			return PaymentRefunded
+		}
+		return PaymentPartiallyRefunded
+	}
+
+	return SettlementStatus(paid, total)
+}
+
+func (i Invoice) Split(splitId string) (BillSplit, bool) {
+	for _, split := range i.Splits {
+		if split.Split_ID == splitId {
+			return split, true
+		}
+	}
+
+	return BillSplit{}, false
+}
+
+// SettlementStatus derives the payment status from what has been paid
+// against total.
+func SettlementStatus(paid, total Money) string {
+	switch {
+	case paid.Amount > 0 && paid.Amount >= total.Amount:
+		return PaymentPaid
+	case total.Amount <= 0:
+		return PaymentPaid
+	case paid.Amount > 0:
+		return PaymentPartiallyPaid
+	}
+
+	return PaymentPending
+}
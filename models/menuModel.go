@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -12,7 +13,193 @@ type Menu struct {
 	Category   string             `json:"category" validate:"required"`
 	Start_Date *time.Time         `json:"start_date"`
 	End_Date   *time.Time         `json:"end_date"`
-	Created_At time.Time          `json:"created_at"`
-	Updated_At time.Time          `json:"updated_at"`
-	Menu_ID    string             `json:"menu_id"`
+	// Schedule limits the menu to certain hours, e.g. breakfast or late
+	// night. Without one the menu is served all day between its dates.
+	Schedule   *MenuSchedule `json:"schedule"`
+	Created_At time.Time     `json:"created_at"`
+	Updated_At time.Time     `json:"updated_at"`
+	Menu_ID    string        `json:"menu_id"`
+}
+
+// MenuSchedule is when a menu is served each week, in Timezone. Exceptions
+// override the weekly hours on particular dates such as holidays.
+type MenuSchedule struct {
+	Timezone   string              `json:"timezone" validate:"required"`
+	Windows    []ServiceWindow     `json:"windows" validate:"required,min=1,dive"`
+	Exceptions []ScheduleException `json:"exceptions" validate:"omitempty,dive"`
+}
+
+// ServiceWindow is a "15:04" clock range on the given days. A window that
+// ends before it starts runs past midnight into the next day.
+type ServiceWindow struct {
+	Days  []string `json:"days" validate:"required,min=1,dive,oneof=MON TUE WED THU FRI SAT SUN"`
+	Start string   `json:"start" validate:"required"`
+	End   string   `json:"end" validate:"required"`
+}
+
+// ScheduleException closes the menu on Date, or serves it only between
+// Start and End that day instead of its usual hours.
+type ScheduleException struct {
+	Date   string `json:"date" validate:"required"`
+	Name   string `json:"name"`
+	Closed bool   `json:"closed"`
+	Start  string `json:"start"`
+	End    string `json:"end"`
+}
+
+var weekdays = map[time.Weekday]string{
+	time.Monday: "MON", time.Tuesday: "TUE", time.Wednesday: "WED", time.Thursday: "THU",
+	time.Friday: "FRI", time.Saturday: "SAT", time.Sunday: "SUN",
+}
+
+// InDateRange reports whether at falls between the menu's start and end
+// dates. Either may be left open.
+func (m Menu) InDateRange(at time.Time) bool {
+	if m.Start_Date != nil && at.Before(*m.Start_Date) {
+		return false
+	}
+
+	if m.End_Date != nil && !at.Before(*m.End_Date) {
+		return false
+	}
+
+	return true
+}
+
+// ActiveAt reports whether the menu is being served at the given time.
+func (m Menu) ActiveAt(at time.Time) bool {
+	if !m.InDateRange(at) {
+		return false
+	}
+
+	if m.Schedule == nil {
+		return true
+	}
+
+	return m.Schedule.OpenAt(at)
+}
+
+func (s MenuSchedule) Validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("timezone %q: %w", s.Timezone, err)
+	}
+
+	for _, window := range s.Windows {
+		if _, _, err := clockRange(window.Start, window.End); err != nil {
+			return err
+		}
+	}
+
+	for _, exception := range s.Exceptions {
+		if _, err := time.Parse("2006-01-02", exception.Date); err != nil {
+			return fmt.Errorf("exception date %q is not YYYY-MM-DD", exception.Date)
+		}
+
+		if !exception.Closed {
+			if _, _, err := clockRange(exception.Start, exception.End); err != nil {
+				return fmt.Errorf("exception on %s: %w", exception.Date, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// OpenAt reports whether the schedule serves at the given time. A window
+// running past midnight counts towards the day it started on.
+func (s MenuSchedule) OpenAt(at time.Time) bool {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+
+	local := at.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	yesterday := local.AddDate(0, 0, -1)
+
+	for _, span := range s.spans(local) {
+		if minute >= span[0] && minute < span[1] {
+			return true
+		}
+	}
+
+	// What is left of yesterday's windows that ran past midnight.
+	for _, span := range s.spans(yesterday) {
+		if span[1] > 24*60 && minute < span[1]-24*60 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// spans is the minute ranges served on day's date, measured from that
+// day's midnight. Ranges running past midnight end after 24*60.
+func (s MenuSchedule) spans(day time.Time) [][2]int {
+	date := day.Format("2006-01-02")
+	for _, exception := range s.Exceptions {
+		if exception.Date != date {
+			continue
+		}
+
+		if exception.Closed {
+			return nil
+		}
+
+		start, end, err := clockRange(exception.Start, exception.End)
+		if err != nil {
+			return nil
+		}
+		return [][2]int{{start, end}}
+	}
+
+	spans := [][2]int{}
+	for _, window := range s.Windows {
+		if !window.on(day.Weekday()) {
+			continue
+		}
+
+		start, end, err := clockRange(window.Start, window.End)
+		if err != nil {
+			continue
+		}
+		spans = append(spans, [2]int{start, end})
+	}
+
+	return spans
+}
+
+func (w ServiceWindow) on(day time.Weekday) bool {
+	for _, d := range w.Days {
+		if d == weekdays[day] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clockRange turns "15:04" start and end times into minutes after midnight.
+// An end before the start is taken to be on the next day.
+func clockRange(start, end string) (int, int, error) {
+	from, err := time.Parse("15:04", start)
+	if err != nil {
+		return 0, 0, fmt.Errorf("start %q is not a 15:04 time", start)
+	}
+
+	to, err := time.Parse("15:04", end)
+	if err != nil {
+		return 0, 0, fmt.Errorf("end %q is not a 15:04 time", end)
+	}
+
+	startMinute := from.Hour()*60 + from.Minute()
+	endMinute := to.Hour()*60 + to.Minute()
+	if endMinute == startMinute {
+		return 0, 0, fmt.Errorf("%s to %s is an empty window", start, end)
+	}
+	if endMinute < startMinute {
+		endMinute += 24 * 60
+	}
+
+	return startMinute, endMinute, nil
 }
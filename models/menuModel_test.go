@@ -0,0 +1,78 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMenuScheduleOpenAt(t *testing.T) {
+	schedule := MenuSchedule{
+		Timezone: "UTC",
+		Windows: []ServiceWindow{
+			{Days: []string{"MON", "TUE", "WED", "THU", "FRI"}, Start: "11:00", End: "15:00"},
+			// Late night runs past midnight into the next day.
+			{Days: []string{"FRI", "SAT"}, Start: "22:00", End: "02:00"},
+		},
+		Exceptions: []ScheduleException{
+			{Date: "2026-12-24", Name: "Christmas Eve", Start: "15:00", End: "18:00"},
+			{Date: "2026-12-25", Name: "Christmas Day", Closed: true},
+		},
+	}
+
+	at := func(date, clock string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", date+" "+clock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		{name: "monday lunch", at: at("2026-10-19", "12:00"), want: true},
+		{name: "monday at opening", at: at("2026-10-19", "11:00"), want: true},
+		{name: "monday before opening", at: at("2026-10-19", "10:59"), want: false},
+		{name: "monday at closing", at: at("2026-10-19", "15:00"), want: false},
+		{name: "friday late night", at: at("2026-10-23", "23:00"), want: true},
+		{name: "friday night after midnight", at: at("2026-10-24", "01:30"), want: true},
+		{name: "friday night at its end", at: at("2026-10-24", "02:00"), want: false},
+		{name: "saturday night after midnight", at: at("2026-10-25", "01:00"), want: true},
+		{name: "nothing runs over from sunday", at: at("2026-10-26", "01:00"), want: false},
+		{name: "no lunch on saturday", at: at("2026-10-24", "12:00"), want: false},
+		{name: "christmas eve hours", at: at("2026-12-24", "16:00"), want: true},
+		{name: "christmas eve replaces lunch", at: at("2026-12-24", "12:00"), want: false},
+		{name: "closed on christmas day", at: at("2026-12-25", "12:00"), want: false},
+		{name: "closed christmas day does not run over", at: at("2026-12-26", "01:00"), want: false},
+		{name: "christmas day late night is closed too", at: at("2026-12-25", "23:00"), want: false},
+	}
+
+	for _, tt := range tests {
+		if got := schedule.OpenAt(tt.at); got != tt.want {
+			t.Errorf("%s: OpenAt(%v) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestMenuScheduleOpenAtTimezone(t *testing.T) {
+	schedule := MenuSchedule{
+		Timezone: "Europe/London",
+		Windows:  []ServiceWindow{{Days: []string{"MON"}, Start: "09:00", End: "10:00"}},
+	}
+
+	// 08:30 UTC is 09:30 in London in summer and 08:30 in winter.
+	if !schedule.OpenAt(time.Date(2026, 7, 6, 8, 30, 0, 0, time.UTC)) {
+		t.Error("the schedule should be open at 09:30 London time")
+	}
+
+	if schedule.OpenAt(time.Date(2026, 1, 5, 8, 30, 0, 0, time.UTC)) {
+		t.Error("the schedule should be closed at 08:30 London time")
+	}
+
+	schedule.Timezone = "Nowhere/Special"
+	if schedule.OpenAt(time.Date(2026, 7, 6, 8, 30, 0, 0, time.UTC)) {
+		t.Error("a schedule in an unknown timezone should never be open")
+	}
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ModifierGroup is a choice made when ordering a food, such as its size or
+// how it is cooked. Guests pick between Min_Select and Max_Select of the
+// group's modifiers; a Max_Select of 0 means as many as they like.
+type ModifierGroup struct {
+	Group_ID   string     `json:"group_id"`
+	Name       string     `json:"name" validate:"required,max=50"`
+	Kind       string     `json:"kind" validate:"required,eq=SIZE|eq=EXTRA|eq=REMOVAL|eq=TEMPERATURE|eq=OPTION"`
+	Min_Select int        `json:"min_select" validate:"gte=0"`
+	Max_Select int        `json:"max_select" validate:"gte=0"`
+	Modifiers  []Modifier `json:"modifiers" validate:"required,min=1,dive"`
+}
+
+const (
+	ModifierSize        = "SIZE"
+	ModifierExtra       = "EXTRA"
+	ModifierRemoval     = "REMOVAL"
+	ModifierTemperature = "TEMPERATURE"
+	ModifierOption      = "OPTION"
+)
+
+// Modifier is one choice in a group. Price_Delta is added to the food's
+// price and may be negative, e.g. for a smaller size.
+type Modifier struct {
+	Modifier_ID string `json:"modifier_id"`
+	Name        string `json:"name" validate:"required,max=50"`
+	Price_Delta Money  `json:"price_delta"`
+	// Allergens the modifier adds, e.g. MILK for extra cheese.
+	Allergens []string `json:"allergens" validate:"omitempty,dive,oneof=CELERY GLUTEN CRUSTACEANS EGGS FISH LUPIN MILK MOLLUSCS MUSTARD TREE_NUTS PEANUTS SESAME SOYA SULPHITES"`
+}
+
+// SelectedModifier is a modifier chosen on an order item. The names and
+// price delta are copied from the food when the item is ordered, so later
+// menu changes do not alter what was sold.
+type SelectedModifier struct {
+	Group_ID    string   `json:"group_id" validate:"required"`
+	Modifier_ID string   `json:"modifier_id" validate:"required"`
+	Group_Name  string   `json:"group_name"`
+	Kind        string   `json:"kind"`
+	Name        string   `json:"name"`
+	Price_Delta Money    `json:"price_delta"`
+	Allergens   []string `json:"allergens"`
+}
+
+// PrepareModifiers gives new groups and modifiers their ids and checks the
+// selection rules against the food's price.
+func (f *Food) PrepareModifiers() error {
+	groupIds := map[string]bool{}
+	for i := range f.Modifier_Groups {
+		group := &f.Modifier_Groups[i]
+		if group.Group_ID == "" {
+			group.Group_ID = primitive.NewObjectID().Hex()
+		}
+		if groupIds[group.Group_ID] {
+			return fmt.Errorf("modifier group %s appears twice", group.Group_ID)
+		}
+		groupIds[group.Group_ID] = true
+
+		if group.Max_Select > 0 && group.Max_Select < group.Min_Select {
+			return fmt.Errorf("%s: max_select is less than min_select", group.Name)
+		}
+		if group.Min_Select > len(group.Modifiers) {
+			return fmt.Errorf("%s: min_select is more than the modifiers on offer", group.Name)
+		}
+
+		modifierIds := map[string]bool{}
+		for j := range group.Modifiers {
+			modifier := &group.Modifiers[j]
+			if modifier.Modifier_ID == "" {
+				modifier.Modifier_ID = primitive.NewObjectID().Hex()
+			}
+			if modifierIds[modifier.Modifier_ID] {
+				return fmt.Errorf("%s: modifier %s appears twice", group.Name, modifier.Modifier_ID)
+			}
+			modifierIds[modifier.Modifier_ID] = true
+
+			if modifier.Price_Delta.Currency == "" {
+				modifier.Price_Delta = NewMoney(modifier.Price_Delta.Amount, f.Price.Currency)
+			}
+			if !modifier.Price_Delta.SameCurrency(*f.Price) {
+				return fmt.Errorf("%s: %s is priced in %s but the food in %s", group.Name, modifier.Name, modifier.Price_Delta.Currency, f.Price.Currency)
+			}
+		}
+	}
+
+	return nil
+}
+
+// SelectModifiers checks a guest's choices against the food's modifier groups
+// and returns them filled in from the menu.
+func (f Food) SelectModifiers(selected []SelectedModifier) ([]SelectedModifier, error) {
+	chosen := []SelectedModifier{}
+	counts := map[string]int{}
+	seen := map[string]bool{}
+	for _, selection := range selected {
+		group, modifier, ok := f.findModifier(selection.Group_ID, selection.Modifier_ID)
+		if !ok {
+			return nil, fmt.Errorf("modifier %s is not offered in group %s", selection.Modifier_ID, selection.Group_ID)
+		}
+
+		key := group.Group_ID + "/" + modifier.Modifier_ID
+		if seen[key] {
+			return nil, fmt.Errorf("%s was chosen more than once", modifier.Name)
+		}
+		seen[key] = true
+		counts[group.Group_ID]++
+
+		chosen = append(chosen, SelectedModifier{
+			Group_ID:    group.Group_ID,
+			Modifier_ID: modifier.Modifier_ID,
+			Group_Name:  group.Name,
+			Kind:        group.Kind,
+			Name:        modifier.Name,
+			Price_Delta: modifier.Price_Delta,
+			Allergens:   modifier.Allergens,
+		})
+	}
+
+	for _, group := range f.Modifier_Groups {
+		count := counts[group.Group_ID]
+		if count < group.Min_Select {
+			return nil, fmt.Errorf("%s: choose at least %d", group.Name, group.Min_Select)
+		}
+		if group.Max_Select > 0 && count > group.Max_Select {
+			return nil, fmt.Errorf("%s: choose at most %d", group.Name, group.Max_Select)
+		}
+	}
+
+	if f.Price != nil {
+		if price := ModifiedPrice(*f.Price, chosen); price.Amount < 0 {
+			return nil, fmt.Errorf("the modifiers chosen take the price below zero")
+		}
+	}
+
+	return chosen, nil
+}
+
+func (f Food) findModifier(groupId, modifierId string) (ModifierGroup, Modifier, bool) {
+	for _, group := range f.Modifier_Groups {
+		if group.Group_ID != groupId {
+			continue
+		}
+
+		for _, modifier := range group.Modifiers {
+			if modifier.Modifier_ID == modifierId {
+				return group, modifier, true
+			}
+		}
+	}
+
+	return ModifierGroup{}, Modifier{}, false
+}
+
+// ModifiedPrice is a unit price with the chosen modifiers' deltas added.
+func ModifiedPrice(base Money, modifiers []SelectedModifier) Money {
+	price := base
+	for _, modifier := range modifiers {
+		price = price.Add(modifier.Price_Delta)
+	}
+
+	return price
+}
@@ -0,0 +1,312 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultCurrency is used for amounts that arrive without a currency code.
+var DefaultCurrency = "USD"
+
+// ErrUnmigratedPrice is returned when a price is still a bare number from
+// before prices became Money.
+var ErrUnmigratedPrice = errors.New("price has not been migrated to money")
+
+// currencyExponents lists currencies whose minor unit is not a hundredth.
+var currencyExponents = map[string]int{
+	"BHD": 3, "CLP": 0, "IQD": 3, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "LYD": 3, "OMR": 3, "PYG": 0, "TND": 3, "UGX": 0, "VND": 0, "XAF": 0, "XOF": 0,
+}
+
+// Money is an exact amount in the currency's minor units, e.g. cents. It is
+// stored in Mongo as {amount: Decimal128, currency} and sent over JSON as
+// {"amount": "12.50", "currency": "USD"}.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+func NewMoney(minor int64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	return Money{Amount: minor, Currency: strings.ToUpper(currency)}
+}
+
+func CurrencyExponent(currency string) int {
+	if exponent, ok := currencyExponents[strings.ToUpper(currency)]; ok {
+		return exponent
+	}
+
+	return 2
+}
+
+// ParseMoney reads a decimal string such as "12.5" into minor units. It
+// refuses amounts with more decimal places than the currency allows rather
+// than silently rounding them.
+func ParseMoney(amount, currency string) (Money, error) {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	exponent := CurrencyExponent(currency)
+	amount = strings.TrimSpace(amount)
+	negative := strings.HasPrefix(amount, "-")
+	amount = strings.TrimPrefix(strings.TrimPrefix(amount, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if whole == "" && fraction == "" {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	if len(fraction) > exponent {
+		if strings.Trim(fraction[exponent:], "0") != "" {
+			return Money{}, fmt.Errorf("amount %q has more than %d decimal places for %s", amount, exponent, currency)
+		}
+		fraction = fraction[:exponent]
+	}
+	fraction += strings.Repeat("0", exponent-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if whole == "" {
+		minor, err = strconv.ParseInt(fraction, 10, 64)
+	}
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q", amount)
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return NewMoney(minor, currency), nil
+}
+
+// Decimal renders the amount in major units, e.g. "12.50".
+func (m Money) Decimal() string {
+	exponent := CurrencyExponent(m.Currency)
+	minor := m.Amount
+	sign := ""
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+
+	digits := strconv.FormatInt(minor, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) Add(other Money) Money {
+	return NewMoney(m.Amount+other.Amount, m.currencyOr(other))
+}
+
+func (m Money) Sub(other Money) Money {
+	return NewMoney(m.Amount-other.Amount, m.currencyOr(other))
+}
+
+func (m Money) Mul(quantity int64) Money {
+	return NewMoney(m.Amount*quantity, m.Currency)
+}
+
+// Scale multiplies the amount by a fraction such as a share of a delivery,
+// rounding half away from zero.
+func (m Money) Scale(factor float64) Money {
+	return NewMoney(int64(math.Round(float64(m.Amount)*factor)), m.Currency)
+}
+
+// SameCurrency reports whether the amounts can be combined. A zero value
+// with no currency combines with anything.
+func (m Money) SameCurrency(other Money) bool {
+	return m.Currency == "" || other.Currency == "" || m.Currency == other.Currency
+}
+
+func (m Money) currencyOr(other Money) string {
+	if m.Currency == "" {
+		return other.Currency
+	}
+
+	return m.Currency
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{Amount: m.Decimal(), Currency: currency})
+}
+
+// UnmarshalJSON accepts {"amount": "12.50", "currency": "USD"} as well as a
+// bare number or string in the default currency, which is what clients sent
+// before prices carried a currency.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var raw moneyJSON
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return err
+		}
+
+		amount, err := jsonAmount(raw.Amount)
+		if err != nil {
+			return err
+		}
+
+		parsed, err := ParseMoney(amount, raw.Currency)
+		if err != nil {
+			return err
+		}
+
+		*m = parsed
+		return nil
+	}
+
+	amount, err := jsonAmount(data)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := ParseMoney(amount, "")
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func jsonAmount(data json.RawMessage) (string, error) {
+	if len(data) == 0 {
+		return "", errors.New("amount is required")
+	}
+
+	if data[0] == '"' {
+		var amount string
+		err := json.Unmarshal(data, &amount)
+		return amount, err
+	}
+
+	var amount json.Number
+	if err := json.Unmarshal(data, &amount); err != nil {
+		return "", fmt.Errorf("invalid amount %s", data)
+	}
+
+	return amount.String(), nil
+}
+
+type moneyBSON struct {
+	Amount   primitive.Decimal128 `bson:"amount"`
+	Currency string               `bson:"currency"`
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	amount, err := primitive.ParseDecimal128(m.Decimal())
+	if err != nil {
+		return 0, nil, err
+	}
+
+	currency := m.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	return bson.MarshalValue(moneyBSON{Amount: amount, Currency: currency})
+}
+
+// UnmarshalBSONValue refuses bare numbers: prices written before they became
+// Money were not all stored the same way, so they must be converted by the
+// price migration, or by hand, rather than guessed at here.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+
+	switch t {
+	case bsontype.EmbeddedDocument:
+		var doc moneyBSON
+		if err := raw.Unmarshal(&doc); err != nil {
+			return err
+		}
+
+		parsed, err := ParseMoney(doc.Amount.String(), doc.Currency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case bsontype.Double, bsontype.Int32, bsontype.Int64, bsontype.Decimal128:
+		return fmt.Errorf("%w: found a bare %s price", ErrUnmigratedPrice, t)
+	case bsontype.Null:
+		*m = Money{}
+	default:
+		return fmt.Errorf("cannot decode %s into Money", t)
+	}
+
+	return nil
+}
+
+// LegacyMoney converts a price stored as a bare number before prices became
+// Money. Prices set on create went through the old toFixed helper, which
+// multiplied by 100 without dividing back, so 4.5 was stored as 450; the value
+// is divided by 100 before being converted into the currency's minor units.
+// toFixed only ever stored whole numbers, so anything else was not written by
+// it and is refused.
+func LegacyMoney(raw bson.RawValue, currency string) (Money, error) {
+	var legacy float64
+	switch raw.Type {
+	case bsontype.Double:
+		legacy = raw.Double()
+	case bsontype.Int32, bsontype.Int64:
+		legacy = float64(raw.AsInt64())
+	case bsontype.Decimal128:
+		parsed, err := strconv.ParseFloat(raw.Decimal128().String(), 64)
+		if err != nil {
+			return Money{}, err
+		}
+		legacy = parsed
+	default:
+		return Money{}, fmt.Errorf("unexpected %s price", raw.Type)
+	}
+
+	if legacy != math.Trunc(legacy) {
+		return Money{}, fmt.Errorf("price %v is not a whole number of cents", legacy)
+	}
+
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	scale := math.Pow10(CurrencyExponent(currency))
+	return NewMoney(int64(math.Round(legacy/100*scale)), currency), nil
+}
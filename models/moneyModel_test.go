@@ -0,0 +1,192 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{amount: "12.5", currency: "USD", want: Money{Amount: 1250, Currency: "USD"}},
+		{amount: "12.50", currency: "USD", want: Money{Amount: 1250, Currency: "USD"}},
+		{amount: "0.05", currency: "USD", want: Money{Amount: 5, Currency: "USD"}},
+		{amount: ".5", currency: "USD", want: Money{Amount: 50, Currency: "USD"}},
+		{amount: "5.", currency: "USD", want: Money{Amount: 500, Currency: "USD"}},
+		{amount: " 7 ", currency: "USD", want: Money{Amount: 700, Currency: "USD"}},
+		{amount: "-3.25", currency: "USD", want: Money{Amount: -325, Currency: "USD"}},
+		{amount: "+1", currency: "USD", want: Money{Amount: 100, Currency: "USD"}},
+		{amount: "1.250", currency: "USD", want: Money{Amount: 125, Currency: "USD"}},
+		{amount: "4.5", currency: "", want: Money{Amount: 450, Currency: DefaultCurrency}},
+		{amount: "4.5", currency: "eur", want: Money{Amount: 450, Currency: "EUR"}},
+		{amount: "1000", currency: "JPY", want: Money{Amount: 1000, Currency: "JPY"}},
+		{amount: "1.234", currency: "KWD", want: Money{Amount: 1234, Currency: "KWD"}},
+		{amount: "1.005", currency: "USD", wantErr: true},
+		{amount: "1.5", currency: "JPY", wantErr: true},
+		{amount: "", currency: "USD", wantErr: true},
+		{amount: ".", currency: "USD", wantErr: true},
+		{amount: "-", currency: "USD", wantErr: true},
+		{amount: "abc", currency: "USD", wantErr: true},
+		{amount: "1.2.3", currency: "USD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.amount, tt.currency)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q, %q) = %v, want an error", tt.amount, tt.currency, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseMoney(%q, %q) failed: %v", tt.amount, tt.currency, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q, %q) = %#v, want %#v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+type priced struct {
+	Price Money `bson:"price"`
+}
+
+func TestMoneyBSONRoundTrip(t *testing.T) {
+	tests := []Money{
+		{Amount: 1250, Currency: "USD"},
+		{Amount: 0, Currency: "USD"},
+		{Amount: -325, Currency: "USD"},
+		{Amount: 5, Currency: "EUR"},
+		{Amount: 1000, Currency: "JPY"},
+		{Amount: 1234, Currency: "KWD"},
+	}
+
+	for _, money := range tests {
+		raw, err := bson.Marshal(priced{Price: money})
+		if err != nil {
+			t.Errorf("marshalling %v failed: %v", money, err)
+			continue
+		}
+
+		var got priced
+		if err := bson.Unmarshal(raw, &got); err != nil {
+			t.Errorf("unmarshalling %v failed: %v", money, err)
+			continue
+		}
+
+		if got.Price != money {
+			t.Errorf("%v came back as %v", money, got.Price)
+		}
+	}
+}
+
+func TestMoneyBSONNoCurrency(t *testing.T) {
+	raw, err := bson.Marshal(priced{Price: Money{Amount: 999}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got priced
+	if err := bson.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (Money{Amount: 999, Currency: DefaultCurrency}); got.Price != want {
+		t.Errorf("got %v, want %v", got.Price, want)
+	}
+}
+
+func TestMoneyBSONLegacyNumbers(t *testing.T) {
+	legacy, _ := primitive.ParseDecimal128("1250")
+
+	tests := []struct {
+		name  string
+		price interface{}
+	}{
+		{name: "double", price: 450.0},
+		{name: "fractional double", price: 4.5},
+		{name: "int32", price: int32(450)},
+		{name: "int64", price: int64(1999)},
+		{name: "decimal128", price: legacy},
+	}
+
+	for _, tt := range tests {
+		raw, err := bson.Marshal(bson.D{{Key: "price", Value: tt.price}})
+		if err != nil {
+			t.Errorf("%s: marshalling failed: %v", tt.name, err)
+			continue
+		}
+
+		var got priced
+		if err := bson.Unmarshal(raw, &got); !errors.Is(err, ErrUnmigratedPrice) {
+			t.Errorf("%s: got %#v and error %v, want ErrUnmigratedPrice", tt.name, got.Price, err)
+		}
+	}
+}
+
+func TestMoneyBSONNull(t *testing.T) {
+	raw, err := bson.Marshal(bson.D{{Key: "price", Value: nil}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got priced
+	if err := bson.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Price != (Money{}) {
+		t.Errorf("got %#v, want the zero Money", got.Price)
+	}
+}
+
+func TestLegacyMoney(t *testing.T) {
+	tests := []struct {
+		price    interface{}
+		currency string
+		want     Money
+		wantErr  bool
+	}{
+		{price: 450.0, currency: "USD", want: Money{Amount: 450, Currency: "USD"}},
+		{price: int32(45000), currency: "JPY", want: Money{Amount: 450, Currency: "JPY"}},
+		{price: int64(1234), currency: "KWD", want: Money{Amount: 12340, Currency: "KWD"}},
+		{price: 450.0, currency: "", want: Money{Amount: 450, Currency: DefaultCurrency}},
+		{price: 4.5, currency: "USD", wantErr: true},
+		{price: 123.4, currency: "KWD", wantErr: true},
+		{price: "4.50", currency: "USD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		kind, data, err := bson.MarshalValue(tt.price)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := LegacyMoney(bson.RawValue{Type: kind, Value: data}, tt.currency)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("LegacyMoney(%v, %q) = %v, want an error", tt.price, tt.currency, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("LegacyMoney(%v, %q) failed: %v", tt.price, tt.currency, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("LegacyMoney(%v, %q) = %#v, want %#v", tt.price, tt.currency, got, tt.want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -13,4 +14,51 @@ type Note struct {
 	Created_At time.Time          `json:"created_at"`
 	Updated_At time.Time          `json:"updated_at"`
 	Note_ID    string             `json:"note_id"`
+	// Subject_Type and Subject_ID say what the note is attached to. A GUEST
+	// note is attached to the guest's user account.
+	Subject_Type string `json:"subject_type" validate:"required,eq=ORDER|eq=ORDER_ITEM|eq=TABLE|eq=INVOICE|eq=GUEST"`
+	Subject_ID   string `json:"subject_id" validate:"required"`
+	// Pinned notes are listed before the rest.
+	Pinned      bool   `json:"pinned"`
+	Author_ID   string `json:"author_id"`
+	Author_Name string `json:"author_name"`
+}
+
+const (
+	NoteOrder     = "ORDER"
+	NoteOrderItem = "ORDER_ITEM"
+	NoteTable     = "TABLE"
+	NoteInvoice   = "INVOICE"
+	NoteGuest     = "GUEST"
+)
+
+// NoteSubject is something notes can be attached to.
+type NoteSubject struct {
+	Type string
+	ID   string
+}
+
+func (n Note) Subject() NoteSubject {
+	return NoteSubject{Type: n.Subject_Type, ID: n.Subject_ID}
+}
+
+// SortNotes puts pinned notes first and the newest first within each.
+func SortNotes(notes []Note) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if notes[i].Pinned != notes[j].Pinned {
+			return notes[i].Pinned
+		}
+
+		return notes[i].Created_At.After(notes[j].Created_At)
+	})
+}
+
+// NoteLine is how a note is printed where there is only room for a line,
+// such as a kitchen ticket.
+func (n Note) NoteLine() string {
+	if n.Title == "" {
+		return n.Text
+	}
+
+	return n.Title + ": " + n.Text
 }
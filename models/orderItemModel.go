@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -8,11 +9,87 @@ import (
 
 type OrderItem struct {
 	ID            primitive.ObjectID `bson:"_id"`
-	Quantity      *int               `json:"quantity" validate:"required,eq=S|eq=M|eq=L"`
-	Unit_Price    *float64           `json:"unit_price" validate:"required"`
+	Quantity      *int               `json:"quantity" validate:"required,min=1"`
+	Unit_Price    *Money             `json:"unit_price"`
 	Created_At    time.Time          `json:"created_at"`
 	Updated_At    time.Time          `json:"updated_at"`
 	Food_ID       *string            `json:"food_id" validate:"required"`
 	Order_Item_ID string             `json:"order_item_id"`
 	Order_ID      *string            `json:"order_id"`
+	Status        *string            `json:"status"`
+	Seat          *int               `json:"seat" validate:"omitempty,min=1"`
+	Adjustment    *ItemAdjustment    `json:"adjustment"`
+	Modifiers     []SelectedModifier `json:"modifiers" validate:"omitempty,dive"`
+	// Allergen_Warnings are declared allergens the item was ordered with
+	// anyway.
+	Allergen_Warnings []string `json:"allergen_warnings"`
+	// Price_Rule is the price rule Unit_Price was set by, if any.
+	Price_Rule *AppliedPriceRule `json:"price_rule"`
+	// Stock_Used is what was taken from stock for the item, so exactly that
+	// goes back if it is cancelled.
+	Stock_Depleted bool         `json:"stock_depleted"`
+	Stock_Used     []RecipeLine `json:"stock_used"`
+}
+
+const (
+	ItemQueued    = "QUEUED"
+	ItemCooking   = "COOKING"
+	ItemReady     = "READY"
+	ItemServed    = "SERVED"
+	ItemCancelled = "CANCELLED"
+)
+
+// itemBumps is the order a kitchen screen moves an item through.
+var itemBumps = map[string]string{
+	ItemQueued:  ItemCooking,
+	ItemCooking: ItemReady,
+	ItemReady:   ItemServed,
+}
+
+// CurrentStatus treats items created before statuses existed as QUEUED.
+func (i OrderItem) CurrentStatus() string {
+	if i.Status == nil || *i.Status == "" {
+		return ItemQueued
+	}
+
+	return *i.Status
+}
+
+// Billable reports whether the item is charged on the bill.
+func (i OrderItem) Billable() bool {
+	if i.CurrentStatus() == ItemCancelled {
+		return false
+	}
+
+	if i.Adjustment != nil && (i.Adjustment.Type == AdjustmentVoid || i.Adjustment.Type == AdjustmentComp) {
+		return false
+	}
+
+	return true
+}
+
+// UnitPrice is what one of the item costs with its modifiers. The price
+// captured when the item was ordered wins over base, the food's current
+// price.
+func (i OrderItem) UnitPrice(base *Money) Money {
+	price := Money{}
+	if i.Unit_Price != nil {
+		price = *i.Unit_Price
+	} else if base != nil {
+		price = *base
+	}
+
+	return ModifiedPrice(price, i.Modifiers)
+}
+
+// Bump advances the item to its next kitchen status.
+func (i *OrderItem) Bump(at time.Time) error {
+	next, ok := itemBumps[i.CurrentStatus()]
+	if !ok {
+		return fmt.Errorf("an item that is %s cannot be bumped", i.CurrentStatus())
+	}
+
+	i.Status = &next
+	i.Updated_At = at
+	return nil
 }
@@ -1,16 +1,148 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type Order struct {
-	ID         primitive.ObjectID `bson:"_id"`
-	Order_Date time.Time          `json:"order_date" validate:"required"`
-	Created_At time.Time          `json:"created_at"`
-	Updated_At time.Time          `json:"updated_at"`
-	Order_ID   string             `json:"order_id"`
-	Table_ID   *string            `json:"table_id" validate:"required"`
+	ID             primitive.ObjectID `bson:"_id"`
+	Order_Date     time.Time          `json:"order_date" validate:"required"`
+	Created_At     time.Time          `json:"created_at"`
+	Updated_At     time.Time          `json:"updated_at"`
+	Order_ID       string             `json:"order_id"`
+	Table_ID       *string            `json:"table_id" validate:"required"`
+	Status         *string            `json:"status"`
+	Status_History []OrderTransition  `json:"status_history"`
+	// Waitlist_Entry_ID seats a waiting party when the order is created.
+	Waitlist_Entry_ID *string `json:"waitlist_entry_id"`
+	// Server_ID is the member of staff looking after the order.
+	Server_ID   *string `json:"server_id"`
+	Merged_Into *string `json:"merged_into,omitempty"`
+	// Allergies are what the guests declared when ordering.
+	Allergies []AllergyDeclaration `json:"allergies" validate:"omitempty,dive"`
+	// Promotions are taken off the bill in the order they were applied.
+	Promotions []AppliedPromotion `json:"promotions"`
+}
+
+type OrderTransition struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+	By     string    `json:"by"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+const (
+	OrderPlaced    = "PLACED"
+	OrderAccepted  = "ACCEPTED"
+	OrderPreparing = "PREPARING"
+	OrderReady     = "READY"
+	OrderServed    = "SERVED"
+	OrderClosed    = "CLOSED"
+	OrderCancelled = "CANCELLED"
+	OrderVoided    = "VOIDED"
+	// OrderMerged orders had their items moved onto another order's check.
+	OrderMerged = "MERGED"
+)
+
+// orderTransitions lists the statuses each status may move to. An order can
+// be cancelled until the kitchen starts on it and voided after that.
+var orderTransitions = map[string][]string{
+	OrderPlaced:    {OrderAccepted, OrderCancelled},
+	OrderAccepted:  {OrderPreparing, OrderCancelled},
+	OrderPreparing: {OrderReady, OrderVoided},
+	OrderReady:     {OrderServed, OrderVoided},
+	OrderServed:    {OrderClosed, OrderVoided},
+}
+
+type IllegalTransitionError struct {
+	From string
+	To   string
+}
+
+func (e *IllegalTransitionError) Error() string {
+	return fmt.Sprintf("an order cannot move from %s to %s", e.From, e.To)
+}
+
+// CurrentStatus treats orders created before statuses existed as PLACED.
+func (o Order) CurrentStatus() string {
+	if o.Status == nil || *o.Status == "" {
+		return OrderPlaced
+	}
+
+	return *o.Status
+}
+
+func CanTransition(from, to string) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Transition moves the order to status to and records who did it and when.
+func (o *Order) Transition(to, by, reason string, at time.Time) error {
+	from := o.CurrentStatus()
+	if !CanTransition(from, to) {
+		return &IllegalTransitionError{From: from, To: to}
+	}
+
+	o.Status = &to
+	o.Status_History = append(o.Status_History, OrderTransition{From: from, To: to, At: at, By: by, Reason: reason})
+	o.Updated_At = at
+	return nil
+}
+
+// ItemsEditable reports whether items may still be added, changed or removed.
+// Once the kitchen has finished preparing an order its items are locked.
+func (o Order) ItemsEditable() bool {
+	switch o.CurrentStatus() {
+	case OrderPlaced, OrderAccepted, OrderPreparing:
+		return true
+	}
+
+	return false
+}
+
+// Open reports whether the order still has its table.
+func (o Order) Open() bool {
+	switch o.CurrentStatus() {
+	case OrderClosed, OrderCancelled, OrderVoided, OrderMerged:
+		return false
+	}
+
+	return true
+}
+
+// MergeInto closes the order once its items have been moved onto target.
+// Unlike Transition it is allowed from any open status.
+func (o *Order) MergeInto(target, by string, at time.Time) error {
+	from := o.CurrentStatus()
+	if !o.Open() {
+		return &IllegalTransitionError{From: from, To: OrderMerged}
+	}
+
+	to := OrderMerged
+	o.Status = &to
+	o.Merged_Into = &target
+	o.Status_History = append(o.Status_History, OrderTransition{From: from, To: to, At: at, By: by, Reason: "merged into " + target})
+	o.Updated_At = at
+	return nil
+}
+
+// StatusChangedAt returns when the order last entered status, if it has.
+func (o Order) StatusChangedAt(status string) (time.Time, bool) {
+	for i := len(o.Status_History) - 1; i >= 0; i-- {
+		if o.Status_History[i].To == status {
+			return o.Status_History[i].At, true
+		}
+	}
+
+	return time.Time{}, false
 }
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var orderStatuses = []string{
+	OrderPlaced, OrderAccepted, OrderPreparing, OrderReady, OrderServed,
+	OrderClosed, OrderCancelled, OrderVoided, OrderMerged,
+}
+
+func TestOrderTransitions(t *testing.T) {
+	allowed := map[[2]string]bool{
+		{OrderPlaced, OrderAccepted}:    true,
+		{OrderPlaced, OrderCancelled}:   true,
+		{OrderAccepted, OrderPreparing}: true,
+		{OrderAccepted, OrderCancelled}: true,
+		{OrderPreparing, OrderReady}:    true,
+		{OrderPreparing, OrderVoided}:   true,
+		{OrderReady, OrderServed}:       true,
+		{OrderReady, OrderVoided}:       true,
+		{OrderServed, OrderClosed}:      true,
+		{OrderServed, OrderVoided}:      true,
+	}
+
+	for _, from := range orderStatuses {
+		for _, to := range orderStatuses {
+			if got, want := CanTransition(from, to), allowed[[2]string{from, to}]; got != want {
+				t.Errorf("CanTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestOrderTransition(t *testing.T) {
+	at := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	status := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		status  *string
+		to      string
+		wantErr bool
+	}{
+		{name: "placed to accepted", status: status(OrderPlaced), to: OrderAccepted},
+		{name: "no status counts as placed", status: nil, to: OrderAccepted},
+		{name: "empty status counts as placed", status: status(""), to: OrderCancelled},
+		{name: "cannot skip the kitchen", status: status(OrderPlaced), to: OrderServed, wantErr: true},
+		{name: "cannot cancel once preparing", status: status(OrderPreparing), to: OrderCancelled, wantErr: true},
+		{name: "closed is final", status: status(OrderClosed), to: OrderVoided, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		order := Order{Status: tt.status}
+		from := order.CurrentStatus()
+		err := order.Transition(tt.to, "user", "reason", at)
+
+		if tt.wantErr {
+			var illegal *IllegalTransitionError
+			if !errors.As(err, &illegal) || illegal.From != from || illegal.To != tt.to {
+				t.Errorf("%s: got %v, want an IllegalTransitionError from %s to %s", tt.name, err, from, tt.to)
+			}
+			if order.CurrentStatus() != from || len(order.Status_History) != 0 {
+				t.Errorf("%s: a refused transition changed the order", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if order.CurrentStatus() != tt.to || !order.Updated_At.Equal(at) {
+			t.Errorf("%s: order is %s at %v, want %s at %v", tt.name, order.CurrentStatus(), order.Updated_At, tt.to, at)
+		}
+
+		want := OrderTransition{From: from, To: tt.to, At: at, By: "user", Reason: "reason"}
+		if len(order.Status_History) != 1 || order.Status_History[0] != want {
+			t.Errorf("%s: history is %+v, want [%+v]", tt.name, order.Status_History, want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PaymentTransaction is the record of one card payment at the provider, kept
+// so what the provider reports can be reconciled against the invoice.
+type PaymentTransaction struct {
+	ID                 primitive.ObjectID `bson:"_id"`
+	Transaction_ID     string             `json:"transaction_id"`
+	Invoice_ID         string             `json:"invoice_id"`
+	Payment_ID         string             `json:"payment_id"`
+	Provider           string             `json:"provider"`
+	Provider_Reference string             `json:"provider_reference"`
+	Status             string             `json:"status"`
+	Amount             Money              `json:"amount"`
+	Refunded           Money              `json:"refunded"`
+	Failure_Reason     string             `json:"failure_reason,omitempty"`
+	Reconciled_At      *time.Time         `json:"reconciled_at"`
+	Events             []TransactionEvent `json:"events"`
+	Created_At         time.Time          `json:"created_at"`
+	Updated_At         time.Time          `json:"updated_at"`
+}
+
+// TransactionEvent is a single call to the provider, or a webhook from it.
+type TransactionEvent struct {
+	Kind     string    `json:"kind"`
+	Status   string    `json:"status"`
+	Amount   Money     `json:"amount"`
+	Event_ID string    `json:"event_id,omitempty"`
+	Reason   string    `json:"reason,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+const (
+	TransactionAuthorized = "AUTHORIZED"
+	TransactionCaptured   = "CAPTURED"
+	TransactionVoided     = "VOIDED"
+	TransactionRefunded   = "REFUNDED"
+	TransactionDeclined   = "DECLINED"
+	TransactionFailed     = "FAILED"
+)
+
+const (
+	EventAuthorize = "AUTHORIZE"
+	EventCapture   = "CAPTURE"
+	EventVoid      = "VOID"
+	EventRefund    = "REFUND"
+	EventWebhook   = "WEBHOOK"
+)
+
+func (t *PaymentTransaction) Record(kind, status string, amount Money, reason string, at time.Time) {
+	t.Status = status
+	t.Updated_At = at
+	if reason != "" {
+		t.Failure_Reason = reason
+	}
+	t.Events = append(t.Events, TransactionEvent{Kind: kind, Status: status, Amount: amount, Reason: reason, At: at})
+}
+
+// SeenEvent reports whether the provider's webhook eventId was already
+// applied, since providers may deliver the same webhook more than once.
+func (t PaymentTransaction) SeenEvent(eventId string) bool {
+	for _, event := range t.Events {
+		if event.Event_ID != "" && event.Event_ID == eventId {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PriceRule changes what foods cost while it is in force: happy hours, lunch
+// specials, weekend surcharges. It covers the foods listed in Food_IDs, on
+// the menus in Menu_IDs or in the menu Categories; a rule naming none of
+// them covers everything.
+type PriceRule struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Rule_ID    string             `json:"rule_id"`
+	Name       *string            `json:"name" validate:"required,min=2,max=100"`
+	Type       *string            `json:"type" validate:"required,eq=PERCENT|eq=AMOUNT|eq=PRICE"`
+	Percent    *float64           `json:"percent" validate:"omitempty,gte=-100"`
+	Amount     *Money             `json:"amount"`
+	Food_IDs   []string           `json:"food_ids"`
+	Menu_IDs   []string           `json:"menu_ids"`
+	Categories []string           `json:"categories"`
+	Starts_At  *time.Time         `json:"starts_at"`
+	Ends_At    *time.Time         `json:"ends_at"`
+	Schedule   *MenuSchedule      `json:"schedule"`
+	Priority   *int               `json:"priority"`
+	Active     *bool              `json:"active"`
+	Created_At time.Time          `json:"created_at"`
+	Updated_At time.Time          `json:"updated_at"`
+}
+
+// A PERCENT rule moves the price by Percent, negative for a discount; an
+// AMOUNT rule adds Amount, which may be negative; a PRICE rule sells at
+// Amount outright.
+const (
+	PriceRulePercent = "PERCENT"
+	PriceRuleAmount  = "AMOUNT"
+	PriceRulePrice   = "PRICE"
+)
+
+// AppliedPriceRule records on an order item which rule priced it and what
+// the food would have cost without it.
+type AppliedPriceRule struct {
+	Rule_ID    string `json:"rule_id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Base_Price Money  `json:"base_price"`
+	Difference Money  `json:"difference"`
+}
+
+// Check makes sure the rule has the value its type needs and sensible times.
+func (r PriceRule) Check() error {
+	switch *r.Type {
+	case PriceRulePercent:
+		if r.Percent == nil {
+			return errors.New("a PERCENT rule needs a percent")
+		}
+	case PriceRuleAmount, PriceRulePrice:
+		if r.Amount == nil {
+			return errors.New("an " + *r.Type + " rule needs an amount")
+		}
+		if *r.Type == PriceRulePrice && r.Amount.Amount < 0 {
+			return errors.New("a PRICE rule cannot sell below zero")
+		}
+	}
+
+	if r.Starts_At != nil && r.Ends_At != nil && !r.Ends_At.After(*r.Starts_At) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	if r.Schedule != nil {
+		return r.Schedule.Validate()
+	}
+
+	return nil
+}
+
+// InForce reports whether the rule is switched on and within its dates and
+// schedule at the given time.
+func (r PriceRule) InForce(at time.Time) bool {
+	if r.Active != nil && !*r.Active {
+		return false
+	}
+
+	if r.Starts_At != nil && at.Before(*r.Starts_At) {
+		return false
+	}
+
+	if r.Ends_At != nil && !at.Before(*r.Ends_At) {
+		return false
+	}
+
+	return r.Schedule == nil || r.Schedule.OpenAt(at)
+}
+
+// Covers reports whether the rule is scoped to the food.
+func (r PriceRule) Covers(food Food, menu Menu) bool {
+	if len(r.Food_IDs) == 0 && len(r.Menu_IDs) == 0 && len(r.Categories) == 0 {
+		return true
+	}
+
+	for _, foodId := range r.Food_IDs {
+		if foodId == food.Food_ID {
+			return true
+		}
+	}
+
+	for _, menuId := range r.Menu_IDs {
+		if food.Menu_ID != nil && menuId == *food.Menu_ID {
+			return true
+		}
+	}
+
+	for _, category := range r.Categories {
+		if category == menu.Category {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Apply works out the price under the rule. Prices never go below zero.
+func (r PriceRule) Apply(base Money) Money {
+	price := base
+	switch *r.Type {
+	case PriceRulePercent:
+		price = NewMoney(int64(math.Round(float64(base.Amount)*(100+*r.Percent)/100)), base.Currency)
+	case PriceRuleAmount:
+		price = base.Add(*r.Amount)
+	case PriceRulePrice:
+		price = NewMoney(r.Amount.Amount, base.currencyOr(*r.Amount))
+	}
+
+	if price.Amount < 0 {
+		price.Amount = 0
+	}
+
+	return price
+}
+
+func (r PriceRule) priority() int {
+	if r.Priority == nil {
+		return 0
+	}
+
+	return *r.Priority
+}
+
+// RulePrice prices food at the given time under the highest priority rule
+// that is in force and covers it. Rules do not stack; on a tie the newer
+// rule wins. Rules with an amount in another currency are passed over. The
+// applied rule is nil when none applies.
+func RulePrice(rules []PriceRule, food Food, menu Menu, at time.Time) (Money, *AppliedPriceRule) {
+	base := Money{}
+	if food.Price != nil {
+		base = *food.Price
+	}
+
+	var best *PriceRule
+	for i, rule := range rules {
+		if !rule.InForce(at) || !rule.Covers(food, menu) {
+			continue
+		}
+
+		if rule.Amount != nil && *rule.Type != PriceRulePercent && !rule.Amount.SameCurrency(base) {
+			continue
+		}
+
+		if best == nil || rule.priority() > best.priority() || (rule.priority() == best.priority() && rule.Created_At.After(best.Created_At)) {
+			best = &rules[i]
+		}
+	}
+
+	if best == nil {
+		return base, nil
+	}
+
+	price := best.Apply(base)
+	return price, &AppliedPriceRule{
+		Rule_ID:    best.Rule_ID,
+		Name:       *best.Name,
+		Type:       *best.Type,
+		Base_Price: base,
+		Difference: price.Sub(base),
+	}
+}
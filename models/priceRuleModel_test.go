@@ -0,0 +1,93 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRulePrice(t *testing.T) {
+	now := time.Date(2026, 10, 19, 17, 30, 0, 0, time.UTC)
+	earlier, later := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+	menuId := "drinks-menu"
+	food := Food{Food_ID: "lager", Menu_ID: &menuId, Price: &Money{Amount: 1000, Currency: "USD"}}
+	menu := Menu{Menu_ID: menuId, Category: "DRINKS"}
+
+	text := func(s string) *string { return &s }
+	number := func(n int) *int { return &n }
+	percent := func(p float64) *float64 { return &p }
+	money := func(minor int64, currency string) *Money { return &Money{Amount: minor, Currency: currency} }
+	off := false
+
+	rule := func(id, kind string, change func(*PriceRule)) PriceRule {
+		r := PriceRule{Rule_ID: id, Name: text(id), Type: text(kind), Created_At: earlier}
+		change(&r)
+		return r
+	}
+	happyHour := rule("happy-hour", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-20) })
+
+	tests := []struct {
+		name     string
+		rules    []PriceRule
+		want     int64
+		wantRule string
+		wantDiff int64
+	}{
+		{name: "no rules", want: 1000},
+		{name: "percent off", rules: []PriceRule{happyHour}, want: 800, wantRule: "happy-hour", wantDiff: -200},
+		{name: "percent on", rules: []PriceRule{rule("surcharge", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(12.5) })}, want: 1125, wantRule: "surcharge", wantDiff: 125},
+		{name: "amount off", rules: []PriceRule{rule("dollar-off", PriceRuleAmount, func(r *PriceRule) { r.Amount = money(-100, "USD") })}, want: 900, wantRule: "dollar-off", wantDiff: -100},
+		{name: "fixed price", rules: []PriceRule{rule("special", PriceRulePrice, func(r *PriceRule) { r.Amount = money(650, "USD") })}, want: 650, wantRule: "special", wantDiff: -350},
+		{name: "never below zero", rules: []PriceRule{rule("giveaway", PriceRuleAmount, func(r *PriceRule) { r.Amount = money(-1500, "USD") })}, want: 0, wantRule: "giveaway", wantDiff: -1000},
+		{name: "switched off", rules: []PriceRule{rule("off", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-50); r.Active = &off })}, want: 1000},
+		{name: "not started", rules: []PriceRule{rule("soon", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-50); r.Starts_At = &later })}, want: 1000},
+		{name: "ended", rules: []PriceRule{rule("over", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-50); r.Ends_At = &now })}, want: 1000},
+		{name: "outside its schedule", rules: []PriceRule{rule("brunch", PriceRulePercent, func(r *PriceRule) {
+			r.Percent = percent(-50)
+			r.Schedule = &MenuSchedule{Timezone: "UTC", Windows: []ServiceWindow{{Days: []string{"SAT", "SUN"}, Start: "10:00", End: "14:00"}}}
+		})}, want: 1000},
+		{name: "within its schedule", rules: []PriceRule{rule("after-work", PriceRulePercent, func(r *PriceRule) {
+			r.Percent = percent(-50)
+			r.Schedule = &MenuSchedule{Timezone: "UTC", Windows: []ServiceWindow{{Days: []string{"MON"}, Start: "17:00", End: "19:00"}}}
+		})}, want: 500, wantRule: "after-work", wantDiff: -500},
+		{name: "other food", rules: []PriceRule{rule("wine", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-50); r.Food_IDs = []string{"wine"} })}, want: 1000},
+		{name: "by food", rules: []PriceRule{rule("lager", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-50); r.Food_IDs = []string{"lager"} })}, want: 500, wantRule: "lager", wantDiff: -500},
+		{name: "by menu", rules: []PriceRule{rule("menu", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-50); r.Menu_IDs = []string{menuId} })}, want: 500, wantRule: "menu", wantDiff: -500},
+		{name: "by category", rules: []PriceRule{rule("category", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-50); r.Categories = []string{"DRINKS"} })}, want: 500, wantRule: "category", wantDiff: -500},
+		{name: "other currency passed over", rules: []PriceRule{rule("euro", PriceRulePrice, func(r *PriceRule) { r.Amount = money(500, "EUR") })}, want: 1000},
+		{name: "higher priority wins", rules: []PriceRule{
+			rule("newer", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-10); r.Created_At = now }),
+			rule("important", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-30); r.Priority = number(5) }),
+		}, want: 700, wantRule: "important", wantDiff: -300},
+		{name: "newer wins a tie", rules: []PriceRule{
+			rule("newer", PriceRulePercent, func(r *PriceRule) { r.Percent = percent(-10); r.Created_At = now }),
+			happyHour,
+		}, want: 900, wantRule: "newer", wantDiff: -100},
+		{name: "rules do not stack", rules: []PriceRule{
+			happyHour,
+			rule("dollar-off", PriceRuleAmount, func(r *PriceRule) { r.Amount = money(-100, "USD"); r.Created_At = earlier.Add(-time.Hour) }),
+		}, want: 800, wantRule: "happy-hour", wantDiff: -200},
+	}
+
+	for _, tt := range tests {
+		price, applied := RulePrice(tt.rules, food, menu, now)
+		if price != (Money{Amount: tt.want, Currency: "USD"}) {
+			t.Errorf("%s: price is %v, want %d", tt.name, price, tt.want)
+		}
+
+		if tt.wantRule == "" {
+			if applied != nil {
+				t.Errorf("%s: rule %s was applied", tt.name, applied.Rule_ID)
+			}
+			continue
+		}
+
+		if applied == nil {
+			t.Errorf("%s: no rule was applied, want %s", tt.name, tt.wantRule)
+			continue
+		}
+
+		if applied.Rule_ID != tt.wantRule || applied.Base_Price != *food.Price || applied.Difference.Amount != tt.wantDiff {
+			t.Errorf("%s: applied %+v, want %s moving %v by %d", tt.name, *applied, tt.wantRule, *food.Price, tt.wantDiff)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Promotion is a discount taken off a bill. Promotions with a Code are
+// coupons the guest hands over; the rest are applied by staff. Like price
+// rules, a promotion scoped to no foods or categories covers everything.
+type Promotion struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Promotion_ID string             `json:"promotion_id"`
+	Name         *string            `json:"name" validate:"required,min=2,max=100"`
+	Code         *string            `json:"code" validate:"omitempty,alphanum,min=3,max=30"`
+	Type         *string            `json:"type" validate:"required,eq=PERCENT|eq=AMOUNT|eq=BUY_X_GET_Y"`
+	Percent      *float64           `json:"percent" validate:"omitempty,gt=0,lte=100"`
+	Amount       *Money             `json:"amount"`
+	// A BUY_X_GET_Y promotion takes Get_Percent, 100 when unset, off the
+	// cheapest Get_Quantity of every Buy_Quantity + Get_Quantity covered
+	// items.
+	Buy_Quantity *int     `json:"buy_quantity" validate:"omitempty,min=1"`
+	Get_Quantity *int     `json:"get_quantity" validate:"omitempty,min=1"`
+	Get_Percent  *float64 `json:"get_percent" validate:"omitempty,gt=0,lte=100"`
+	Food_IDs     []string `json:"food_ids"`
+	Categories   []string `json:"categories"`
+	// Min_Spend is what the bill must come to, before discounts, for the
+	// promotion to take effect.
+	Min_Spend *Money     `json:"min_spend"`
+	Starts_At *time.Time `json:"starts_at"`
+	Ends_At   *time.Time `json:"ends_at"`
+	// Max_Uses limits how many orders the promotion can be applied to; Uses
+	// counts them.
+	Max_Uses *int `json:"max_uses" validate:"omitempty,min=1"`
+	Uses     int  `json:"uses"`
+	// Stackable promotions can be combined with other stackable ones. A
+	// promotion that is not stackable must be the only one on the order.
+	Stackable  *bool     `json:"stackable"`
+	Active     *bool     `json:"active"`
+	Created_At time.Time `json:"created_at"`
+	Updated_At time.Time `json:"updated_at"`
+}
+
+const (
+	PromotionPercent  = "PERCENT"
+	PromotionAmount   = "AMOUNT"
+	PromotionBuyXGetY = "BUY_X_GET_Y"
+)
+
+// AppliedPromotion is a promotion put on an order.
+type AppliedPromotion struct {
+	Promotion_ID string    `json:"promotion_id"`
+	Code         string    `json:"code,omitempty"`
+	Applied_By   string    `json:"applied_by"`
+	Applied_At   time.Time `json:"applied_at"`
+}
+
+// NormalizeCode is how coupon codes are stored and looked up, so guests can
+// type them in any case.
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Check makes sure the promotion has what its type needs and sensible times.
+func (p Promotion) Check() error {
+	switch *p.Type {
+	case PromotionPercent:
+		if p.Percent == nil {
+			return errors.New("a PERCENT promotion needs a percent")
+		}
+	case PromotionAmount:
+		if p.Amount == nil || p.Amount.Amount <= 0 {
+			return errors.New("an AMOUNT promotion needs an amount above zero")
+		}
+	case PromotionBuyXGetY:
+		if p.Buy_Quantity == nil || p.Get_Quantity == nil {
+			return errors.New("a BUY_X_GET_Y promotion needs buy_quantity and get_quantity")
+		}
+	}
+
+	if p.Min_Spend != nil && p.Min_Spend.Amount < 0 {
+		return errors.New("min_spend cannot be negative")
+	}
+
+	if p.Starts_At != nil && p.Ends_At != nil && !p.Ends_At.After(*p.Starts_At) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	return nil
+}
+
+// Usable says why the promotion cannot be applied at the given time, or
+// returns nil when it can.
+func (p Promotion) Usable(at time.Time) error {
+	if p.Active != nil && !*p.Active {
+		return errors.New("that promotion is not active")
+	}
+
+	if p.Starts_At != nil && at.Before(*p.Starts_At) {
+		return errors.New("that promotion has not started yet")
+	}
+
+	if p.Ends_At != nil && !at.Before(*p.Ends_At) {
+		return errors.New("that promotion has expired")
+	}
+
+	if p.Max_Uses != nil && p.Uses >= *p.Max_Uses {
+		return errors.New("that promotion has been used up")
+	}
+
+	return nil
+}
+
+// IsStackable reports whether the promotion may share an order with others.
+func (p Promotion) IsStackable() bool {
+	return p.Stackable != nil && *p.Stackable
+}
+
+// Covers reports whether a food on the bill counts towards the promotion.
+func (p Promotion) Covers(foodId, category string) bool {
+	if len(p.Food_IDs) == 0 && len(p.Categories) == 0 {
+		return true
+	}
+
+	for _, id := range p.Food_IDs {
+		if id == foodId {
+			return true
+		}
+	}
+
+	for _, c := range p.Categories {
+		if c == category {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPromotion reports whether promotionId is already applied to the order.
+func (o Order) HasPromotion(promotionId string) bool {
+	for _, applied := range o.Promotions {
+		if applied.Promotion_ID == promotionId {
+			return true
+		}
+	}
+
+	return false
+}
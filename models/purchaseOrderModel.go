@@ -0,0 +1,202 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PurchaseOrder is stock ordered from a supplier. Lines are priced for the
+// whole quantity ordered, as on the supplier's invoice, since a single gram
+// often costs less than the smallest coin.
+type PurchaseOrder struct {
+	ID                primitive.ObjectID  `bson:"_id"`
+	Purchase_Order_ID string              `json:"purchase_order_id"`
+	Supplier_ID       *string             `json:"supplier_id" validate:"required"`
+	Status            string              `json:"status"`
+	Lines             []PurchaseOrderLine `json:"lines" validate:"required,min=1,dive"`
+	Deliveries        []Delivery          `json:"deliveries"`
+	Expected_At       *time.Time          `json:"expected_at"`
+	Ordered_At        *time.Time          `json:"ordered_at"`
+	Created_By        string              `json:"created_by"`
+	Created_At        time.Time           `json:"created_at"`
+	Updated_At        time.Time           `json:"updated_at"`
+}
+
+type PurchaseOrderLine struct {
+	Ingredient_ID string  `json:"ingredient_id" validate:"required"`
+	Quantity      float64 `json:"quantity" validate:"gt=0"`
+	// Cost is the agreed price for all of Quantity.
+	Cost     *Money  `json:"cost" validate:"required"`
+	Received float64 `json:"received"`
+}
+
+// quantityTolerance is how far apart two stock quantities can be and still
+// count as the same, since 0.1 and 0.2 of a unit received do not add up to
+// exactly 0.3 in floating point.
+const quantityTolerance = 1e-6
+
+// Remaining is how much of the line is still to be delivered. A line short
+// by no more than quantityTolerance has nothing remaining.
+func (l PurchaseOrderLine) Remaining() float64 {
+	if l.Quantity-l.Received <= quantityTolerance {
+		return 0
+	}
+
+	return l.Quantity - l.Received
+}
+
+// Delivery is stock received against a purchase order.
+type Delivery struct {
+	Delivery_ID string         `json:"delivery_id"`
+	Lines       []DeliveryLine `json:"lines" validate:"required,min=1,dive"`
+	Received_By string         `json:"received_by"`
+	Received_At time.Time      `json:"received_at"`
+}
+
+type DeliveryLine struct {
+	Ingredient_ID string  `json:"ingredient_id" validate:"required"`
+	Quantity      float64 `json:"quantity" validate:"gt=0"`
+	// Cost is what was paid for Quantity. Left out, it is the ordered
+	// line's price for that much.
+	Cost *Money `json:"cost"`
+}
+
+const (
+	PurchaseDraft     = "DRAFT"
+	PurchaseOrdered   = "ORDERED"
+	PurchasePartial   = "PARTIALLY_RECEIVED"
+	PurchaseReceived  = "RECEIVED"
+	PurchaseCancelled = "CANCELLED"
+)
+
+var purchaseTransitions = map[string][]string{
+	PurchaseDraft:   {PurchaseOrdered, PurchaseCancelled},
+	PurchaseOrdered: {PurchaseCancelled},
+	// A part delivered order is closed short once nothing more is coming.
+	PurchasePartial: {PurchaseReceived},
+}
+
+func (p PurchaseOrder) Editable() bool {
+	return p.Status == PurchaseDraft
+}
+
+// Open reports whether more stock is still expected on the order.
+func (p PurchaseOrder) Open() bool {
+	return p.Status == PurchaseOrdered || p.Status == PurchasePartial
+}
+
+func (p *PurchaseOrder) MoveTo(status string, at time.Time) error {
+	for _, allowed := range purchaseTransitions[p.Status] {
+		if allowed == status {
+			if status == PurchaseOrdered {
+				p.Ordered_At = &at
+			}
+			p.Status = status
+			p.Updated_At = at
+			return nil
+		}
+	}
+
+	return fmt.Errorf("a purchase order that is %s cannot become %s", p.Status, status)
+}
+
+// Check reports lines that repeat an ingredient or are priced in another
+// currency.
+func (p PurchaseOrder) Check() error {
+	seen := map[string]bool{}
+	for _, line := range p.Lines {
+		if seen[line.Ingredient_ID] {
+			return fmt.Errorf("ingredient %s appears twice on the order", line.Ingredient_ID)
+		}
+		seen[line.Ingredient_ID] = true
+
+		if line.Cost.Amount < 0 {
+			return fmt.Errorf("the cost of ingredient %s cannot be negative", line.Ingredient_ID)
+		}
+
+		if !line.Cost.SameCurrency(*p.Lines[0].Cost) {
+			return fmt.Errorf("every line must be priced in %s", p.Lines[0].Cost.Currency)
+		}
+	}
+
+	return nil
+}
+
+func (p PurchaseOrder) Total() Money {
+	var total Money
+	for _, line := range p.Lines {
+		total = total.Add(*line.Cost)
+	}
+
+	return total
+}
+
+// Outstanding is how much of the ingredient is still to be delivered.
+func (p PurchaseOrder) Outstanding(ingredientId string) float64 {
+	if !p.Open() {
+		return 0
+	}
+
+	for _, line := range p.Lines {
+		if line.Ingredient_ID == ingredientId {
+			return line.Remaining()
+		}
+	}
+
+	return 0
+}
+
+// Receive books a delivery against the order, pricing lines sent without a
+// cost from the order, and marks the order received once every line is.
+func (p *PurchaseOrder) Receive(delivery *Delivery) error {
+	if !p.Open() {
+		return fmt.Errorf("stock cannot be received on a purchase order that is %s", p.Status)
+	}
+
+	lines := map[string]int{}
+	for i, line := range p.Lines {
+		lines[line.Ingredient_ID] = i
+	}
+
+	seen := map[string]bool{}
+	for i, received := range delivery.Lines {
+		index, ok := lines[received.Ingredient_ID]
+		if !ok {
+			return fmt.Errorf("ingredient %s is not on the order", received.Ingredient_ID)
+		}
+		if seen[received.Ingredient_ID] {
+			return fmt.Errorf("ingredient %s appears twice in the delivery", received.Ingredient_ID)
+		}
+		seen[received.Ingredient_ID] = true
+
+		line := &p.Lines[index]
+		if received.Quantity > line.Remaining()+quantityTolerance {
+			return fmt.Errorf("only %g more of ingredient %s is on order", line.Remaining(), received.Ingredient_ID)
+		}
+
+		if received.Cost == nil {
+			cost := line.Cost.Scale(received.Quantity / line.Quantity)
+			delivery.Lines[i].Cost = &cost
+		} else if received.Cost.Amount < 0 || !received.Cost.SameCurrency(*line.Cost) {
+			return fmt.Errorf("the cost of ingredient %s must be a positive amount in %s", received.Ingredient_ID, line.Cost.Currency)
+		}
+
+		line.Received += received.Quantity
+		if line.Remaining() == 0 {
+			line.Received = line.Quantity
+		}
+	}
+
+	p.Status = PurchaseReceived
+	for _, line := range p.Lines {
+		if line.Remaining() > 0 {
+			p.Status = PurchasePartial
+		}
+	}
+
+	p.Deliveries = append(p.Deliveries, *delivery)
+	p.Updated_At = delivery.Received_At
+	return nil
+}
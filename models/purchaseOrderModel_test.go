@@ -0,0 +1,178 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurchaseOrderReceive(t *testing.T) {
+	cost := func(minor int64, currency string) *Money { return &Money{Amount: minor, Currency: currency} }
+	line := func(ingredientId string, quantity float64) DeliveryLine {
+		return DeliveryLine{Ingredient_ID: ingredientId, Quantity: quantity}
+	}
+
+	tests := []struct {
+		name       string
+		status     string
+		deliveries [][]DeliveryLine
+		wantErr    bool
+		wantStatus string
+		// wantReceived is what has been received of flour and sugar.
+		wantReceived [2]float64
+	}{
+		{
+			name:         "everything at once",
+			deliveries:   [][]DeliveryLine{{line("flour", 0.3), line("sugar", 10)}},
+			wantStatus:   PurchaseReceived,
+			wantReceived: [2]float64{0.3, 10},
+		},
+		{
+			name:         "in parts that do not add up exactly",
+			deliveries:   [][]DeliveryLine{{line("flour", 0.1), line("sugar", 4)}, {line("flour", 0.2), line("sugar", 6)}},
+			wantStatus:   PurchaseReceived,
+			wantReceived: [2]float64{0.3, 10},
+		},
+		{
+			name:         "partly",
+			deliveries:   [][]DeliveryLine{{line("flour", 0.1)}},
+			wantStatus:   PurchasePartial,
+			wantReceived: [2]float64{0.1, 0},
+		},
+		{
+			name:         "the rest of a partial order",
+			status:       PurchasePartial,
+			deliveries:   [][]DeliveryLine{{line("flour", 0.2)}, {line("flour", 0.1), line("sugar", 10)}},
+			wantStatus:   PurchaseReceived,
+			wantReceived: [2]float64{0.3, 10},
+		},
+		{
+			name:       "more than was ordered",
+			deliveries: [][]DeliveryLine{{line("flour", 0.1)}, {line("flour", 0.3)}},
+			wantErr:    true,
+		},
+		{
+			name:       "an ingredient not on the order",
+			deliveries: [][]DeliveryLine{{line("salt", 1)}},
+			wantErr:    true,
+		},
+		{
+			name:       "an ingredient twice",
+			deliveries: [][]DeliveryLine{{line("sugar", 1), line("sugar", 1)}},
+			wantErr:    true,
+		},
+		{
+			name:       "a draft order",
+			status:     PurchaseDraft,
+			deliveries: [][]DeliveryLine{{line("sugar", 1)}},
+			wantErr:    true,
+		},
+		{
+			name:       "a cancelled order",
+			status:     PurchaseCancelled,
+			deliveries: [][]DeliveryLine{{line("sugar", 1)}},
+			wantErr:    true,
+		},
+		{
+			name:       "a cost in another currency",
+			deliveries: [][]DeliveryLine{{{Ingredient_ID: "sugar", Quantity: 1, Cost: cost(100, "EUR")}}},
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		status := tt.status
+		if status == "" {
+			status = PurchaseOrdered
+		}
+
+		order := PurchaseOrder{
+			Status: status,
+			Lines: []PurchaseOrderLine{
+				{Ingredient_ID: "flour", Quantity: 0.3, Cost: cost(900, "USD")},
+				{Ingredient_ID: "sugar", Quantity: 10, Cost: cost(2000, "USD")},
+			},
+		}
+
+		var err error
+		for _, lines := range tt.deliveries {
+			if err = order.Receive(&Delivery{Lines: lines, Received_At: time.Now()}); err != nil {
+				break
+			}
+		}
+
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: the delivery was accepted", tt.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		if order.Status != tt.wantStatus {
+			t.Errorf("%s: order is %s, want %s", tt.name, order.Status, tt.wantStatus)
+		}
+
+		for i, want := range tt.wantReceived {
+			if got := order.Lines[i].Received; got < want-quantityTolerance || got > want+quantityTolerance {
+				t.Errorf("%s: received %g of %s, want %g", tt.name, got, order.Lines[i].Ingredient_ID, want)
+			}
+		}
+
+		if len(order.Deliveries) != len(tt.deliveries) {
+			t.Errorf("%s: %d deliveries recorded, want %d", tt.name, len(order.Deliveries), len(tt.deliveries))
+		}
+	}
+}
+
+func TestPurchaseOrderReceiveInexactParts(t *testing.T) {
+	tests := []struct {
+		ordered float64
+		parts   []float64
+	}{
+		{ordered: 0.3, parts: []float64{0.1, 0.2}},
+		{ordered: 0.8, parts: []float64{0.1, 0.7}},
+		{ordered: 1, parts: []float64{0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1, 0.1}},
+	}
+
+	for _, tt := range tests {
+		order := PurchaseOrder{
+			Status: PurchaseOrdered,
+			Lines:  []PurchaseOrderLine{{Ingredient_ID: "flour", Quantity: tt.ordered, Cost: &Money{Amount: 100, Currency: "USD"}}},
+		}
+
+		for _, part := range tt.parts {
+			if err := order.Receive(&Delivery{Lines: []DeliveryLine{{Ingredient_ID: "flour", Quantity: part}}}); err != nil {
+				t.Errorf("receiving %v of %g: %v", tt.parts, tt.ordered, err)
+				break
+			}
+		}
+
+		if order.Status != PurchaseReceived || order.Outstanding("flour") != 0 {
+			t.Errorf("receiving %v of %g left the order %s with %g outstanding", tt.parts, tt.ordered, order.Status, order.Outstanding("flour"))
+		}
+	}
+}
+
+func TestPurchaseOrderReceivePricesDelivery(t *testing.T) {
+	order := PurchaseOrder{
+		Status: PurchaseOrdered,
+		Lines:  []PurchaseOrderLine{{Ingredient_ID: "sugar", Quantity: 10, Cost: &Money{Amount: 2000, Currency: "USD"}}},
+	}
+
+	delivery := Delivery{Lines: []DeliveryLine{{Ingredient_ID: "sugar", Quantity: 4}}}
+	if err := order.Receive(&delivery); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := *delivery.Lines[0].Cost, (Money{Amount: 800, Currency: "USD"}); got != want {
+		t.Errorf("4 of 10 costing 20.00 was priced at %v, want %v", got, want)
+	}
+
+	if got := order.Outstanding("sugar"); got != 6 {
+		t.Errorf("%g of sugar is outstanding, want 6", got)
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Reservation struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	Reservation_ID string             `json:"reservation_id"`
+	Guest_Name     *string            `json:"guest_name" validate:"required,min=1,max=100"`
+	Guest_Phone    *string            `json:"guest_phone" validate:"required"`
+	Guest_Email    *string            `json:"guest_email" validate:"omitempty,email"`
+	Party_Size     *int               `json:"party_size" validate:"required,min=1"`
+	Start_Time     *time.Time         `json:"start_time" validate:"required"`
+	End_Time       time.Time          `json:"end_time"`
+	Table_ID       string             `json:"table_id"`
+	Status         string             `json:"status"`
+	Notes          *string            `json:"notes"`
+	Booked_By      string             `json:"booked_by"`
+	Created_At     time.Time          `json:"created_at"`
+	Updated_At     time.Time          `json:"updated_at"`
+}
+
+const (
+	ReservationBooked    = "BOOKED"
+	ReservationSeated    = "SEATED"
+	ReservationCompleted = "COMPLETED"
+	ReservationCancelled = "CANCELLED"
+	ReservationNoShow    = "NO_SHOW"
+)
+
+var reservationTransitions = map[string][]string{
+	ReservationBooked: {ReservationSeated, ReservationCancelled, ReservationNoShow},
+	ReservationSeated: {ReservationCompleted},
+}
+
+// Holds reports whether the reservation still has a claim on its table.
+func (r Reservation) Holds() bool {
+	return r.Status == ReservationBooked || r.Status == ReservationSeated
+}
+
+// Overlaps reports whether the reservation holds its table at any point in
+// [from, to).
+func (r Reservation) Overlaps(from, to time.Time) bool {
+	return r.Holds() && r.Start_Time != nil && r.Start_Time.Before(to) && r.End_Time.After(from)
+}
+
+func (r *Reservation) MoveTo(status string, at time.Time) error {
+	for _, allowed := range reservationTransitions[r.Status] {
+		if allowed == status {
+			r.Status = status
+			r.Updated_At = at
+			return nil
+		}
+	}
+
+	return fmt.Errorf("a reservation that is %s cannot become %s", r.Status, status)
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReservationMoveTo(t *testing.T) {
+	statuses := []string{ReservationBooked, ReservationSeated, ReservationCompleted, ReservationCancelled, ReservationNoShow}
+	allowed := map[[2]string]bool{
+		{ReservationBooked, ReservationSeated}:    true,
+		{ReservationBooked, ReservationCancelled}: true,
+		{ReservationBooked, ReservationNoShow}:    true,
+		{ReservationSeated, ReservationCompleted}: true,
+	}
+	at := time.Date(2026, 10, 19, 19, 0, 0, 0, time.UTC)
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			reservation := Reservation{Status: from}
+			err := reservation.MoveTo(to, at)
+
+			if allowed[[2]string{from, to}] {
+				if err != nil {
+					t.Errorf("%s to %s: %v", from, to, err)
+				} else if reservation.Status != to || !reservation.Updated_At.Equal(at) {
+					t.Errorf("%s to %s: reservation is %s at %v", from, to, reservation.Status, reservation.Updated_At)
+				}
+				continue
+			}
+
+			if err == nil {
+				t.Errorf("%s to %s was allowed", from, to)
+			} else if reservation.Status != from {
+				t.Errorf("%s to %s was refused but left the reservation %s", from, to, reservation.Status)
+			}
+		}
+	}
+}
+
+func TestReservationOverlaps(t *testing.T) {
+	start := time.Date(2026, 10, 19, 19, 0, 0, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+
+	tests := []struct {
+		name     string
+		status   string
+		from, to time.Time
+		want     bool
+	}{
+		{name: "same time", status: ReservationBooked, from: start, to: end, want: true},
+		{name: "ends as it starts", status: ReservationBooked, from: start.Add(-time.Hour), to: start, want: false},
+		{name: "starts as it ends", status: ReservationSeated, from: end, to: end.Add(time.Hour), want: false},
+		{name: "inside", status: ReservationSeated, from: start.Add(10 * time.Minute), to: start.Add(20 * time.Minute), want: true},
+		{name: "cancelled holds nothing", status: ReservationCancelled, from: start, to: end, want: false},
+		{name: "no-show holds nothing", status: ReservationNoShow, from: start, to: end, want: false},
+	}
+
+	for _, tt := range tests {
+		reservation := Reservation{Status: tt.status, Start_Time: &start, End_Time: end}
+		if got := reservation.Overlaps(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: Overlaps = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RevokedToken struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Token_ID   string             `json:"token_id"`
+	User_ID    string             `json:"user_id"`
+	Expires_At time.Time          `json:"expires_at"`
+	Created_At time.Time          `json:"created_at"`
+}
@@ -7,5 +7,15 @@ type SignedDetails struct {
 	First_Name string `json:"first_name"`
 	Last_Name  string `json:"last_name"`
 	User_ID    string `json:"user_id"`
+	Role       string `json:"role"`
+	Token_Type string `json:"token_type"`
 	jwt.StandardClaims
 }
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+	// ApprovalToken lets a manager approve one refund, void or comp made
+	// from someone else's session.
+	ApprovalToken = "approval"
+)
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Supplier struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	Supplier_ID  string             `json:"supplier_id"`
+	Name         *string            `json:"name" validate:"required,min=2,max=100"`
+	Contact_Name *string            `json:"contact_name"`
+	Email        *string            `json:"email" validate:"omitempty,email"`
+	Phone        *string            `json:"phone"`
+	// Lead_Time_Days is how long the supplier usually takes to deliver.
+	Lead_Time_Days *int      `json:"lead_time_days" validate:"omitempty,gte=0"`
+	Created_At     time.Time `json:"created_at"`
+	Updated_At     time.Time `json:"updated_at"`
+}
+
+func (s Supplier) LeadTime() int {
+	if s.Lead_Time_Days == nil {
+		return 0
+	}
+
+	return *s.Lead_Time_Days
+}
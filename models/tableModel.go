@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,6 +12,87 @@ type Table struct {
 	Number_Of_Guests *int               `json:"number_of_guests" validate:"required"`
 	Table_Number     *int               `json:"table_number" validate:"required"`
 	Table_ID         string             `json:"table_id"`
-	Created_At       time.Time          `json:"created_at"`
-	Updated_At       time.Time          `json:"updated_at"`
+	// The floor plan. Min_Guests and Number_Of_Guests are the range of party
+	// sizes the table is meant for.
+	Section     *string     `json:"section" validate:"omitempty,max=50"`
+	Layout      *TableShape `json:"layout"`
+	Min_Guests  *int        `json:"min_guests" validate:"omitempty,min=1"`
+	Dirty_Since *time.Time  `json:"dirty_since"`
+	Created_At  time.Time   `json:"created_at"`
+	Updated_At  time.Time   `json:"updated_at"`
+}
+
+// TableShape places a table on the floor plan. Coordinates and sizes are in
+// whatever units the floor plan is drawn in; the API does not interpret them.
+type TableShape struct {
+	Shape    string  `json:"shape" validate:"required,eq=ROUND|eq=SQUARE|eq=RECTANGLE|eq=BOOTH"`
+	X        float64 `json:"x"`
+	Y        float64 `json:"y"`
+	Width    float64 `json:"width" validate:"gte=0"`
+	Height   float64 `json:"height" validate:"gte=0"`
+	Rotation int     `json:"rotation" validate:"gte=0,lt=360"`
+}
+
+// Live table statuses, derived from what is happening at the table rather
+// than stored.
+const (
+	TableFree         = "FREE"
+	TableSeated       = "SEATED"
+	TableOrdered      = "ORDERED"
+	TableAwaitingBill = "AWAITING_BILL"
+	TableDirty        = "DIRTY"
+	UnassignedSection = "Unassigned"
+)
+
+// TableActivity is what is going on at a table right now.
+type TableActivity struct {
+	Open_Orders    []string // open orders seated at the table
+	Items          int      // billable items on those orders
+	Unpaid_Bills   int      // unpaid invoices raised for those orders
+	Seated_Booking bool     // a reservation seated at the table
+}
+
+// LiveStatus works out the table's status. An unpaid bill outranks
+// everything else, since the guests are still there; a dirty table is only
+// reported once nobody is sitting at it.
+func (t Table) LiveStatus(activity TableActivity) string {
+	switch {
+	case activity.Unpaid_Bills > 0:
+		return TableAwaitingBill
+	case activity.Items > 0:
+		return TableOrdered
+	case len(activity.Open_Orders) > 0 || activity.Seated_Booking:
+		return TableSeated
+	case t.Dirty_Since != nil:
+		return TableDirty
+	}
+
+	return TableFree
+}
+
+// SectionName is the section the table is in, or UnassignedSection.
+func (t Table) SectionName() string {
+	if t.Section == nil || *t.Section == "" {
+		return UnassignedSection
+	}
+
+	return *t.Section
+}
+
+// CheckCapacity makes sure the capacity range is the right way round.
+func (t Table) CheckCapacity() error {
+	if t.Min_Guests != nil && *t.Min_Guests > t.Seats() {
+		return fmt.Errorf("min_guests (%d) is more than number_of_guests (%d)", *t.Min_Guests, t.Seats())
+	}
+
+	return nil
+}
+
+// Seats is the largest party the table takes.
+func (t Table) Seats() int {
+	if t.Number_Of_Guests == nil {
+		return 0
+	}
+
+	return *t.Number_Of_Guests
 }
@@ -16,7 +16,27 @@ type User struct {
 	Phone         *string            `json:"phone" validate:"required"`
 	Token         *string            `json:"token"`
 	Refresh_Token *string            `json:"refresh_token"`
+	Role          *string            `json:"role" validate:"omitempty,eq=ADMIN|eq=MANAGER|eq=SERVER|eq=KITCHEN|eq=CASHIER|eq=CUSTOMER"`
 	Created_At    time.Time          `json:"created_at"`
 	Updated_At    time.Time          `json:"updated_at"`
 	User_ID       string             `json:"user_id"`
 }
+
+const (
+	RoleAdmin    = "ADMIN"
+	RoleManager  = "MANAGER"
+	RoleServer   = "SERVER"
+	RoleKitchen  = "KITCHEN"
+	RoleCashier  = "CASHIER"
+	RoleCustomer = "CUSTOMER"
+)
+
+// UserRole is the user's role, falling back to RoleCustomer for accounts
+// created before roles existed.
+func (u User) UserRole() string {
+	if u.Role == nil || *u.Role == "" {
+		return RoleCustomer
+	}
+
+	return *u.Role
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WaitlistEntry is a walk-in party waiting for a table.
+type WaitlistEntry struct {
+	ID               primitive.ObjectID `bson:"_id"`
+	Entry_ID         string             `json:"entry_id"`
+	Party_Name       *string            `json:"party_name" validate:"required,min=1,max=100"`
+	Party_Size       *int               `json:"party_size" validate:"required,min=1"`
+	Phone            *string            `json:"phone" validate:"required"`
+	Notes            *string            `json:"notes"`
+	Status           string             `json:"status"`
+	Quoted_Minutes   int                `json:"quoted_minutes"`
+	Offered_Table_ID string             `json:"offered_table_id,omitempty"`
+	Notified_At      *time.Time         `json:"notified_at"`
+	Order_ID         string             `json:"order_id,omitempty"`
+	Seated_At        *time.Time         `json:"seated_at"`
+	Created_At       time.Time          `json:"created_at"`
+	Updated_At       time.Time          `json:"updated_at"`
+}
+
+const (
+	WaitlistWaiting  = "WAITING"
+	WaitlistNotified = "NOTIFIED"
+	WaitlistSeated   = "SEATED"
+	WaitlistLeft     = "LEFT"
+)
+
+// Waiting reports whether the party is still in the queue.
+func (w WaitlistEntry) Waiting() bool {
+	return w.Status == WaitlistWaiting || w.Status == WaitlistNotified
+}
+
+func (w *WaitlistEntry) Notified(tableId string, at time.Time) error {
+	if !w.Waiting() {
+		return fmt.Errorf("a party that has %s cannot be notified", w.Status)
+	}
+
+	w.Status = WaitlistNotified
+	w.Offered_Table_ID = tableId
+	w.Notified_At = &at
+	w.Updated_At = at
+	return nil
+}
+
+func (w *WaitlistEntry) Seat(orderId string, at time.Time) error {
+	if !w.Waiting() {
+		return fmt.Errorf("a party that has %s cannot be seated", w.Status)
+	}
+
+	w.Status = WaitlistSeated
+	w.Order_ID = orderId
+	w.Seated_At = &at
+	w.Updated_At = at
+	return nil
+}
+
+func (w *WaitlistEntry) Leave(at time.Time) error {
+	if !w.Waiting() {
+		return fmt.Errorf("a party that has %s cannot leave the waitlist", w.Status)
+	}
+
+	w.Status = WaitlistLeft
+	w.Updated_At = at
+	return nil
+}
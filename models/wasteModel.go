@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WasteEntry records stock thrown away: either an ingredient, in its unit,
+// or portions of a finished food, which waste what its recipe takes.
+type WasteEntry struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Waste_ID      string             `json:"waste_id"`
+	Ingredient_ID *string            `json:"ingredient_id"`
+	Food_ID       *string            `json:"food_id"`
+	Quantity      *float64           `json:"quantity" validate:"required,gt=0"`
+	Reason        *string            `json:"reason" validate:"required,eq=SPOILED|eq=EXPIRED|eq=OVERPRODUCED|eq=DROPPED|eq=RETURNED|eq=PREP_WASTE|eq=OTHER"`
+	Note          *string            `json:"note" validate:"omitempty,max=500"`
+	// Stock_Used is what the entry took out of stock and Cost what that
+	// stock cost when it was last delivered. Uncosted is set when some of
+	// it has never had a delivery to price it by.
+	Stock_Used     []RecipeLine `json:"stock_used"`
+	Cost           Money        `json:"cost"`
+	Uncosted       bool         `json:"uncosted"`
+	Logged_By      string       `json:"logged_by"`
+	Logged_By_Name string       `json:"logged_by_name"`
+	Wasted_At      time.Time    `json:"wasted_at"`
+	Created_At     time.Time    `json:"created_at"`
+}
+
+const (
+	WasteSpoiled      = "SPOILED"
+	WasteExpired      = "EXPIRED"
+	WasteOverproduced = "OVERPRODUCED"
+	WasteDropped      = "DROPPED"
+	WasteReturned     = "RETURNED"
+	WastePrep         = "PREP_WASTE"
+	WasteOther        = "OTHER"
+)
+
+// Check reports an entry that is not for exactly one ingredient or food.
+func (w WasteEntry) Check() error {
+	if (w.Ingredient_ID == nil) == (w.Food_ID == nil) {
+		return errors.New("waste is logged against either an ingredient or a food")
+	}
+
+	return nil
+}
+
+// Day is the UTC date the waste happened on, as 2006-01-02.
+func (w WasteEntry) Day() string {
+	return w.Wasted_At.UTC().Format("2006-01-02")
+}
@@ -0,0 +1,72 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+var ErrDeclined = errors.New("payments: payment declined")
+var ErrInvalidSignature = errors.New("payments: webhook signature is not valid")
+var ErrUnknownReference = errors.New("payments: unknown provider reference")
+
+// Result is what a provider reports back for a single operation.
+type Result struct {
+	Reference      string
+	Status         string
+	Amount         models.Money
+	Failure_Reason string
+}
+
+// WebhookEvent is a provider notification about a payment it holds, already
+// checked for authenticity.
+type WebhookEvent struct {
+	Event_ID  string       `json:"event_id"`
+	Type      string       `json:"type"`
+	Reference string       `json:"reference"`
+	Amount    models.Money `json:"amount"`
+	Reason    string       `json:"reason,omitempty"`
+}
+
+const (
+	EventCaptured = "payment.captured"
+	EventFailed   = "payment.failed"
+	EventVoided   = "payment.voided"
+	EventRefunded = "payment.refunded"
+)
+
+// PaymentProvider is a card processor. Authorize places a hold on the card
+// named by source; the hold is later either captured or voided. Refund gives
+// back some or all of a captured amount; asking again with the same
+// idempotency key returns the first refund rather than making another.
+type PaymentProvider interface {
+	Name() string
+	Authorize(ctx context.Context, source string, amount models.Money) (Result, error)
+	Capture(ctx context.Context, reference string, amount models.Money) (Result, error)
+	Void(ctx context.Context, reference string) (Result, error)
+	Refund(ctx context.Context, reference string, amount models.Money, idempotencyKey string) (Result, error)
+	VerifyWebhook(header http.Header, body []byte) (WebhookEvent, error)
+}
+
+// Config selects and configures the provider.
+type Config struct {
+	Provider       string
+	Webhook_Secret string
+	Webhook_URL    string
+}
+
+// New returns the provider cfg names. There is no default: the simulator
+// takes no real money, so it is only used when asked for by name.
+func New(cfg Config) (PaymentProvider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, fmt.Errorf("payments: no provider configured; set PAYMENT_PROVIDER, or %q for development", SimulatorName)
+	case SimulatorName:
+		return NewSimulator(cfg.Webhook_Secret, cfg.Webhook_URL), nil
+	}
+
+	return nil, fmt.Errorf("payments: unknown provider %q", cfg.Provider)
+}
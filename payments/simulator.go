@@ -0,0 +1,304 @@
+package payments
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const SimulatorName = "simulator"
+
+// SignatureHeader carries the simulator's webhook signature, in the form
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+const SignatureHeader = "X-Simulator-Signature"
+
+// signatureTolerance is how old a signed webhook may be before it is
+// treated as a replay.
+const signatureTolerance = 5 * time.Minute
+
+// Test card sources understood by the simulator. Any other source is
+// declined as an invalid card.
+const (
+	CardVisa              = "tok_visa"
+	CardMastercard        = "tok_mastercard"
+	CardDeclined          = "tok_declined"
+	CardInsufficientFunds = "tok_insufficient_funds"
+	// CardCaptureFails is accepted up front but the capture is reported
+	// as failed afterwards by webhook, as happens with real processors.
+	CardCaptureFails = "tok_capture_fails"
+)
+
+type simulatedCharge struct {
+	source     string
+	status     string
+	authorized models.Money
+	captured   models.Money
+	refunded   models.Money
+	// refunds remembers each refund by its idempotency key.
+	refunds map[string]Result
+}
+
+// Simulator is an in-process PaymentProvider for development and tests. It
+// keeps its charges in memory and, when given a webhook URL, delivers signed
+// webhooks for them the way a real processor would.
+type Simulator struct {
+	mu         sync.Mutex
+	charges    map[string]*simulatedCharge
+	secret     []byte
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSimulator returns a simulator signing webhooks with secret. Without a
+// secret a random one is used, which is enough for deliveries made by the
+// simulator itself.
+func NewSimulator(secret, webhookURL string) *Simulator {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		rand.Read(key)
+	}
+
+	return &Simulator{
+		charges:    map[string]*simulatedCharge{},
+		secret:     key,
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *Simulator) Name() string {
+	return SimulatorName
+}
+
+func (s *Simulator) Authorize(ctx context.Context, source string, amount models.Money) (Result, error) {
+	reference := "sim_" + primitive.NewObjectID().Hex()
+	result := Result{Reference: reference, Amount: amount}
+
+	switch source {
+	case CardVisa, CardMastercard, CardCaptureFails:
+	case CardDeclined:
+		result.Status, result.Failure_Reason = models.TransactionDeclined, "card_declined"
+		return result, ErrDeclined
+	case CardInsufficientFunds:
+		result.Status, result.Failure_Reason = models.TransactionDeclined, "insufficient_funds"
+		return result, ErrDeclined
+	default:
+		result.Status, result.Failure_Reason = models.TransactionDeclined, "invalid_card"
+		return result, ErrDeclined
+	}
+
+	if amount.Amount <= 0 {
+		return result, fmt.Errorf("payments: cannot authorize %s", amount.Decimal())
+	}
+
+	s.mu.Lock()
+	s.charges[reference] = &simulatedCharge{source: source, status: models.TransactionAuthorized, authorized: amount}
+	s.mu.Unlock()
+
+	result.Status = models.TransactionAuthorized
+	return result, nil
+}
+
+// Capture takes up to the authorized amount; anything left on the hold is
+// released.
+func (s *Simulator) Capture(ctx context.Context, reference string, amount models.Money) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[reference]
+	if !ok {
+		return Result{Reference: reference}, ErrUnknownReference
+	}
+
+	if charge.status != models.TransactionAuthorized {
+		return s.result(reference, charge), fmt.Errorf("payments: cannot capture a %s payment", strings.ToLower(charge.status))
+	}
+
+	if !amount.SameCurrency(charge.authorized) || amount.Amount <= 0 || amount.Amount > charge.authorized.Amount {
+		return s.result(reference, charge), fmt.Errorf("payments: cannot capture %s of %s authorized", amount.Decimal(), charge.authorized.Decimal())
+	}
+
+	charge.status = models.TransactionCaptured
+	charge.captured = amount
+
+	if charge.source == CardCaptureFails {
+		s.deliver(WebhookEvent{Type: EventFailed, Reference: reference, Amount: amount, Reason: "processor_error"})
+	} else {
+		s.deliver(WebhookEvent{Type: EventCaptured, Reference: reference, Amount: amount})
+	}
+
+	return s.result(reference, charge), nil
+}
+
+func (s *Simulator) Void(ctx context.Context, reference string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[reference]
+	if !ok {
+		return Result{Reference: reference}, ErrUnknownReference
+	}
+
+	if charge.status != models.TransactionAuthorized {
+		return s.result(reference, charge), fmt.Errorf("payments: cannot void a %s payment", strings.ToLower(charge.status))
+	}
+
+	charge.status = models.TransactionVoided
+	s.deliver(WebhookEvent{Type: EventVoided, Reference: reference, Amount: charge.authorized})
+
+	return s.result(reference, charge), nil
+}
+
+// Refund gives back part or all of what was captured. The charge only
+// counts as refunded once nothing captured is left.
+func (s *Simulator) Refund(ctx context.Context, reference string, amount models.Money, idempotencyKey string) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	charge, ok := s.charges[reference]
+	if !ok {
+		return Result{Reference: reference}, ErrUnknownReference
+	}
+
+	if previous, ok := charge.refunds[idempotencyKey]; ok && idempotencyKey != "" {
+		return previous, nil
+	}
+
+	if charge.status != models.TransactionCaptured {
+		return s.result(reference, charge), fmt.Errorf("payments: cannot refund a %s payment", strings.ToLower(charge.status))
+	}
+
+	remaining := charge.captured.Sub(charge.refunded)
+	if !amount.SameCurrency(remaining) || amount.Amount <= 0 || amount.Amount > remaining.Amount {
+		return s.result(reference, charge), fmt.Errorf("payments: cannot refund %s of %s remaining", amount.Decimal(), remaining.Decimal())
+	}
+
+	charge.refunded = charge.refunded.Add(amount)
+	if charge.refunded.Amount == charge.captured.Amount {
+		charge.status = models.TransactionRefunded
+	}
+
+	s.deliver(WebhookEvent{Type: EventRefunded, Reference: reference, Amount: amount})
+
+	result := s.result(reference, charge)
+	result.Amount = amount
+	if idempotencyKey != "" {
+		if charge.refunds == nil {
+			charge.refunds = map[string]Result{}
+		}
+		charge.refunds[idempotencyKey] = result
+	}
+
+	return result, nil
+}
+
+func (s *Simulator) VerifyWebhook(header http.Header, body []byte) (WebhookEvent, error) {
+	var event WebhookEvent
+
+	var timestamp, signature string
+	for _, part := range strings.Split(header.Get(SignatureHeader), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return event, ErrInvalidSignature
+	}
+
+	if age := time.Since(time.Unix(seconds, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return event, ErrInvalidSignature
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(expected, s.sign(timestamp, body)) {
+		return event, ErrInvalidSignature
+	}
+
+	if err := json.Unmarshal(body, &event); err != nil {
+		return event, fmt.Errorf("payments: parsing webhook: %w", err)
+	}
+
+	return event, nil
+}
+
+// Signature returns the header value that signs body at time at, for
+// tools that need to send the simulator's webhooks by hand.
+func (s *Simulator) Signature(body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(s.sign(timestamp, body))
+}
+
+func (s *Simulator) sign(timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+func (s *Simulator) result(reference string, charge *simulatedCharge) Result {
+	amount := charge.authorized
+	if charge.status == models.TransactionCaptured || charge.status == models.TransactionRefunded {
+		amount = charge.captured
+	}
+
+	return Result{Reference: reference, Status: charge.status, Amount: amount}
+}
+
+// deliver posts event to the webhook URL in the background, a moment after
+// the call that caused it has returned, as a real processor would.
+func (s *Simulator) deliver(event WebhookEvent) {
+	if s.webhookURL == "" {
+		return
+	}
+
+	event.Event_ID = "evt_" + primitive.NewObjectID().Hex()
+	body, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("payments: encoding webhook %s: %v", event.Event_ID, err)
+		return
+	}
+
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+
+		req, err := http.NewRequest(http.MethodPost, s.webhookURL, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("payments: delivering webhook %s: %v", event.Event_ID, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(SignatureHeader, s.Signature(body, time.Now()))
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			log.Printf("payments: delivering webhook %s: %v", event.Event_ID, err)
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			log.Printf("payments: webhook %s was answered with %s", event.Event_ID, resp.Status)
+		}
+	}()
+}
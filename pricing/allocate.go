@@ -0,0 +1,52 @@
+package pricing
+
+import (
+	"sort"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+// Allocate divides total between weights in proportion, using the largest
+// remainder method so the shares always add back up to total exactly. With
+// all weights zero the total is shared evenly.
+func Allocate(total models.Money, weights []int64) []models.Money {
+	shares := make([]models.Money, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	sum := int64(0)
+	for _, weight := range weights {
+		sum += weight
+	}
+
+	if sum == 0 {
+		even := make([]int64, len(weights))
+		for i := range even {
+			even[i] = 1
+		}
+		return Allocate(total, even)
+	}
+
+	type remainder struct {
+		index int
+		value int64
+	}
+
+	allocated := int64(0)
+	remainders := make([]remainder, len(weights))
+	for i, weight := range weights {
+		amount := total.Amount * weight / sum
+		shares[i] = models.NewMoney(amount, total.Currency)
+		remainders[i] = remainder{index: i, value: total.Amount * weight % sum}
+		allocated += amount
+	}
+
+	sort.SliceStable(remainders, func(a, b int) bool { return remainders[a].value > remainders[b].value })
+	for i := 0; allocated < total.Amount; i++ {
+		shares[remainders[i%len(remainders)].index].Amount++
+		allocated++
+	}
+
+	return shares
+}
@@ -0,0 +1,49 @@
+package pricing
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		total   int64
+		weights []int64
+		want    []int64
+	}{
+		{name: "even split", total: 900, weights: []int64{1, 1, 1}, want: []int64{300, 300, 300}},
+		{name: "a cent over", total: 100, weights: []int64{1, 1, 1}, want: []int64{34, 33, 33}},
+		{name: "two cents over", total: 200, weights: []int64{1, 1, 1}, want: []int64{67, 67, 66}},
+		{name: "in proportion", total: 1000, weights: []int64{1, 2, 1}, want: []int64{250, 500, 250}},
+		{name: "largest remainder first", total: 10, weights: []int64{1, 2, 4}, want: []int64{1, 3, 6}},
+		{name: "zero weights share evenly", total: 10, weights: []int64{0, 0, 0}, want: []int64{4, 3, 3}},
+		{name: "a zero weight gets nothing", total: 500, weights: []int64{0, 3, 2}, want: []int64{0, 300, 200}},
+		{name: "nothing to share", total: 0, weights: []int64{1, 2}, want: []int64{0, 0}},
+		{name: "no weights", total: 500, weights: []int64{}, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		shares := Allocate(models.NewMoney(tt.total, "EUR"), tt.weights)
+
+		got := make([]int64, len(shares))
+		sum := int64(0)
+		for i, share := range shares {
+			if share.Currency != "EUR" {
+				t.Errorf("%s: share %d is in %s", tt.name, i, share.Currency)
+			}
+			got[i] = share.Amount
+			sum += share.Amount
+		}
+
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Allocate(%d, %v) = %v, want %v", tt.name, tt.total, tt.weights, got, tt.want)
+		}
+
+		if len(tt.weights) > 0 && sum != tt.total {
+			t.Errorf("%s: shares add up to %d, not %d", tt.name, sum, tt.total)
+		}
+	}
+}
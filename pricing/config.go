@@ -0,0 +1,91 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// TaxRate applies to foods whose menu has Category. The rate with an empty
+// category is the default for everything else. Rates are percentages.
+type TaxRate struct {
+	Name      string  `json:"name"`
+	Category  string  `json:"category"`
+	Rate      float64 `json:"rate"`
+	Inclusive bool    `json:"inclusive"`
+}
+
+// ServiceCharge applies to parties of at least Min_Guests. When several
+// match, the one with the largest Min_Guests wins.
+type ServiceCharge struct {
+	Name       string  `json:"name"`
+	Min_Guests int     `json:"min_guests"`
+	Rate       float64 `json:"rate"`
+}
+
+type Config struct {
+	Tax_Rates       []TaxRate       `json:"tax_rates"`
+	Service_Charges []ServiceCharge `json:"service_charges"`
+	Rounding        string          `json:"rounding"`
+}
+
+func DefaultConfig() Config {
+	return Config{Rounding: HalfUp}
+}
+
+// LoadConfig reads a JSON config from path. An empty path gives the default
+// config: no tax, no service charge and half-up rounding.
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("pricing: reading %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("pricing: parsing %s: %w", path, err)
+	}
+
+	if cfg.Rounding == "" {
+		cfg.Rounding = HalfUp
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (cfg Config) Validate() error {
+	if err := validRounding(cfg.Rounding); err != nil {
+		return err
+	}
+
+	categories := map[string]bool{}
+	for _, rate := range cfg.Tax_Rates {
+		if rate.Rate < 0 {
+			return fmt.Errorf("pricing: tax rate %q is negative", rate.Name)
+		}
+
+		if categories[rate.Category] {
+			return fmt.Errorf("pricing: more than one tax rate for category %q", rate.Category)
+		}
+		categories[rate.Category] = true
+	}
+
+	for _, charge := range cfg.Service_Charges {
+		if charge.Rate < 0 {
+			return fmt.Errorf("pricing: service charge %q is negative", charge.Name)
+		}
+	}
+
+	return nil
+}
+
+// basisPoints turns a percentage into hundredths of a percent so all the
+// arithmetic can stay in integers.
+func basisPoints(percent float64) int64 {
+	return int64(math.Round(percent * 100))
+}
@@ -0,0 +1,217 @@
+package pricing
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+// Line is one priced item going into the engine.
+type Line struct {
+	Reference  string
+	Food_ID    string
+	Name       string
+	Category   string
+	Unit_Price models.Money
+	Quantity   int
+}
+
+type LineBreakdown struct {
+	Reference     string       `json:"reference"`
+	Name          string       `json:"name"`
+	Category      string       `json:"category"`
+	Quantity      int          `json:"quantity"`
+	Unit_Price    models.Money `json:"unit_price"`
+	Gross         models.Money `json:"gross"`
+	Discount      models.Money `json:"discount"`
+	Tax_Name      string       `json:"tax_name,omitempty"`
+	Tax_Inclusive bool         `json:"tax_inclusive"`
+}
+
+// Charged is what the line comes to after discounts.
+func (l LineBreakdown) Charged() models.Money {
+	return l.Gross.Sub(l.Discount)
+}
+
+type TaxBreakdown struct {
+	Name      string       `json:"name"`
+	Rate      float64      `json:"rate"`
+	Inclusive bool         `json:"inclusive"`
+	Taxable   models.Money `json:"taxable"`
+	Amount    models.Money `json:"amount"`
+}
+
+type Breakdown struct {
+	Lines               []LineBreakdown     `json:"lines"`
+	Discounts           []DiscountBreakdown `json:"discounts"`
+	Discount            models.Money        `json:"discount"`
+	Subtotal            models.Money        `json:"subtotal"`
+	Taxes               []TaxBreakdown      `json:"taxes"`
+	Tax                 models.Money        `json:"tax"`
+	Service_Charge_Name string              `json:"service_charge_name,omitempty"`
+	Service_Charge_Rate float64             `json:"service_charge_rate"`
+	Service_Charge      models.Money        `json:"service_charge"`
+	Total               models.Money        `json:"total"`
+	Rounding            string              `json:"rounding"`
+}
+
+type Engine struct {
+	cfg Config
+}
+
+func NewEngine(cfg Config) (*Engine, error) {
+	if cfg.Rounding == "" {
+		cfg.Rounding = HalfUp
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Engine{cfg: cfg}, nil
+}
+
+// Price works out the bill for lines served to a party of guests. Amounts
+// are handled in minor units throughout and each tax is rounded once over
+// everything it applies to, rather than line by line. All lines must be in
+// the same currency.
+//
+// Promotions are taken off the lines first, in the order given, each on
+// what is left after the ones before it. Subtotal is the amount after
+// discounts and before tax. For tax-inclusive rates the tax is carved out of
+// the line price, so the guest pays the menu price; exclusive taxes are
+// added on top. The service charge is a percentage of the subtotal.
+func (e *Engine) Price(lines []Line, guests int, promotions ...models.Promotion) (Breakdown, error) {
+	breakdown := Breakdown{Lines: []LineBreakdown{}, Discounts: []DiscountBreakdown{}, Taxes: []TaxBreakdown{}, Rounding: e.cfg.Rounding}
+
+	currency := models.DefaultCurrency
+	if len(lines) > 0 && lines[0].Unit_Price.Currency != "" {
+		currency = lines[0].Unit_Price.Currency
+	}
+	money := func(minor int64) models.Money { return models.NewMoney(minor, currency) }
+
+	charged := make([]int64, len(lines))
+	spend := int64(0)
+	for i, line := range lines {
+		quantity := line.Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+
+		if line.Unit_Price.Currency != "" && line.Unit_Price.Currency != currency {
+			return breakdown, fmt.Errorf("pricing: cannot mix %s and %s on one bill", currency, line.Unit_Price.Currency)
+		}
+
+		gross := line.Unit_Price.Amount * int64(quantity)
+		lineBreakdown := LineBreakdown{
+			Reference:  line.Reference,
+			Name:       line.Name,
+			Category:   line.Category,
+			Quantity:   quantity,
+			Unit_Price: line.Unit_Price,
+			Gross:      money(gross),
+			Discount:   money(0),
+		}
+
+		charged[i] = gross
+		spend += gross
+		breakdown.Lines = append(breakdown.Lines, lineBreakdown)
+	}
+
+	discount := int64(0)
+	for _, promotion := range promotions {
+		discountBreakdown, shares := e.discount(promotion, lines, charged, spend, currency)
+		for i, share := range shares {
+			charged[i] -= share
+			breakdown.Lines[i].Discount.Amount += share
+		}
+
+		discount += discountBreakdown.Amount.Amount
+		breakdown.Discounts = append(breakdown.Discounts, discountBreakdown)
+	}
+
+	grossByTax := map[int]int64{}
+	untaxed := int64(0)
+	for i, line := range lines {
+		if t, ok := e.taxFor(line.Category); ok {
+			grossByTax[t] += charged[i]
+			breakdown.Lines[i].Tax_Name = e.cfg.Tax_Rates[t].Name
+			breakdown.Lines[i].Tax_Inclusive = e.cfg.Tax_Rates[t].Inclusive
+		} else {
+			untaxed += charged[i]
+		}
+	}
+
+	taxIndexes := make([]int, 0, len(grossByTax))
+	for i := range grossByTax {
+		taxIndexes = append(taxIndexes, i)
+	}
+	sort.Ints(taxIndexes)
+
+	subtotal, tax := untaxed, int64(0)
+	for _, i := range taxIndexes {
+		rate, gross := e.cfg.Tax_Rates[i], grossByTax[i]
+		bps := basisPoints(rate.Rate)
+
+		var net, amount int64
+		if rate.Inclusive {
+			net = divRound(gross*10000, 10000+bps, e.cfg.Rounding)
+			amount = gross - net
+		} else {
+			net = gross
+			amount = divRound(gross*bps, 10000, e.cfg.Rounding)
+		}
+
+		subtotal += net
+		tax += amount
+		breakdown.Taxes = append(breakdown.Taxes, TaxBreakdown{
+			Name:      rate.Name,
+			Rate:      rate.Rate,
+			Inclusive: rate.Inclusive,
+			Taxable:   money(net),
+			Amount:    money(amount),
+		})
+	}
+
+	service := int64(0)
+	if charge, ok := e.serviceChargeFor(guests); ok {
+		service = divRound(subtotal*basisPoints(charge.Rate), 10000, e.cfg.Rounding)
+		breakdown.Service_Charge_Name = charge.Name
+		breakdown.Service_Charge_Rate = charge.Rate
+	}
+
+	breakdown.Discount = money(discount)
+	breakdown.Subtotal = money(subtotal)
+	breakdown.Tax = money(tax)
+	breakdown.Service_Charge = money(service)
+	breakdown.Total = money(subtotal + tax + service)
+	return breakdown, nil
+}
+
+func (e *Engine) taxFor(category string) (int, bool) {
+	fallback := -1
+	for i, rate := range e.cfg.Tax_Rates {
+		if rate.Category == category && category != "" {
+			return i, true
+		}
+
+		if rate.Category == "" {
+			fallback = i
+		}
+	}
+
+	return fallback, fallback >= 0
+}
+
+func (e *Engine) serviceChargeFor(guests int) (ServiceCharge, bool) {
+	var best ServiceCharge
+	found := false
+	for _, charge := range e.cfg.Service_Charges {
+		if guests >= charge.Min_Guests && (!found || charge.Min_Guests > best.Min_Guests) {
+			best, found = charge, true
+		}
+	}
+
+	return best, found
+}
@@ -0,0 +1,242 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+func usd(minor int64) models.Money {
+	return models.NewMoney(minor, "USD")
+}
+
+func TestEnginePrice(t *testing.T) {
+	text := func(s string) *string { return &s }
+	number := func(n int) *int { return &n }
+	percent := func(p float64) *float64 { return &p }
+	amount := func(minor int64) *models.Money { m := usd(minor); return &m }
+
+	burgers := Line{Reference: "1", Food_ID: "burger", Category: "FOOD", Unit_Price: usd(1000), Quantity: 2}
+	salad := Line{Reference: "2", Food_ID: "salad", Category: "FOOD", Unit_Price: usd(750), Quantity: 1}
+	beer := Line{Reference: "3", Food_ID: "beer", Category: "DRINKS", Unit_Price: usd(500), Quantity: 1}
+
+	type want struct {
+		discount, subtotal, tax, service, total int64
+	}
+
+	tests := []struct {
+		name       string
+		cfg        Config
+		lines      []Line
+		guests     int
+		promotions []models.Promotion
+		want       want
+	}{
+		{
+			name:  "no tax",
+			lines: []Line{burgers, salad},
+			want:  want{subtotal: 2750, total: 2750},
+		},
+		{
+			name:  "nothing ordered",
+			cfg:   Config{Tax_Rates: []TaxRate{{Name: "VAT", Rate: 10}}},
+			lines: []Line{},
+			want:  want{},
+		},
+		{
+			name:  "quantity below one counts as one",
+			lines: []Line{{Unit_Price: usd(1000), Quantity: 0}},
+			want:  want{subtotal: 1000, total: 1000},
+		},
+		{
+			name:  "exclusive tax",
+			cfg:   Config{Tax_Rates: []TaxRate{{Name: "Sales tax", Rate: 10}}},
+			lines: []Line{burgers, salad},
+			want:  want{subtotal: 2750, tax: 275, total: 3025},
+		},
+		{
+			name:  "exclusive tax rounded once over the bill",
+			cfg:   Config{Tax_Rates: []TaxRate{{Name: "Sales tax", Rate: 8.25}}},
+			lines: []Line{{Unit_Price: usd(199), Quantity: 3}},
+			// 5.97 at 8.25% is 0.4925.
+			want: want{subtotal: 597, tax: 49, total: 646},
+		},
+		{
+			name:  "half even rounding",
+			cfg:   Config{Tax_Rates: []TaxRate{{Name: "Sales tax", Rate: 5}}, Rounding: HalfEven},
+			lines: []Line{{Unit_Price: usd(10), Quantity: 1}, {Unit_Price: usd(80), Quantity: 1}},
+			// 0.90 at 5% is 0.045.
+			want: want{subtotal: 90, tax: 4, total: 94},
+		},
+		{
+			name:  "half up rounding",
+			cfg:   Config{Tax_Rates: []TaxRate{{Name: "Sales tax", Rate: 5}}},
+			lines: []Line{{Unit_Price: usd(10), Quantity: 1}, {Unit_Price: usd(80), Quantity: 1}},
+			want:  want{subtotal: 90, tax: 5, total: 95},
+		},
+		{
+			name:  "inclusive tax is carved out of the price",
+			cfg:   Config{Tax_Rates: []TaxRate{{Name: "VAT", Rate: 20, Inclusive: true}}},
+			lines: []Line{{Unit_Price: usd(1000), Quantity: 1}},
+			want:  want{subtotal: 833, tax: 167, total: 1000},
+		},
+		{
+			name: "tax by category with a default",
+			cfg: Config{Tax_Rates: []TaxRate{
+				{Name: "Food", Rate: 5},
+				{Name: "Alcohol", Category: "DRINKS", Rate: 20},
+			}},
+			lines: []Line{burgers, beer},
+			want:  want{subtotal: 2500, tax: 200, total: 2700},
+		},
+		{
+			name:  "no default rate leaves other categories untaxed",
+			cfg:   Config{Tax_Rates: []TaxRate{{Name: "Alcohol", Category: "DRINKS", Rate: 20}}},
+			lines: []Line{burgers, beer},
+			want:  want{subtotal: 2500, tax: 100, total: 2600},
+		},
+		{
+			name:   "service charge below its party size",
+			cfg:    Config{Service_Charges: []ServiceCharge{{Name: "Large party", Min_Guests: 6, Rate: 10}}},
+			lines:  []Line{burgers, salad},
+			guests: 5,
+			want:   want{subtotal: 2750, total: 2750},
+		},
+		{
+			name:   "service charge on the subtotal",
+			cfg:    Config{Tax_Rates: []TaxRate{{Name: "Sales tax", Rate: 10}}, Service_Charges: []ServiceCharge{{Name: "Large party", Min_Guests: 6, Rate: 10}}},
+			lines:  []Line{burgers, salad},
+			guests: 6,
+			want:   want{subtotal: 2750, tax: 275, service: 275, total: 3300},
+		},
+		{
+			name: "largest matching service charge",
+			cfg: Config{Service_Charges: []ServiceCharge{
+				{Name: "Large party", Min_Guests: 6, Rate: 10},
+				{Name: "Very large party", Min_Guests: 8, Rate: 12.5},
+			}},
+			lines:  []Line{burgers, salad},
+			guests: 9,
+			want:   want{subtotal: 2750, service: 344, total: 3094},
+		},
+		{
+			name:       "percent promotion before tax",
+			cfg:        Config{Tax_Rates: []TaxRate{{Name: "Sales tax", Rate: 10}}},
+			lines:      []Line{burgers, salad},
+			promotions: []models.Promotion{{Promotion_ID: "p", Type: text(models.PromotionPercent), Percent: percent(10)}},
+			want:       want{discount: 275, subtotal: 2475, tax: 248, total: 2723},
+		},
+		{
+			name:       "amount promotion capped at what it covers",
+			lines:      []Line{burgers, beer},
+			promotions: []models.Promotion{{Promotion_ID: "p", Type: text(models.PromotionAmount), Amount: amount(800), Categories: []string{"DRINKS"}}},
+			want:       want{discount: 500, subtotal: 2000, total: 2000},
+		},
+		{
+			name:       "minimum spend not reached",
+			lines:      []Line{burgers},
+			promotions: []models.Promotion{{Promotion_ID: "p", Type: text(models.PromotionAmount), Amount: amount(500), Min_Spend: amount(3000)}},
+			want:       want{subtotal: 2000, total: 2000},
+		},
+		{
+			name:  "buy two get one free",
+			lines: []Line{{Food_ID: "burger", Unit_Price: usd(1000), Quantity: 3}, {Food_ID: "slider", Unit_Price: usd(400), Quantity: 1}},
+			promotions: []models.Promotion{{
+				Promotion_ID: "p", Type: text(models.PromotionBuyXGetY), Buy_Quantity: number(2), Get_Quantity: number(1),
+			}},
+			// The slider is the fourth item, so only the third burger is free.
+			want: want{discount: 1000, subtotal: 2400, total: 2400},
+		},
+		{
+			name:  "promotions in turn",
+			lines: []Line{burgers},
+			promotions: []models.Promotion{
+				{Promotion_ID: "a", Type: text(models.PromotionAmount), Amount: amount(500)},
+				{Promotion_ID: "b", Type: text(models.PromotionPercent), Percent: percent(10)},
+			},
+			want: want{discount: 650, subtotal: 1350, total: 1350},
+		},
+	}
+
+	for _, tt := range tests {
+		engine, err := NewEngine(tt.cfg)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		breakdown, err := engine.Price(tt.lines, tt.guests, tt.promotions...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+
+		got := want{
+			discount: breakdown.Discount.Amount,
+			subtotal: breakdown.Subtotal.Amount,
+			tax:      breakdown.Tax.Amount,
+			service:  breakdown.Service_Charge.Amount,
+			total:    breakdown.Total.Amount,
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+
+		discounted := int64(0)
+		for _, line := range breakdown.Lines {
+			discounted += line.Discount.Amount
+		}
+		if discounted != breakdown.Discount.Amount {
+			t.Errorf("%s: line discounts add up to %d, not %d", tt.name, discounted, breakdown.Discount.Amount)
+		}
+
+		if len(breakdown.Discounts) != len(tt.promotions) {
+			t.Errorf("%s: %d discounts listed for %d promotions", tt.name, len(breakdown.Discounts), len(tt.promotions))
+		}
+	}
+}
+
+func TestEnginePriceMixedCurrencies(t *testing.T) {
+	engine, err := NewEngine(DefaultConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := []Line{{Unit_Price: usd(1000), Quantity: 1}, {Unit_Price: models.NewMoney(1000, "EUR"), Quantity: 1}}
+	if _, err := engine.Price(lines, 2); err == nil {
+		t.Error("a bill mixing USD and EUR was priced")
+	}
+}
+
+func TestEnginePriceCurrency(t *testing.T) {
+	engine, err := NewEngine(Config{Tax_Rates: []TaxRate{{Name: "Consumption tax", Rate: 10}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	breakdown, err := engine.Price([]Line{{Unit_Price: models.NewMoney(1200, "JPY"), Quantity: 1}}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := models.NewMoney(1320, "JPY"); breakdown.Total != want {
+		t.Errorf("total is %v, want %v", breakdown.Total, want)
+	}
+}
+
+func TestNewEngineRejectsBadConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{name: "unknown rounding", cfg: Config{Rounding: "DOWN"}},
+		{name: "negative tax", cfg: Config{Tax_Rates: []TaxRate{{Name: "VAT", Rate: -1}}}},
+		{name: "two default rates", cfg: Config{Tax_Rates: []TaxRate{{Name: "A", Rate: 1}, {Name: "B", Rate: 2}}}},
+		{name: "negative service charge", cfg: Config{Service_Charges: []ServiceCharge{{Name: "Service", Rate: -5}}}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewEngine(tt.cfg); err == nil {
+			t.Errorf("%s: the config was accepted", tt.name)
+		}
+	}
+}
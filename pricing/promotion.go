@@ -0,0 +1,143 @@
+package pricing
+
+import (
+	"sort"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+// DiscountBreakdown is one promotion as it came off the bill. A promotion
+// that did not take effect is listed with a zero amount and a note saying
+// why.
+type DiscountBreakdown struct {
+	Promotion_ID string       `json:"promotion_id"`
+	Name         string       `json:"name"`
+	Code         string       `json:"code,omitempty"`
+	Type         string       `json:"type"`
+	Amount       models.Money `json:"amount"`
+	Note         string       `json:"note,omitempty"`
+}
+
+// discount works out what promotion takes off the bill and how much of it
+// falls on each line, given what is still charged on each line after earlier
+// promotions. spend is the bill before any discount.
+func (e *Engine) discount(promotion models.Promotion, lines []Line, charged []int64, spend int64, currency string) (DiscountBreakdown, []int64) {
+	shares := make([]int64, len(lines))
+	breakdown := DiscountBreakdown{
+		Promotion_ID: promotion.Promotion_ID,
+		Type:         *promotion.Type,
+		Amount:       models.NewMoney(0, currency),
+	}
+	if promotion.Name != nil {
+		breakdown.Name = *promotion.Name
+	}
+	if promotion.Code != nil {
+		breakdown.Code = *promotion.Code
+	}
+
+	for _, amount := range []*models.Money{promotion.Amount, promotion.Min_Spend} {
+		if amount != nil && amount.Currency != "" && amount.Currency != currency {
+			breakdown.Note = "the promotion is in " + amount.Currency + " but the bill is in " + currency
+			return breakdown, shares
+		}
+	}
+
+	if promotion.Min_Spend != nil && spend < promotion.Min_Spend.Amount {
+		breakdown.Note = "the bill has not reached the minimum spend of " + promotion.Min_Spend.Decimal()
+		return breakdown, shares
+	}
+
+	covered := []int{}
+	base := int64(0)
+	for i, line := range lines {
+		if promotion.Covers(line.Food_ID, line.Category) {
+			covered = append(covered, i)
+			base += charged[i]
+		}
+	}
+
+	if base <= 0 {
+		breakdown.Note = "nothing on the bill qualifies"
+		return breakdown, shares
+	}
+
+	switch *promotion.Type {
+	case models.PromotionPercent:
+		e.share(shares, divRound(base*basisPoints(*promotion.Percent), 10000, e.cfg.Rounding), covered, charged)
+	case models.PromotionAmount:
+		amount := promotion.Amount.Amount
+		if amount > base {
+			amount = base
+		}
+		e.share(shares, amount, covered, charged)
+	case models.PromotionBuyXGetY:
+		e.freeItems(shares, promotion, lines, covered, charged)
+	}
+
+	total := int64(0)
+	for _, share := range shares {
+		total += share
+	}
+
+	breakdown.Amount = models.NewMoney(total, currency)
+	if total == 0 && *promotion.Type == models.PromotionBuyXGetY {
+		breakdown.Note = "not enough qualifying items on the bill"
+	}
+
+	return breakdown, shares
+}
+
+// share spreads amount over the covered lines in proportion to what is
+// charged on each. amount must not be more than their total.
+func (e *Engine) share(shares []int64, amount int64, covered []int, charged []int64) {
+	weights := make([]int64, len(covered))
+	for j, i := range covered {
+		weights[j] = charged[i]
+	}
+
+	for j, portion := range Allocate(models.NewMoney(amount, ""), weights) {
+		shares[covered[j]] = portion.Amount
+	}
+}
+
+// freeItems discounts the cheapest Get_Quantity units of every
+// Buy_Quantity + Get_Quantity covered units, most expensive first, so the
+// guest pays for the dearer items in each group.
+func (e *Engine) freeItems(shares []int64, promotion models.Promotion, lines []Line, covered []int, charged []int64) {
+	type unit struct {
+		line  int
+		price int64
+	}
+
+	units := []unit{}
+	for _, i := range covered {
+		quantity := lines[i].Quantity
+		if quantity < 1 {
+			quantity = 1
+		}
+
+		for q := 0; q < quantity; q++ {
+			units = append(units, unit{line: i, price: lines[i].Unit_Price.Amount})
+		}
+	}
+	sort.SliceStable(units, func(a, b int) bool { return units[a].price > units[b].price })
+
+	percent := 100.0
+	if promotion.Get_Percent != nil {
+		percent = *promotion.Get_Percent
+	}
+
+	buy, get := *promotion.Buy_Quantity, *promotion.Get_Quantity
+	group := buy + get
+	for start := 0; start+group <= len(units); start += group {
+		for _, free := range units[start+buy : start+group] {
+			shares[free.line] += divRound(free.price*basisPoints(percent), 10000, e.cfg.Rounding)
+		}
+	}
+
+	for _, i := range covered {
+		if shares[i] > charged[i] {
+			shares[i] = charged[i]
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package pricing
+
+import "fmt"
+
+const (
+	HalfUp   = "HALF_UP"
+	HalfEven = "HALF_EVEN"
+)
+
+// divRound divides num by den, rounding the quotient to the nearest integer
+// with ties resolved by mode. den must be positive.
+func divRound(num, den int64, mode string) int64 {
+	quotient, remainder := num/den, num%den
+	if remainder == 0 {
+		return quotient
+	}
+
+	sign := int64(1)
+	if num < 0 {
+		sign, remainder = -1, -remainder
+	}
+
+	switch twice := remainder * 2; {
+	case twice > den:
+		return quotient + sign
+	case twice < den:
+		return quotient
+	}
+
+	if mode == HalfEven && quotient%2 == 0 {
+		return quotient
+	}
+
+	return quotient + sign
+}
+
+func validRounding(mode string) error {
+	switch mode {
+	case HalfUp, HalfEven:
+		return nil
+	}
+
+	return fmt.Errorf("pricing: unknown rounding mode %q", mode)
+}
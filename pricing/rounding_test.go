@@ -0,0 +1,35 @@
+package pricing
+
+import "testing"
+
+func TestDivRound(t *testing.T) {
+	tests := []struct {
+		num, den int64
+		mode     string
+		want     int64
+	}{
+		{num: 6, den: 3, mode: HalfUp, want: 2},
+		{num: 10, den: 3, mode: HalfUp, want: 3},
+		{num: 11, den: 3, mode: HalfUp, want: 4},
+		{num: 1, den: 3, mode: HalfUp, want: 0},
+		{num: 5, den: 2, mode: HalfUp, want: 3},
+		{num: 7, den: 2, mode: HalfUp, want: 4},
+		{num: 5, den: 2, mode: HalfEven, want: 2},
+		{num: 7, den: 2, mode: HalfEven, want: 4},
+		{num: 11, den: 3, mode: HalfEven, want: 4},
+		{num: -10, den: 3, mode: HalfUp, want: -3},
+		{num: -11, den: 3, mode: HalfUp, want: -4},
+		{num: -5, den: 2, mode: HalfUp, want: -3},
+		{num: -5, den: 2, mode: HalfEven, want: -2},
+		{num: -7, den: 2, mode: HalfEven, want: -4},
+		{num: 0, den: 7, mode: HalfUp, want: 0},
+		// Tax at 8.25%, in basis points, on 12345.00.
+		{num: 1234500 * 825, den: 10000, mode: HalfUp, want: 101846},
+	}
+
+	for _, tt := range tests {
+		if got := divRound(tt.num, tt.den, tt.mode); got != tt.want {
+			t.Errorf("divRound(%d, %d, %s) = %d, want %d", tt.num, tt.den, tt.mode, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdjustmentRoutes(incomingRoutes *gin.Engine, s *store.Store) {
+	incomingRoutes.POST("/api/approvals", middleware.Authorize(middleware.ApproveAdjustments), controller.CreateApproval())
+	incomingRoutes.GET("/api/adjustments", middleware.Authorize(middleware.ReadAdjustments), controller.GetAdjustments(s))
+}
@@ -2,14 +2,17 @@ package routes
 
 import (
 	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-func FoodRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/api/foods", controller.GetFoods())
-	incomingRoutes.POST("/api/foods", controller.CreateFood())
-	incomingRoutes.GET("/api/foods/:food_id", controller.GetFood())
-	incomingRoutes.PATCH("/api/foods/:food_id", controller.UpdateFood())
-	incomingRoutes.DELETE("/api/foods/:food_id", controller.DeleteFood())
+func FoodRoutes(incomingRoutes *gin.Engine, s *store.Store) {
+	incomingRoutes.GET("/api/foods", middleware.Authorize(middleware.ReadFoods), controller.GetFoods(s))
+	incomingRoutes.POST("/api/foods", middleware.Authorize(middleware.WriteFoods), controller.CreateFood(s))
+	incomingRoutes.GET("/api/foods/:food_id", middleware.Authorize(middleware.ReadFoods), controller.GetFood(s))
+	incomingRoutes.PATCH("/api/foods/:food_id", middleware.Authorize(middleware.WriteFoods), controller.UpdateFood(s))
+	incomingRoutes.POST("/api/foods/:food_id/availability", middleware.Authorize(middleware.EightySixFoods), controller.SetFoodAvailability(s))
+	incomingRoutes.DELETE("/api/foods/:food_id", middleware.Authorize(middleware.WriteFoods), controller.DeleteFood(s))
 }
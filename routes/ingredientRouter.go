@@ -0,0 +1,20 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func IngredientRoutes(incomingRoutes *gin.Engine, s *store.Store, inv *inventory.Inventory) {
+	incomingRoutes.GET("/api/ingredients", middleware.Authorize(middleware.ReadInventory), controller.GetIngredients(s))
+	incomingRoutes.POST("/api/ingredients", middleware.Authorize(middleware.WriteInventory), controller.CreateIngredient(s))
+	incomingRoutes.GET("/api/ingredients/reorder", middleware.Authorize(middleware.ReadInventory), controller.GetReorderSuggestions(s))
+	incomingRoutes.GET("/api/ingredients/:ingredient_id", middleware.Authorize(middleware.ReadInventory), controller.GetIngredient(s))
+	incomingRoutes.PATCH("/api/ingredients/:ingredient_id", middleware.Authorize(middleware.WriteInventory), controller.UpdateIngredient(s, inv))
+	incomingRoutes.DELETE("/api/ingredients/:ingredient_id", middleware.Authorize(middleware.WriteInventory), controller.DeleteIngredient(s))
+	incomingRoutes.POST("/api/ingredients/:ingredient_id/stock", middleware.Authorize(middleware.CountStock), controller.UpdateStock(s, inv))
+}
@@ -2,14 +2,24 @@ package routes
 
 import (
 	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/payments"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-func InvoiceRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/api/invoices", controller.GetInvoices())
-	incomingRoutes.POST("/api/invoices", controller.CreateInvoice())
-	incomingRoutes.GET("/api/invoices/:invoice_id", controller.GetInvoice())
-	incomingRoutes.PATCH("/api/invoices/:invoice_id", controller.UpdateInvoice())
-	incomingRoutes.DELETE("/api/invoices/:invoice_id", controller.DeleteInvoice())
+func InvoiceRoutes(incomingRoutes *gin.Engine, s *store.Store, engine *pricing.Engine, provider payments.PaymentProvider) {
+	incomingRoutes.GET("/api/invoices", middleware.Authorize(middleware.ReadInvoices), controller.GetInvoices(s))
+	incomingRoutes.POST("/api/invoices", middleware.Authorize(middleware.WriteInvoices), controller.CreateInvoice(s))
+	incomingRoutes.GET("/api/invoices/:invoice_id", middleware.Authorize(middleware.ReadInvoices), controller.GetInvoice(s, engine))
+	incomingRoutes.PATCH("/api/invoices/:invoice_id", middleware.Authorize(middleware.WriteInvoices), controller.UpdateInvoice(s))
+	incomingRoutes.POST("/api/invoices/:invoice_id/splits", middleware.Authorize(middleware.WriteInvoices), controller.SplitInvoice(s, engine))
+	incomingRoutes.POST("/api/invoices/:invoice_id/payments", middleware.Authorize(middleware.WriteInvoices), controller.AddPayment(s, engine, provider))
+	incomingRoutes.GET("/api/invoices/:invoice_id/transactions", middleware.Authorize(middleware.ReadInvoices), controller.GetInvoiceTransactions(s))
+	incomingRoutes.POST("/api/invoices/:invoice_id/refund", middleware.Authorize(middleware.WriteInvoices), controller.RefundInvoice(s, engine, provider))
+	incomingRoutes.POST("/api/invoices/:invoice_id/void", middleware.Authorize(middleware.WriteInvoices), controller.VoidInvoice(s, engine))
+	incomingRoutes.POST("/api/invoices/:invoice_id/comp", middleware.Authorize(middleware.WriteInvoices), controller.CompInvoice(s, engine))
+	incomingRoutes.DELETE("/api/invoices/:invoice_id", middleware.Authorize(middleware.DeleteInvoices), controller.DeleteInvoice(s))
 }
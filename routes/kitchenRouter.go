@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func KitchenRoutes(incomingRoutes *gin.Engine, s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory) {
+	incomingRoutes.GET("/api/kitchen/tickets", middleware.Authorize(middleware.ReadOrders), controller.GetKitchenTickets(s))
+	incomingRoutes.GET("/api/kitchen/stream", middleware.Authorize(middleware.ReadOrders), controller.KitchenStream(s, hub))
+	incomingRoutes.POST("/api/kitchen/items/:order_item_id/bump", middleware.Authorize(middleware.AdvanceOrders), controller.BumpOrderItem(s, hub, inv))
+}
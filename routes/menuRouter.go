@@ -2,14 +2,17 @@ package routes
 
 import (
 	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-func MenuRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/api/menu", controller.GetMenus())
-	incomingRoutes.POST("/api/menus", controller.CreateMenu())
-	incomingRoutes.GET("/api/menus/:menu_id", controller.GetMenu())
-	incomingRoutes.PATCH("/api/menus/:menu_id", controller.UpdateMenu())
-	incomingRoutes.DELETE("/api/menus/:menu_id", controller.DeleteMenu())
+func MenuRoutes(incomingRoutes *gin.Engine, s *store.Store) {
+	incomingRoutes.GET("/api/menu", middleware.Authorize(middleware.ReadMenus), controller.GetMenus(s))
+	incomingRoutes.POST("/api/menus", middleware.Authorize(middleware.WriteMenus), controller.CreateMenu(s))
+	incomingRoutes.GET("/api/menus/active", middleware.Authorize(middleware.ReadMenus), controller.GetActiveMenus(s))
+	incomingRoutes.GET("/api/menus/:menu_id", middleware.Authorize(middleware.ReadMenus), controller.GetMenu(s))
+	incomingRoutes.PATCH("/api/menus/:menu_id", middleware.Authorize(middleware.WriteMenus), controller.UpdateMenu(s))
+	incomingRoutes.DELETE("/api/menus/:menu_id", middleware.Authorize(middleware.WriteMenus), controller.DeleteMenu(s))
 }
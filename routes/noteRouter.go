@@ -0,0 +1,20 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func NoteRoutes(incomingRoutes *gin.Engine, s *store.Store, hub *kitchen.Hub) {
+	incomingRoutes.GET("/api/notes", middleware.Authorize(middleware.ReadNotes), controller.GetNotes(s))
+	incomingRoutes.POST("/api/notes", middleware.Authorize(middleware.WriteNotes), controller.CreateNote(s, hub))
+	incomingRoutes.GET("/api/notes/:note_id", middleware.Authorize(middleware.ReadNotes), controller.GetNote(s))
+	incomingRoutes.PATCH("/api/notes/:note_id", middleware.Authorize(middleware.WriteNotes), controller.UpdateNote(s, hub))
+	incomingRoutes.DELETE("/api/notes/:note_id", middleware.Authorize(middleware.WriteNotes), controller.DeleteNote(s, hub))
+	incomingRoutes.POST("/api/notes/:note_id/pin", middleware.Authorize(middleware.WriteNotes), controller.PinNote(s, hub, true))
+	incomingRoutes.DELETE("/api/notes/:note_id/pin", middleware.Authorize(middleware.WriteNotes), controller.PinNote(s, hub, false))
+}
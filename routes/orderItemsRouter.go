@@ -2,15 +2,23 @@ package routes
 
 import (
 	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/payments"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-func OrderItemsRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/api/orderItems", controller.GetOrderItems())
-	incomingRoutes.POST("/api/orderItems", controller.CreateOrderItem())
-	incomingRoutes.GET("/api/orderItems/:orderItem_id", controller.GetOrderItem())
-	incomingRoutes.PATCH("/api/orderItems/:orderItem_id", controller.UpdateOrderItem())
-	incomingRoutes.DELETE("/api/orderItems/:orderItem_id", controller.DeleteOrderItem())
-	incomingRoutes.GET("/api/orderItems/order/:order_id", controller.GetOrderItemsByOrder())
+func OrderItemsRoutes(incomingRoutes *gin.Engine, s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory, engine *pricing.Engine, provider payments.PaymentProvider) {
+	incomingRoutes.GET("/api/orderItems", middleware.Authorize(middleware.ReadOrders), controller.GetOrderItems(s))
+	incomingRoutes.POST("/api/orderItems", middleware.Authorize(middleware.WriteOrders), controller.CreateOrderItem(s, hub, inv))
+	incomingRoutes.GET("/api/orderItems/:order_item_id", middleware.Authorize(middleware.ReadOrders), controller.GetOrderItem(s))
+	incomingRoutes.PATCH("/api/orderItems/:order_item_id", middleware.Authorize(middleware.WriteOrders), controller.UpdateOrderItem(s, hub, inv))
+	incomingRoutes.POST("/api/orderItems/:order_item_id/refund", middleware.Authorize(middleware.WriteInvoices), controller.RefundOrderItem(s, engine, provider))
+	incomingRoutes.POST("/api/orderItems/:order_item_id/void", middleware.Authorize(middleware.WriteInvoices), controller.VoidOrderItem(s, hub, inv, engine))
+	incomingRoutes.POST("/api/orderItems/:order_item_id/comp", middleware.Authorize(middleware.WriteInvoices), controller.CompOrderItem(s, engine))
+	incomingRoutes.GET("/api/orderItems/order/:order_id", middleware.Authorize(middleware.ReadOrders), controller.GetOrderItemsByOrder(s, engine))
 }
@@ -2,14 +2,23 @@ package routes
 
 import (
 	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/kitchen"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-func OrderRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/api/orders", controller.GetOrders())
-	incomingRoutes.POST("/api/orders", controller.CreateOrder())
-	incomingRoutes.GET("/api/orders/:order_id", controller.GetOrder())
-	incomingRoutes.PATCH("/api/orders/:order_id", controller.UpdateOrder())
-	incomingRoutes.DELETE("/api/orders/:order_id", controller.DeleteOrder())
+func OrderRoutes(incomingRoutes *gin.Engine, s *store.Store, hub *kitchen.Hub, inv *inventory.Inventory, engine *pricing.Engine) {
+	incomingRoutes.GET("/api/orders", middleware.Authorize(middleware.ReadOrders), controller.GetOrders(s))
+	incomingRoutes.POST("/api/orders", middleware.Authorize(middleware.WriteOrders), controller.CreateOrder(s))
+	incomingRoutes.GET("/api/orders/:order_id", middleware.Authorize(middleware.ReadOrders), controller.GetOrder(s))
+	incomingRoutes.PATCH("/api/orders/:order_id", middleware.Authorize(middleware.WriteOrders), controller.UpdateOrder(s))
+	incomingRoutes.POST("/api/orders/:order_id/transitions", middleware.Authorize(middleware.AdvanceOrders), controller.TransitionOrder(s, hub, inv, engine))
+	incomingRoutes.POST("/api/orders/:order_id/merge", middleware.Authorize(middleware.WriteOrders), controller.MergeOrders(s, hub))
+	incomingRoutes.POST("/api/orders/:order_id/split", middleware.Authorize(middleware.WriteOrders), controller.SplitOrder(s, hub))
+	incomingRoutes.POST("/api/orders/:order_id/move-items", middleware.Authorize(middleware.WriteOrders), controller.MoveOrderItems(s, hub))
+	incomingRoutes.POST("/api/tables/:table_id/transfer", middleware.Authorize(middleware.WriteOrders), controller.TransferTable(s, hub))
 }
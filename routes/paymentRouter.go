@@ -0,0 +1,16 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/payments"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PaymentWebhookRoutes must be registered before the authentication
+// middleware; webhooks authenticate with the provider's signature instead.
+func PaymentWebhookRoutes(incomingRoutes *gin.Engine, s *store.Store, engine *pricing.Engine, provider payments.PaymentProvider) {
+	incomingRoutes.POST("/api/payments/webhooks/:provider", controller.PaymentWebhook(s, engine, provider))
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PriceRuleRoutes(incomingRoutes *gin.Engine, s *store.Store) {
+	incomingRoutes.GET("/api/price-rules", middleware.Authorize(middleware.ReadFoods), controller.GetPriceRules(s))
+	incomingRoutes.POST("/api/price-rules", middleware.Authorize(middleware.WriteFoods), controller.CreatePriceRule(s))
+	incomingRoutes.GET("/api/price-rules/:rule_id", middleware.Authorize(middleware.ReadFoods), controller.GetPriceRule(s))
+	incomingRoutes.PATCH("/api/price-rules/:rule_id", middleware.Authorize(middleware.WriteFoods), controller.UpdatePriceRule(s))
+	incomingRoutes.DELETE("/api/price-rules/:rule_id", middleware.Authorize(middleware.WriteFoods), controller.DeletePriceRule(s))
+}
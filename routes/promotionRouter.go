@@ -0,0 +1,20 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/pricing"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PromotionRoutes(incomingRoutes *gin.Engine, s *store.Store, engine *pricing.Engine) {
+	incomingRoutes.GET("/api/promotions", middleware.Authorize(middleware.ReadPromotions), controller.GetPromotions(s))
+	incomingRoutes.POST("/api/promotions", middleware.Authorize(middleware.WritePromotions), controller.CreatePromotion(s))
+	incomingRoutes.GET("/api/promotions/:promotion_id", middleware.Authorize(middleware.ReadPromotions), controller.GetPromotion(s))
+	incomingRoutes.PATCH("/api/promotions/:promotion_id", middleware.Authorize(middleware.WritePromotions), controller.UpdatePromotion(s))
+	incomingRoutes.DELETE("/api/promotions/:promotion_id", middleware.Authorize(middleware.WritePromotions), controller.DeletePromotion(s))
+	incomingRoutes.POST("/api/orders/:order_id/promotions", middleware.Authorize(middleware.ApplyPromotions), controller.ApplyPromotion(s, engine))
+	incomingRoutes.DELETE("/api/orders/:order_id/promotions/:promotion_id", middleware.Authorize(middleware.ApplyPromotions), controller.RemovePromotion(s, engine))
+}
@@ -0,0 +1,20 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func PurchaseOrderRoutes(incomingRoutes *gin.Engine, s *store.Store, inv *inventory.Inventory) {
+	incomingRoutes.GET("/api/purchase-orders", middleware.Authorize(middleware.ReadInventory), controller.GetPurchaseOrders(s))
+	incomingRoutes.POST("/api/purchase-orders", middleware.Authorize(middleware.WriteInventory), controller.CreatePurchaseOrder(s))
+	incomingRoutes.GET("/api/purchase-orders/:purchase_order_id", middleware.Authorize(middleware.ReadInventory), controller.GetPurchaseOrder(s))
+	incomingRoutes.PATCH("/api/purchase-orders/:purchase_order_id", middleware.Authorize(middleware.WriteInventory), controller.UpdatePurchaseOrder(s))
+	incomingRoutes.DELETE("/api/purchase-orders/:purchase_order_id", middleware.Authorize(middleware.WriteInventory), controller.DeletePurchaseOrder(s))
+	incomingRoutes.POST("/api/purchase-orders/:purchase_order_id/transitions", middleware.Authorize(middleware.WriteInventory), controller.TransitionPurchaseOrder(s))
+	incomingRoutes.POST("/api/purchase-orders/:purchase_order_id/deliveries", middleware.Authorize(middleware.CountStock), controller.ReceiveDelivery(s, inv))
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/jamesconfy/restaurant-management/booking"
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ReservationRoutes(incomingRoutes *gin.Engine, s *store.Store, planner *booking.Planner) {
+	incomingRoutes.GET("/api/tables/availability", middleware.Authorize(middleware.CheckAvailability), controller.GetTableAvailability(s, planner))
+	incomingRoutes.GET("/api/reservations", middleware.Authorize(middleware.ReadReservations), controller.GetReservations(s, planner))
+	incomingRoutes.POST("/api/reservations", middleware.Authorize(middleware.WriteReservations), controller.CreateReservation(s, planner))
+	incomingRoutes.GET("/api/reservations/:reservation_id", middleware.Authorize(middleware.ReadReservations), controller.GetReservation(s))
+	incomingRoutes.PATCH("/api/reservations/:reservation_id", middleware.Authorize(middleware.WriteReservations), controller.UpdateReservation(s, planner))
+	incomingRoutes.POST("/api/reservations/:reservation_id/cancel", middleware.Authorize(middleware.WriteReservations), controller.CancelReservation(s, planner))
+	incomingRoutes.POST("/api/reservations/:reservation_id/seat", middleware.Authorize(middleware.WriteReservations), controller.SeatReservation(s, planner))
+	incomingRoutes.POST("/api/reservations/:reservation_id/no-show", middleware.Authorize(middleware.WriteReservations), controller.MarkNoShow(s, planner))
+}
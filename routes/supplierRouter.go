@@ -0,0 +1,17 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func SupplierRoutes(incomingRoutes *gin.Engine, s *store.Store) {
+	incomingRoutes.GET("/api/suppliers", middleware.Authorize(middleware.ReadInventory), controller.GetSuppliers(s))
+	incomingRoutes.POST("/api/suppliers", middleware.Authorize(middleware.WriteInventory), controller.CreateSupplier(s))
+	incomingRoutes.GET("/api/suppliers/:supplier_id", middleware.Authorize(middleware.ReadInventory), controller.GetSupplier(s))
+	incomingRoutes.PATCH("/api/suppliers/:supplier_id", middleware.Authorize(middleware.WriteInventory), controller.UpdateSupplier(s))
+	incomingRoutes.DELETE("/api/suppliers/:supplier_id", middleware.Authorize(middleware.WriteInventory), controller.DeleteSupplier(s))
+}
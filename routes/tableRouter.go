@@ -2,14 +2,19 @@ package routes
 
 import (
 	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+	"github.com/jamesconfy/restaurant-management/waitlist"
 
 	"github.com/gin-gonic/gin"
 )
 
-func TableRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/api/tables", controller.GetTables())
-	incomingRoutes.POST("/api/tables", controller.CreateTable())
-	incomingRoutes.GET("/api/tables/:table_id", controller.GetTable())
-	incomingRoutes.PATCH("/api/tables/:table_id", controller.UpdateTable())
-	incomingRoutes.DELETE("/api/tables/:table_id", controller.DeleteTable())
+func TableRoutes(incomingRoutes *gin.Engine, s *store.Store, notifier waitlist.Notifier) {
+	incomingRoutes.GET("/api/floor", middleware.Authorize(middleware.ReadTables), controller.GetFloor(s))
+	incomingRoutes.GET("/api/tables", middleware.Authorize(middleware.ReadTables), controller.GetTables(s))
+	incomingRoutes.POST("/api/tables", middleware.Authorize(middleware.WriteTables), controller.CreateTable(s))
+	incomingRoutes.GET("/api/tables/:table_id", middleware.Authorize(middleware.ReadTables), controller.GetTable(s))
+	incomingRoutes.PATCH("/api/tables/:table_id", middleware.Authorize(middleware.WriteTables), controller.UpdateTable(s))
+	incomingRoutes.DELETE("/api/tables/:table_id", middleware.Authorize(middleware.WriteTables), controller.DeleteTable(s))
+	incomingRoutes.POST("/api/tables/:table_id/clean", middleware.Authorize(middleware.CleanTables), controller.CleanTable(s, notifier))
 }
@@ -2,13 +2,21 @@ package routes
 
 import (
 	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserRoutes(incomingRoutes *gin.Engine) {
-	incomingRoutes.GET("/api/users", controller.GetUsers())
-	incomingRoutes.GET("/api/users/:user_id", controller.GetUser())
-	incomingRoutes.POST("/api/register", controller.Register())
-	incomingRoutes.POST("/api/login", controller.Login())
+func AuthRoutes(incomingRoutes *gin.Engine, s *store.Store) {
+	incomingRoutes.POST("/api/register", controller.Register(s))
+	incomingRoutes.POST("/api/login", controller.Login(s))
+	incomingRoutes.POST("/api/token/refresh", controller.RefreshToken(s))
+}
+
+func UserRoutes(incomingRoutes *gin.Engine, s *store.Store) {
+	incomingRoutes.POST("/api/logout", controller.Logout(s))
+	incomingRoutes.GET("/api/users", middleware.Authorize(middleware.ReadUsers), controller.GetUsers(s))
+	incomingRoutes.GET("/api/users/:user_id", middleware.Authorize(middleware.ReadUsers), controller.GetUser(s))
+	incomingRoutes.PATCH("/api/users/:user_id/role", middleware.Authorize(middleware.ManageUsers), controller.UpdateUserRole(s))
 }
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/jamesconfy/restaurant-management/booking"
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+	"github.com/jamesconfy/restaurant-management/waitlist"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WaitlistRoutes(incomingRoutes *gin.Engine, s *store.Store, planner *booking.Planner, notifier waitlist.Notifier) {
+	incomingRoutes.GET("/api/waitlist", middleware.Authorize(middleware.ReadReservations), controller.GetWaitlist(s, planner))
+	incomingRoutes.POST("/api/waitlist", middleware.Authorize(middleware.WriteReservations), controller.AddToWaitlist(s, planner))
+	incomingRoutes.GET("/api/waitlist/quote", middleware.Authorize(middleware.ReadReservations), controller.QuoteWait(s, planner))
+	incomingRoutes.GET("/api/waitlist/:entry_id", middleware.Authorize(middleware.ReadReservations), controller.GetWaitlistEntry(s))
+	incomingRoutes.PATCH("/api/waitlist/:entry_id", middleware.Authorize(middleware.WriteReservations), controller.UpdateWaitlistEntry(s))
+	incomingRoutes.POST("/api/waitlist/:entry_id/notify", middleware.Authorize(middleware.WriteReservations), controller.NotifyWaitlistEntry(s, notifier))
+	incomingRoutes.POST("/api/waitlist/:entry_id/leave", middleware.Authorize(middleware.WriteReservations), controller.LeaveWaitlist(s))
+}
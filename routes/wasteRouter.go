@@ -0,0 +1,18 @@
+package routes
+
+import (
+	controller "github.com/jamesconfy/restaurant-management/controllers"
+	"github.com/jamesconfy/restaurant-management/inventory"
+	"github.com/jamesconfy/restaurant-management/middleware"
+	"github.com/jamesconfy/restaurant-management/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+func WasteRoutes(incomingRoutes *gin.Engine, s *store.Store, inv *inventory.Inventory) {
+	incomingRoutes.GET("/api/waste", middleware.Authorize(middleware.ReadInventory), controller.GetWaste(s))
+	incomingRoutes.POST("/api/waste", middleware.Authorize(middleware.LogWaste), controller.LogWaste(s, inv))
+	incomingRoutes.GET("/api/waste/report", middleware.Authorize(middleware.ReadInventory), controller.GetWasteReport(s))
+	incomingRoutes.GET("/api/waste/:waste_id", middleware.Authorize(middleware.ReadInventory), controller.GetWasteEntry(s))
+	incomingRoutes.DELETE("/api/waste/:waste_id", middleware.Authorize(middleware.WriteInventory), controller.DeleteWaste(s, inv))
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AdjustmentFilter narrows an adjustment listing. Zero fields match
+// everything; From is inclusive and To exclusive.
+type AdjustmentFilter struct {
+	Invoice_ID     string
+	Order_Item_ID  string
+	Type           string
+	Approved_By_ID string
+	From           time.Time
+	To             time.Time
+}
+
+// AdjustmentRepository is append-only: adjustments are an audit trail.
+type AdjustmentRepository interface {
+	List(ctx context.Context, filter AdjustmentFilter) ([]models.Adjustment, error)
+	Create(ctx context.Context, adjustment models.Adjustment) error
+}
+
+type mongoAdjustmentRepository struct {
+	mongoCollection[models.Adjustment]
+}
+
+func (r *mongoAdjustmentRepository) List(ctx context.Context, filter AdjustmentFilter) ([]models.Adjustment, error) {
+	query := bson.M{}
+	if filter.Invoice_ID != "" {
+		query["invoice_id"] = filter.Invoice_ID
+	}
+	if filter.Order_Item_ID != "" {
+		query["order_item_id"] = filter.Order_Item_ID
+	}
+	if filter.Type != "" {
+		query["type"] = filter.Type
+	}
+	if filter.Approved_By_ID != "" {
+		query["approved_by_id"] = filter.Approved_By_ID
+	}
+
+	createdAt := bson.M{}
+	if !filter.From.IsZero() {
+		createdAt["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		createdAt["$lt"] = filter.To
+	}
+	if len(createdAt) > 0 {
+		query["created_at"] = createdAt
+	}
+
+	return r.find(ctx, query, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+func (r *mongoAdjustmentRepository) Create(ctx context.Context, adjustment models.Adjustment) error {
+	return r.insert(ctx, adjustment)
+}
+
+func (r *mongoAdjustmentRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "invoice_id", Value: 1}}},
+		{Keys: bson.D{{Key: "created_at", Value: 1}}},
+	})
+	return err
+}
+
+type memoryAdjustmentRepository struct {
+	*memoryCollection[models.Adjustment]
+}
+
+func (r *memoryAdjustmentRepository) List(ctx context.Context, filter AdjustmentFilter) ([]models.Adjustment, error) {
+	return r.find(func(a models.Adjustment) bool {
+		return (filter.Invoice_ID == "" || a.Invoice_ID == filter.Invoice_ID) &&
+			(filter.Order_Item_ID == "" || a.Order_Item_ID == filter.Order_Item_ID) &&
+			(filter.Type == "" || a.Type == filter.Type) &&
+			(filter.Approved_By_ID == "" || a.Approved_By_ID == filter.Approved_By_ID) &&
+			(filter.From.IsZero() || !a.Created_At.Before(filter.From)) &&
+			(filter.To.IsZero() || a.Created_At.Before(filter.To))
+	})
+}
+
+func (r *memoryAdjustmentRepository) Create(ctx context.Context, adjustment models.Adjustment) error {
+	return r.insert(ctx, adjustment)
+}
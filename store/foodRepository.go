@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FoodFilter narrows a food listing to foods free of every one of
+// Without_Allergens and carrying all of Dietary_Tags. Uses_Ingredient keeps
+// foods whose recipe calls for that ingredient, and Available keeps foods
+// that are, or are not, 86'd.
+type FoodFilter struct {
+	Without_Allergens []string
+	Dietary_Tags      []string
+	Uses_Ingredient   string
+	Available         *bool
+}
+
+func (f FoodFilter) query() bson.M {
+	query := bson.M{}
+	if len(f.Without_Allergens) > 0 {
+		query["allergens"] = bson.M{"$nin": f.Without_Allergens}
+	}
+	if len(f.Dietary_Tags) > 0 {
+		query["dietary_tags"] = bson.M{"$all": f.Dietary_Tags}
+	}
+	if f.Uses_Ingredient != "" {
+		query["recipe.ingredient_id"] = f.Uses_Ingredient
+	}
+	if f.Available != nil {
+		if *f.Available {
+			query["available"] = bson.M{"$ne": false}
+		} else {
+			query["available"] = false
+		}
+	}
+
+	return query
+}
+
+func (f FoodFilter) matches(food models.Food) bool {
+	for _, allergen := range f.Without_Allergens {
+		for _, contains := range food.Allergens {
+			if contains == allergen {
+				return false
+			}
+		}
+	}
+
+	if f.Uses_Ingredient != "" && !food.UsesIngredient(f.Uses_Ingredient) {
+		return false
+	}
+
+	if f.Available != nil && food.IsAvailable() != *f.Available {
+		return false
+	}
+
+	return food.HasDietaryTags(f.Dietary_Tags)
+}
+
+type FoodRepository interface {
+	List(ctx context.Context, filter FoodFilter, offset, limit int) ([]models.Food, int64, error)
+	Get(ctx context.Context, foodId string) (models.Food, error)
+	Create(ctx context.Context, food models.Food) error
+	Update(ctx context.Context, food models.Food) error
+	Delete(ctx context.Context, foodId string) error
+}
+
+type mongoFoodRepository struct {
+	mongoCollection[models.Food]
+}
+
+func (r *mongoFoodRepository) List(ctx context.Context, filter FoodFilter, offset, limit int) ([]models.Food, int64, error) {
+	total, err := r.count(ctx, filter.query())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(int64(offset)).SetLimit(int64(limit))
+	foods, err := r.find(ctx, filter.query(), opts)
+	return foods, total, err
+}
+
+func (r *mongoFoodRepository) Get(ctx context.Context, foodId string) (models.Food, error) {
+	return r.get(ctx, foodId)
+}
+
+func (r *mongoFoodRepository) Create(ctx context.Context, food models.Food) error {
+	return r.insert(ctx, food)
+}
+
+func (r *mongoFoodRepository) Update(ctx context.Context, food models.Food) error {
+	return r.replace(ctx, food.Food_ID, food)
+}
+
+func (r *mongoFoodRepository) Delete(ctx context.Context, foodId string) error {
+	return r.delete(ctx, foodId)
+}
+
+type memoryFoodRepository struct {
+	*memoryCollection[models.Food]
+}
+
+func (r *memoryFoodRepository) List(ctx context.Context, filter FoodFilter, offset, limit int) ([]models.Food, int64, error) {
+	foods, err := r.find(filter.matches)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return paginate(foods, offset, limit), int64(len(foods)), nil
+}
+
+func (r *memoryFoodRepository) Get(ctx context.Context, foodId string) (models.Food, error) {
+	return r.get(foodId)
+}
+
+func (r *memoryFoodRepository) Create(ctx context.Context, food models.Food) error {
+	return r.insert(ctx, food)
+}
+
+func (r *memoryFoodRepository) Update(ctx context.Context, food models.Food) error {
+	return r.replace(ctx, food.Food_ID, food)
+}
+
+func (r *memoryFoodRepository) Delete(ctx context.Context, foodId string) error {
+	return r.delete(ctx, foodId)
+}
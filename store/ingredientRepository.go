@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type IngredientRepository interface {
+	List(ctx context.Context) ([]models.Ingredient, error)
+	Get(ctx context.Context, ingredientId string) (models.Ingredient, error)
+	Create(ctx context.Context, ingredient models.Ingredient) error
+	// UpdateDetails saves the ingredient's name, unit, levels and supplier.
+	// What is on hand and what it last cost are left alone, since stock
+	// moves and deliveries change those on their own, and the ingredient is
+	// returned as it now stands.
+	UpdateDetails(ctx context.Context, ingredient models.Ingredient) (models.Ingredient, error)
+	Delete(ctx context.Context, ingredientId string) error
+	// Adjust adds delta to what is on hand in one step, so concurrent
+	// orders cannot lose each other's changes, and returns the ingredient
+	// as it now stands.
+	Adjust(ctx context.Context, ingredientId string, delta float64, at time.Time) (models.Ingredient, error)
+	// SetCost records what the latest delivery cost without touching what
+	// is on hand.
+	SetCost(ctx context.Context, ingredientId string, cost models.StockCost, at time.Time) error
+}
+
+type mongoIngredientRepository struct {
+	mongoCollection[models.Ingredient]
+}
+
+func (r *mongoIngredientRepository) List(ctx context.Context) ([]models.Ingredient, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoIngredientRepository) Get(ctx context.Context, ingredientId string) (models.Ingredient, error) {
+	return r.get(ctx, ingredientId)
+}
+
+func (r *mongoIngredientRepository) Create(ctx context.Context, ingredient models.Ingredient) error {
+	return r.insert(ctx, ingredient)
+}
+
+func (r *mongoIngredientRepository) UpdateDetails(ctx context.Context, ingredient models.Ingredient) (models.Ingredient, error) {
+	var after models.Ingredient
+	err := r.coll.FindOneAndUpdate(ctx,
+		bson.M{"ingredient_id": ingredient.Ingredient_ID},
+		bson.M{"$set": bson.M{
+			"name":                ingredient.Name,
+			"unit":                ingredient.Unit,
+			"low_stock_threshold": ingredient.Low_Stock_Threshold,
+			"par_level":           ingredient.Par_Level,
+			"supplier_id":         ingredient.Supplier_ID,
+			"updated_at":          ingredient.Updated_At,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&after)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return after, ErrNotFound
+	}
+
+	return after, err
+}
+
+func (r *mongoIngredientRepository) Delete(ctx context.Context, ingredientId string) error {
+	return r.delete(ctx, ingredientId)
+}
+
+func (r *mongoIngredientRepository) Adjust(ctx context.Context, ingredientId string, delta float64, at time.Time) (models.Ingredient, error) {
+	var ingredient models.Ingredient
+	err := r.coll.FindOneAndUpdate(ctx,
+		bson.M{"ingredient_id": ingredientId},
+		bson.M{"$inc": bson.M{"on_hand": delta}, "$set": bson.M{"updated_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&ingredient)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ingredient, ErrNotFound
+	}
+
+	return ingredient, err
+}
+
+func (r *mongoIngredientRepository) SetCost(ctx context.Context, ingredientId string, cost models.StockCost, at time.Time) error {
+	result, err := r.coll.UpdateOne(ctx,
+		bson.M{"ingredient_id": ingredientId},
+		bson.M{"$set": bson.M{"last_cost": cost, "updated_at": at}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+type memoryIngredientRepository struct {
+	*memoryCollection[models.Ingredient]
+}
+
+func (r *memoryIngredientRepository) List(ctx context.Context) ([]models.Ingredient, error) {
+	return r.find(nil)
+}
+
+func (r *memoryIngredientRepository) Get(ctx context.Context, ingredientId string) (models.Ingredient, error) {
+	return r.get(ingredientId)
+}
+
+func (r *memoryIngredientRepository) Create(ctx context.Context, ingredient models.Ingredient) error {
+	return r.insert(ctx, ingredient)
+}
+
+func (r *memoryIngredientRepository) UpdateDetails(ctx context.Context, ingredient models.Ingredient) (models.Ingredient, error) {
+	return r.update(ctx, ingredient.Ingredient_ID, func(after *models.Ingredient) {
+		after.Name = ingredient.Name
+		after.Unit = ingredient.Unit
+		after.Low_Stock_Threshold = ingredient.Low_Stock_Threshold
+		after.Par_Level = ingredient.Par_Level
+		after.Supplier_ID = ingredient.Supplier_ID
+		after.Updated_At = ingredient.Updated_At
+	})
+}
+
+func (r *memoryIngredientRepository) Delete(ctx context.Context, ingredientId string) error {
+	return r.delete(ctx, ingredientId)
+}
+
+func (r *memoryIngredientRepository) Adjust(ctx context.Context, ingredientId string, delta float64, at time.Time) (models.Ingredient, error) {
+	return r.update(ctx, ingredientId, func(ingredient *models.Ingredient) {
+		ingredient.On_Hand += delta
+		ingredient.Updated_At = at
+	})
+}
+
+func (r *memoryIngredientRepository) SetCost(ctx context.Context, ingredientId string, cost models.StockCost, at time.Time) error {
+	_, err := r.update(ctx, ingredientId, func(ingredient *models.Ingredient) {
+		ingredient.Last_Cost = &cost
+		ingredient.Updated_At = at
+	})
+	return err
+}
@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type InvoiceRepository interface {
+	List(ctx context.Context) ([]models.Invoice, error)
+	Get(ctx context.Context, invoiceId string) (models.Invoice, error)
+	GetByOrder(ctx context.Context, orderId string) (models.Invoice, error)
+	Create(ctx context.Context, invoice models.Invoice) error
+	Update(ctx context.Context, invoice models.Invoice) error
+	Delete(ctx context.Context, invoiceId string) error
+}
+
+type mongoInvoiceRepository struct {
+	mongoCollection[models.Invoice]
+}
+
+func (r *mongoInvoiceRepository) List(ctx context.Context) ([]models.Invoice, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoInvoiceRepository) Get(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	return r.get(ctx, invoiceId)
+}
+
+func (r *mongoInvoiceRepository) GetByOrder(ctx context.Context, orderId string) (models.Invoice, error) {
+	return r.findOne(ctx, bson.M{"order_id": orderId})
+}
+
+func (r *mongoInvoiceRepository) Create(ctx context.Context, invoice models.Invoice) error {
+	return r.insert(ctx, invoice)
+}
+
+func (r *mongoInvoiceRepository) Update(ctx context.Context, invoice models.Invoice) error {
+	return r.replace(ctx, invoice.Invoice_ID, invoice)
+}
+
+func (r *mongoInvoiceRepository) Delete(ctx context.Context, invoiceId string) error {
+	return r.delete(ctx, invoiceId)
+}
+
+type memoryInvoiceRepository struct {
+	*memoryCollection[models.Invoice]
+}
+
+func (r *memoryInvoiceRepository) List(ctx context.Context) ([]models.Invoice, error) {
+	return r.find(nil)
+}
+
+func (r *memoryInvoiceRepository) Get(ctx context.Context, invoiceId string) (models.Invoice, error) {
+	return r.get(invoiceId)
+}
+
+func (r *memoryInvoiceRepository) GetByOrder(ctx context.Context, orderId string) (models.Invoice, error) {
+	return r.findOne(func(i models.Invoice) bool { return i.Order_ID == orderId })
+}
+
+func (r *memoryInvoiceRepository) Create(ctx context.Context, invoice models.Invoice) error {
+	return r.insert(ctx, invoice)
+}
+
+func (r *memoryInvoiceRepository) Update(ctx context.Context, invoice models.Invoice) error {
+	return r.replace(ctx, invoice.Invoice_ID, invoice)
+}
+
+func (r *memoryInvoiceRepository) Delete(ctx context.Context, invoiceId string) error {
+	return r.delete(ctx, invoiceId)
+}
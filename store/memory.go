@@ -0,0 +1,306 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// NewMemoryStore returns a Store that keeps everything in process memory. It
+// behaves like the Mongo store as far as the handlers can tell, which makes it
+// suitable for local development and tests.
+func NewMemoryStore() *Store {
+	s := &Store{
+		Foods:          &memoryFoodRepository{newMemoryCollection(func(f models.Food) string { return f.Food_ID })},
+		Menus:          &memoryMenuRepository{newMemoryCollection(func(m models.Menu) string { return m.Menu_ID })},
+		Tables:         &memoryTableRepository{newMemoryCollection(func(t models.Table) string { return t.Table_ID })},
+		Orders:         &memoryOrderRepository{newMemoryCollection(func(o models.Order) string { return o.Order_ID })},
+		OrderItems:     &memoryOrderItemRepository{newMemoryCollection(func(i models.OrderItem) string { return i.Order_Item_ID })},
+		Invoices:       &memoryInvoiceRepository{newMemoryCollection(func(i models.Invoice) string { return i.Invoice_ID })},
+		Users:          &memoryUserRepository{newMemoryCollection(func(u models.User) string { return u.User_ID })},
+		Revocations:    &memoryRevocationRepository{newMemoryCollection(func(t models.RevokedToken) string { return t.Token_ID })},
+		Transactions:   &memoryTransactionRepository{newMemoryCollection(func(t models.PaymentTransaction) string { return t.Transaction_ID })},
+		Adjustments:    &memoryAdjustmentRepository{newMemoryCollection(func(a models.Adjustment) string { return a.Adjustment_ID })},
+		Reservations:   &memoryReservationRepository{newMemoryCollection(func(r models.Reservation) string { return r.Reservation_ID })},
+		Waitlist:       &memoryWaitlistRepository{newMemoryCollection(func(w models.WaitlistEntry) string { return w.Entry_ID })},
+		PriceRules:     &memoryPriceRuleRepository{newMemoryCollection(func(r models.PriceRule) string { return r.Rule_ID })},
+		Promotions:     &memoryPromotionRepository{newMemoryCollection(func(p models.Promotion) string { return p.Promotion_ID })},
+		Notes:          &memoryNoteRepository{newMemoryCollection(func(n models.Note) string { return n.Note_ID })},
+		Ingredients:    &memoryIngredientRepository{newMemoryCollection(func(i models.Ingredient) string { return i.Ingredient_ID })},
+		Suppliers:      &memorySupplierRepository{newMemoryCollection(func(s models.Supplier) string { return s.Supplier_ID })},
+		PurchaseOrders: &memoryPurchaseOrderRepository{newMemoryCollection(func(p models.PurchaseOrder) string { return p.Purchase_Order_ID })},
+		Waste:          &memoryWasteRepository{newMemoryCollection(func(w models.WasteEntry) string { return w.Waste_ID })},
+	}
+	tx := &memoryTransactor{store: s}
+	s.tx = tx
+
+	type locker interface {
+		shareLock(mu *sync.Mutex)
+	}
+	for _, repo := range s.repositories() {
+		if r, ok := repo.(locker); ok {
+			r.shareLock(&tx.mu)
+		}
+	}
+
+	return s
+}
+
+// memoryTransactor runs one transaction at a time and undoes a failed one by
+// putting every collection back the way it was. Every write to the store
+// takes the same lock, so writes from other requests wait for a running
+// transaction instead of being undone along with it.
+type memoryTransactor struct {
+	mu    sync.Mutex
+	store *Store
+}
+
+// memoryTransactionKey marks the context of a running memory transaction,
+// whose writes already hold the lock.
+type memoryTransactionKey struct{}
+
+func (t *memoryTransactor) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	type snapshotter interface {
+		snapshot() func()
+	}
+
+	if ctx.Value(memoryTransactionKey{}) != nil {
+		return fn(ctx)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ctx = context.WithValue(ctx, memoryTransactionKey{}, true)
+
+	restores := []func(){}
+	for _, repo := range t.store.repositories() {
+		if r, ok := repo.(snapshotter); ok {
+			restores = append(restores, r.snapshot())
+		}
+	}
+
+	if err := fn(ctx); err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+		return err
+	}
+
+	return nil
+}
+
+// memoryCollection is an insertion-ordered map of documents. Documents are
+// copied through BSON on the way in and out so callers can never mutate
+// stored state through the pointer fields on the models.
+type memoryCollection[T any] struct {
+	mu   sync.RWMutex
+	key  func(T) string
+	ids  []string
+	docs map[string][]byte
+	// writes is the store-wide write lock shared with the transactor.
+	writes *sync.Mutex
+}
+
+func newMemoryCollection[T any](key func(T) string) *memoryCollection[T] {
+	return &memoryCollection[T]{key: key, docs: map[string][]byte{}}
+}
+
+func (m *memoryCollection[T]) shareLock(mu *sync.Mutex) {
+	m.writes = mu
+}
+
+// write runs fn under the store-wide write lock, unless ctx belongs to the
+// transaction already holding it.
+func (m *memoryCollection[T]) write(ctx context.Context, fn func() error) error {
+	if m.writes != nil && ctx.Value(memoryTransactionKey{}) == nil {
+		m.writes.Lock()
+		defer m.writes.Unlock()
+	}
+
+	return fn()
+}
+
+// snapshot returns a function that puts the collection back as it is now.
+func (m *memoryCollection[T]) snapshot() func() {
+	m.mu.RLock()
+	ids := append([]string(nil), m.ids...)
+	docs := make(map[string][]byte, len(m.docs))
+	for id, raw := range m.docs {
+		docs[id] = raw
+	}
+	m.mu.RUnlock()
+
+	return func() {
+		m.mu.Lock()
+		m.ids, m.docs = ids, docs
+		m.mu.Unlock()
+	}
+}
+
+func (m *memoryCollection[T]) get(id string) (T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var doc T
+	raw, ok := m.docs[id]
+	if !ok {
+		return doc, ErrNotFound
+	}
+
+	err := bson.Unmarshal(raw, &doc)
+	return doc, err
+}
+
+func (m *memoryCollection[T]) findOne(match func(T) bool) (T, error) {
+	docs, err := m.find(match)
+	if err != nil || len(docs) == 0 {
+		var doc T
+		if err == nil {
+			err = ErrNotFound
+		}
+		return doc, err
+	}
+
+	return docs[0], nil
+}
+
+func (m *memoryCollection[T]) find(match func(T) bool) ([]T, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	docs := []T{}
+	for _, id := range m.ids {
+		var doc T
+		if err := bson.Unmarshal(m.docs[id], &doc); err != nil {
+			return nil, err
+		}
+
+		if match == nil || match(doc) {
+			docs = append(docs, doc)
+		}
+	}
+
+	return docs, nil
+}
+
+func (m *memoryCollection[T]) count(match func(T) bool) (int64, error) {
+	docs, err := m.find(match)
+	return int64(len(docs)), err
+}
+
+func (m *memoryCollection[T]) insert(ctx context.Context, doc T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return m.write(ctx, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		id := m.key(doc)
+		if _, ok := m.docs[id]; ok {
+			return ErrDuplicate
+		}
+
+		m.ids = append(m.ids, id)
+		m.docs[id] = raw
+		return nil
+	})
+}
+
+func (m *memoryCollection[T]) replace(ctx context.Context, id string, doc T) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	return m.write(ctx, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.docs[id]; !ok {
+			return ErrNotFound
+		}
+
+		m.docs[id] = raw
+		return nil
+	})
+}
+
+func (m *memoryCollection[T]) delete(ctx context.Context, id string) error {
+	return m.write(ctx, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		if _, ok := m.docs[id]; !ok {
+			return ErrNotFound
+		}
+
+		delete(m.docs, id)
+		for i, existing := range m.ids {
+			if existing == id {
+				m.ids = append(m.ids[:i], m.ids[i+1:]...)
+				break
+			}
+		}
+
+		return nil
+	})
+}
+
+// update changes a stored document in place under the write lock, so
+// concurrent read-modify-write changes cannot lose each other's work.
+func (m *memoryCollection[T]) update(ctx context.Context, id string, change func(*T)) (T, error) {
+	return m.updateIf(ctx, id, nil, change)
+}
+
+// updateIf is update for a document that still matches; if it does not, it
+// is left alone and ErrNotFound returned, as for a Mongo filter that no
+// longer matches.
+func (m *memoryCollection[T]) updateIf(ctx context.Context, id string, match func(T) bool, change func(*T)) (T, error) {
+	var doc T
+	err := m.write(ctx, func() error {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		raw, ok := m.docs[id]
+		if !ok {
+			return ErrNotFound
+		}
+
+		if err := bson.Unmarshal(raw, &doc); err != nil {
+			return err
+		}
+
+		if match != nil && !match(doc) {
+			return ErrNotFound
+		}
+
+		change(&doc)
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+
+		m.docs[id] = raw
+		return nil
+	})
+
+	return doc, err
+}
+
+func paginate[T any](docs []T, offset, limit int) []T {
+	if offset >= len(docs) {
+		return []T{}
+	}
+
+	end := offset + limit
+	if end > len(docs) {
+		end = len(docs)
+	}
+
+	return docs[offset:end]
+}
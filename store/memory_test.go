@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func newTable(number int) models.Table {
+	guests := 4
+	table := models.Table{ID: primitive.NewObjectID(), Number_Of_Guests: &guests, Table_Number: &number}
+	table.Table_ID = table.ID.Hex()
+	return table
+}
+
+func TestMemoryCRUD(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	table := newTable(1)
+	if err := s.Tables.Create(ctx, table); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Tables.Create(ctx, table); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("creating the same table twice: got %v, want ErrDuplicate", err)
+	}
+
+	number := 7
+	table.Table_Number = &number
+	if err := s.Tables.Update(ctx, table); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Tables.Get(ctx, table.Table_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got.Table_Number != 7 {
+		t.Errorf("table number = %d after the update, want 7", *got.Table_Number)
+	}
+
+	if err := s.Tables.Delete(ctx, table.Table_ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Tables.Get(ctx, table.Table_ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("getting a deleted table: got %v, want ErrNotFound", err)
+	}
+	if err := s.Tables.Update(ctx, table); !errors.Is(err, ErrNotFound) {
+		t.Errorf("updating a deleted table: got %v, want ErrNotFound", err)
+	}
+	if err := s.Tables.Delete(ctx, table.Table_ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleting a deleted table: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryTransactionRollsBack(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	kept := newTable(1)
+	if err := s.Tables.Create(ctx, kept); err != nil {
+		t.Fatal(err)
+	}
+
+	failed := errors.New("failed")
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := s.Tables.Create(ctx, newTable(2)); err != nil {
+			return err
+		}
+		if err := s.Tables.Delete(ctx, kept.Table_ID); err != nil {
+			return err
+		}
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want the error the transaction failed with", err)
+	}
+
+	tables, err := s.Tables.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0].Table_ID != kept.Table_ID {
+		t.Errorf("after the rollback the tables are %v, want only the one created before", tables)
+	}
+}
+
+func TestMemoryTransactionCommits(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	err := s.WithTransaction(ctx, func(ctx context.Context) error {
+		// A nested transaction joins the one already running.
+		return s.WithTransaction(ctx, func(ctx context.Context) error {
+			return s.Tables.Create(ctx, newTable(1))
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := s.Tables.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 {
+		t.Errorf("got %d tables, want the one created in the transaction", len(tables))
+	}
+}
+
+// TestMemoryTransactionsSerialise checks that concurrent read-modify-write
+// transactions on the same document do not lose each other's writes.
+func TestMemoryTransactionsSerialise(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	table := newTable(0)
+	if err := s.Tables.Create(ctx, table); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := s.WithTransaction(ctx, func(ctx context.Context) error {
+				current, err := s.Tables.Get(ctx, table.Table_ID)
+				if err != nil {
+					return err
+				}
+
+				number := *current.Table_Number + 1
+				current.Table_Number = &number
+				return s.Tables.Update(ctx, current)
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	got, err := s.Tables.Get(ctx, table.Table_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got.Table_Number != 20 {
+		t.Errorf("table number = %d after 20 increments, want 20", *got.Table_Number)
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type MenuRepository interface {
+	List(ctx context.Context) ([]models.Menu, error)
+	Get(ctx context.Context, menuId string) (models.Menu, error)
+	Create(ctx context.Context, menu models.Menu) error
+	Update(ctx context.Context, menu models.Menu) error
+	Delete(ctx context.Context, menuId string) error
+}
+
+type mongoMenuRepository struct {
+	mongoCollection[models.Menu]
+}
+
+func (r *mongoMenuRepository) List(ctx context.Context) ([]models.Menu, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoMenuRepository) Get(ctx context.Context, menuId string) (models.Menu, error) {
+	return r.get(ctx, menuId)
+}
+
+func (r *mongoMenuRepository) Create(ctx context.Context, menu models.Menu) error {
+	return r.insert(ctx, menu)
+}
+
+func (r *mongoMenuRepository) Update(ctx context.Context, menu models.Menu) error {
+	return r.replace(ctx, menu.Menu_ID, menu)
+}
+
+func (r *mongoMenuRepository) Delete(ctx context.Context, menuId string) error {
+	return r.delete(ctx, menuId)
+}
+
+type memoryMenuRepository struct {
+	*memoryCollection[models.Menu]
+}
+
+func (r *memoryMenuRepository) List(ctx context.Context) ([]models.Menu, error) {
+	return r.find(nil)
+}
+
+func (r *memoryMenuRepository) Get(ctx context.Context, menuId string) (models.Menu, error) {
+	return r.get(menuId)
+}
+
+func (r *memoryMenuRepository) Create(ctx context.Context, menu models.Menu) error {
+	return r.insert(ctx, menu)
+}
+
+func (r *memoryMenuRepository) Update(ctx context.Context, menu models.Menu) error {
+	return r.replace(ctx, menu.Menu_ID, menu)
+}
+
+func (r *memoryMenuRepository) Delete(ctx context.Context, menuId string) error {
+	return r.delete(ctx, menuId)
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MigrationReport counts what a migration did to one field. Review lists the
+// ids of documents it could not convert safely; those keep their old value,
+// fail to load until fixed, and need someone to set the price by hand.
+type MigrationReport struct {
+	Collection string
+	Field      string
+	Scanned    int
+	Repaired   int
+	Review     []string
+}
+
+// MigratePrices rewrites prices stored as bare numbers into Money documents,
+// converting them with models.LegacyMoney. Only documents never updated since
+// they were created are converted: the old create handlers stored prices
+// scaled by 100, but the old update handlers stored them unscaled, so for a
+// document updated since there is no telling which it holds. Documents that
+// already hold Money are left alone, so the migration can be re-run safely.
+func MigratePrices(ctx context.Context, db *mongo.Database, currency string) ([]MigrationReport, error) {
+	targets := []MigrationReport{
+		{Collection: "food", Field: "price"},
+		{Collection: "orderItem", Field: "unit_price"},
+	}
+
+	for i := range targets {
+		if err := migratePriceField(ctx, db.Collection(targets[i].Collection), currency, &targets[i]); err != nil {
+			return targets, fmt.Errorf("store: migrating %s.%s: %w", targets[i].Collection, targets[i].Field, err)
+		}
+	}
+
+	return targets, nil
+}
+
+func migratePriceField(ctx context.Context, coll *mongo.Collection, currency string, report *MigrationReport) error {
+	filter := bson.M{report.Field: bson.M{"$type": "number"}}
+	cursor, err := coll.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		report.Scanned++
+
+		id := cursor.Current.Lookup("_id")
+		raw := cursor.Current.Lookup(report.Field)
+		if !neverUpdated(cursor.Current) {
+			report.Review = append(report.Review, documentId(id))
+			continue
+		}
+
+		price, err := models.LegacyMoney(raw, currency)
+		if err != nil {
+			report.Review = append(report.Review, documentId(id))
+			continue
+		}
+
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: report.Field, Value: price}}}}
+		match := bson.D{{Key: "_id", Value: id}, {Key: report.Field, Value: raw}}
+
+		result, err := coll.UpdateOne(ctx, match, update)
+		if err != nil {
+			return err
+		}
+		report.Repaired += int(result.ModifiedCount)
+	}
+
+	return cursor.Err()
+}
+
+// neverUpdated reports whether doc still has the timestamps it was created
+// with. The old handlers set both from separate clock reads, so they may be a
+// second apart.
+func neverUpdated(doc bson.Raw) bool {
+	created, ok := doc.Lookup("created_at").TimeOK()
+	if !ok {
+		return false
+	}
+
+	updated, ok := doc.Lookup("updated_at").TimeOK()
+	if !ok {
+		return false
+	}
+
+	return !updated.After(created.Add(time.Second))
+}
+
+func documentId(id bson.RawValue) string {
+	if oid, ok := id.ObjectIDOK(); ok {
+		return oid.Hex()
+	}
+
+	return id.String()
+}
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func NewMongoStore(db *mongo.Database) *Store {
+	s := &Store{
+		Foods:          &mongoFoodRepository{newMongoCollection[models.Food](db, "food", "food_id")},
+		Menus:          &mongoMenuRepository{newMongoCollection[models.Menu](db, "menu", "menu_id")},
+		Tables:         &mongoTableRepository{newMongoCollection[models.Table](db, "table", "table_id")},
+		Orders:         &mongoOrderRepository{newMongoCollection[models.Order](db, "order", "order_id")},
+		OrderItems:     &mongoOrderItemRepository{newMongoCollection[models.OrderItem](db, "orderItem", "order_item_id")},
+		Invoices:       &mongoInvoiceRepository{newMongoCollection[models.Invoice](db, "invoice", "invoice_id")},
+		Users:          &mongoUserRepository{newMongoCollection[models.User](db, "user", "user_id")},
+		Revocations:    &mongoRevocationRepository{newMongoCollection[models.RevokedToken](db, "revokedToken", "token_id")},
+		Transactions:   &mongoTransactionRepository{newMongoCollection[models.PaymentTransaction](db, "paymentTransaction", "transaction_id")},
+		Adjustments:    &mongoAdjustmentRepository{newMongoCollection[models.Adjustment](db, "adjustment", "adjustment_id")},
+		Reservations:   &mongoReservationRepository{newMongoCollection[models.Reservation](db, "reservation", "reservation_id")},
+		Waitlist:       &mongoWaitlistRepository{newMongoCollection[models.WaitlistEntry](db, "waitlist", "entry_id")},
+		PriceRules:     &mongoPriceRuleRepository{newMongoCollection[models.PriceRule](db, "priceRule", "rule_id")},
+		Promotions:     &mongoPromotionRepository{newMongoCollection[models.Promotion](db, "promotion", "promotion_id")},
+		Notes:          &mongoNoteRepository{newMongoCollection[models.Note](db, "note", "note_id")},
+		Ingredients:    &mongoIngredientRepository{newMongoCollection[models.Ingredient](db, "ingredient", "ingredient_id")},
+		Suppliers:      &mongoSupplierRepository{newMongoCollection[models.Supplier](db, "supplier", "supplier_id")},
+		PurchaseOrders: &mongoPurchaseOrderRepository{newMongoCollection[models.PurchaseOrder](db, "purchaseOrder", "purchase_order_id")},
+		Waste:          &mongoWasteRepository{newMongoCollection[models.WasteEntry](db, "waste", "waste_id")},
+	}
+	s.tx = mongoTransactor{client: db.Client()}
+
+	return s
+}
+
+// mongoTransactor runs each transaction in its own session. MongoDB only
+// supports transactions on a replica set or sharded cluster, so a standalone
+// server will refuse them.
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+func (t mongoTransactor) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// RequireTransactions checks that the server the client is connected to can
+// run transactions, which a standalone mongod cannot.
+func RequireTransactions(ctx context.Context, client *mongo.Client) error {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return fmt.Errorf("store: checking for transaction support: %w", err)
+	}
+
+	// Members of a replica set report its name; mongos reports isdbgrid.
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return errors.New("store: MongoDB is running as a standalone server, which cannot run transactions; start it as a replica set (mongod --replSet rs0, then rs.initiate()) and add ?replicaSet=rs0 to MONGO_URI")
+	}
+
+	return nil
+}
+
+// EnsureIndexes creates the indexes the Mongo repositories rely on, such as
+// the TTL index that expires revoked tokens. It is safe to call on every
+// start up.
+func EnsureIndexes(ctx context.Context, s *Store) error {
+	type indexer interface {
+		ensureIndexes(ctx context.Context) error
+	}
+
+	for _, repo := range s.repositories() {
+		if r, ok := repo.(indexer); ok {
+			if err := r.ensureIndexes(ctx); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// mongoCollection wraps a collection whose documents are addressed by a
+// business id field (food_id, order_id, ...) rather than by _id.
+type mongoCollection[T any] struct {
+	coll *mongo.Collection
+	key  string
+}
+
+func newMongoCollection[T any](db *mongo.Database, name, key string) mongoCollection[T] {
+	return mongoCollection[T]{coll: db.Collection(name), key: key}
+}
+
+func (m mongoCollection[T]) get(ctx context.Context, id string) (T, error) {
+	return m.findOne(ctx, bson.M{m.key: id})
+}
+
+func (m mongoCollection[T]) findOne(ctx context.Context, filter interface{}) (T, error) {
+	var doc T
+	err := m.coll.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return doc, ErrNotFound
+	}
+
+	return doc, err
+}
+
+func (m mongoCollection[T]) find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]T, error) {
+	cursor, err := m.coll.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []T{}
+	if err = cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func (m mongoCollection[T]) count(ctx context.Context, filter interface{}) (int64, error) {
+	return m.coll.CountDocuments(ctx, filter)
+}
+
+func (m mongoCollection[T]) insert(ctx context.Context, doc T) error {
+	_, err := m.coll.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDuplicate
+	}
+
+	return err
+}
+
+func (m mongoCollection[T]) replace(ctx context.Context, id string, doc T) error {
+	result, err := m.coll.ReplaceOne(ctx, bson.M{m.key: id}, doc)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (m mongoCollection[T]) delete(ctx context.Context, id string) error {
+	result, err := m.coll.DeleteOne(ctx, bson.M{m.key: id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// NoteFilter narrows a note listing. Text matches notes whose title or text
+// contains it, ignoring case.
+type NoteFilter struct {
+	Subject_Type string
+	Subject_ID   string
+	Author_ID    string
+	Pinned       *bool
+	Text         string
+}
+
+func (f NoteFilter) query() bson.M {
+	query := bson.M{}
+	if f.Subject_Type != "" {
+		query["subject_type"] = f.Subject_Type
+	}
+	if f.Subject_ID != "" {
+		query["subject_id"] = f.Subject_ID
+	}
+	if f.Author_ID != "" {
+		query["author_id"] = f.Author_ID
+	}
+	if f.Pinned != nil {
+		query["pinned"] = *f.Pinned
+	}
+	if f.Text != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(f.Text), "$options": "i"}
+		query["$or"] = bson.A{bson.M{"title": pattern}, bson.M{"text": pattern}}
+	}
+
+	return query
+}
+
+func (f NoteFilter) matches(note models.Note) bool {
+	if f.Subject_Type != "" && note.Subject_Type != f.Subject_Type {
+		return false
+	}
+	if f.Subject_ID != "" && note.Subject_ID != f.Subject_ID {
+		return false
+	}
+	if f.Author_ID != "" && note.Author_ID != f.Author_ID {
+		return false
+	}
+	if f.Pinned != nil && note.Pinned != *f.Pinned {
+		return false
+	}
+	if f.Text != "" {
+		text := strings.ToLower(f.Text)
+		return strings.Contains(strings.ToLower(note.Title), text) || strings.Contains(strings.ToLower(note.Text), text)
+	}
+
+	return true
+}
+
+// notesOrder lists pinned notes first, newest first within each.
+var notesOrder = bson.D{{Key: "pinned", Value: -1}, {Key: "created_at", Value: -1}}
+
+type NoteRepository interface {
+	List(ctx context.Context, filter NoteFilter, offset, limit int) ([]models.Note, int64, error)
+	// Attached lists every note on any of subjects, pinned first.
+	Attached(ctx context.Context, subjects ...models.NoteSubject) ([]models.Note, error)
+	Get(ctx context.Context, noteId string) (models.Note, error)
+	Create(ctx context.Context, note models.Note) error
+	Update(ctx context.Context, note models.Note) error
+	Delete(ctx context.Context, noteId string) error
+}
+
+type mongoNoteRepository struct {
+	mongoCollection[models.Note]
+}
+
+func (r *mongoNoteRepository) List(ctx context.Context, filter NoteFilter, offset, limit int) ([]models.Note, int64, error) {
+	total, err := r.count(ctx, filter.query())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSort(notesOrder).SetSkip(int64(offset)).SetLimit(int64(limit))
+	notes, err := r.find(ctx, filter.query(), opts)
+	return notes, total, err
+}
+
+func (r *mongoNoteRepository) Attached(ctx context.Context, subjects ...models.NoteSubject) ([]models.Note, error) {
+	if len(subjects) == 0 {
+		return []models.Note{}, nil
+	}
+
+	matches := bson.A{}
+	for _, subject := range subjects {
+		matches = append(matches, bson.M{"subject_type": subject.Type, "subject_id": subject.ID})
+	}
+
+	return r.find(ctx, bson.M{"$or": matches}, options.Find().SetSort(notesOrder))
+}
+
+func (r *mongoNoteRepository) Get(ctx context.Context, noteId string) (models.Note, error) {
+	return r.get(ctx, noteId)
+}
+
+func (r *mongoNoteRepository) Create(ctx context.Context, note models.Note) error {
+	return r.insert(ctx, note)
+}
+
+func (r *mongoNoteRepository) Update(ctx context.Context, note models.Note) error {
+	return r.replace(ctx, note.Note_ID, note)
+}
+
+func (r *mongoNoteRepository) Delete(ctx context.Context, noteId string) error {
+	return r.delete(ctx, noteId)
+}
+
+func (r *mongoNoteRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "subject_type", Value: 1}, {Key: "subject_id", Value: 1}}},
+		{Keys: bson.D{{Key: "author_id", Value: 1}}},
+	})
+	return err
+}
+
+type memoryNoteRepository struct {
+	*memoryCollection[models.Note]
+}
+
+func (r *memoryNoteRepository) List(ctx context.Context, filter NoteFilter, offset, limit int) ([]models.Note, int64, error) {
+	notes, err := r.find(filter.matches)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	models.SortNotes(notes)
+	return paginate(notes, offset, limit), int64(len(notes)), nil
+}
+
+func (r *memoryNoteRepository) Attached(ctx context.Context, subjects ...models.NoteSubject) ([]models.Note, error) {
+	notes, err := r.find(func(note models.Note) bool {
+		for _, subject := range subjects {
+			if note.Subject() == subject {
+				return true
+			}
+		}
+
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	models.SortNotes(notes)
+	return notes, nil
+}
+
+func (r *memoryNoteRepository) Get(ctx context.Context, noteId string) (models.Note, error) {
+	return r.get(noteId)
+}
+
+func (r *memoryNoteRepository) Create(ctx context.Context, note models.Note) error {
+	return r.insert(ctx, note)
+}
+
+func (r *memoryNoteRepository) Update(ctx context.Context, note models.Note) error {
+	return r.replace(ctx, note.Note_ID, note)
+}
+
+func (r *memoryNoteRepository) Delete(ctx context.Context, noteId string) error {
+	return r.delete(ctx, noteId)
+}
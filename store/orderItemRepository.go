@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type OrderItemRepository interface {
+	List(ctx context.Context) ([]models.OrderItem, error)
+	Get(ctx context.Context, orderItemId string) (models.OrderItem, error)
+	Create(ctx context.Context, orderItem models.OrderItem) error
+	Update(ctx context.Context, orderItem models.OrderItem) error
+	Delete(ctx context.Context, orderItemId string) error
+	ListByOrder(ctx context.Context, orderId string) ([]models.OrderItem, error)
+	ListByStatus(ctx context.Context, statuses ...string) ([]models.OrderItem, error)
+	// ListDepleted lists items ordered since that have taken stock.
+	ListDepleted(ctx context.Context, since time.Time) ([]models.OrderItem, error)
+}
+
+type mongoOrderItemRepository struct {
+	mongoCollection[models.OrderItem]
+}
+
+func (r *mongoOrderItemRepository) List(ctx context.Context) ([]models.OrderItem, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoOrderItemRepository) Get(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	return r.get(ctx, orderItemId)
+}
+
+func (r *mongoOrderItemRepository) Create(ctx context.Context, orderItem models.OrderItem) error {
+	return r.insert(ctx, orderItem)
+}
+
+func (r *mongoOrderItemRepository) Update(ctx context.Context, orderItem models.OrderItem) error {
+	return r.replace(ctx, orderItem.Order_Item_ID, orderItem)
+}
+
+func (r *mongoOrderItemRepository) Delete(ctx context.Context, orderItemId string) error {
+	return r.delete(ctx, orderItemId)
+}
+
+func (r *mongoOrderItemRepository) ListByOrder(ctx context.Context, orderId string) ([]models.OrderItem, error) {
+	return r.find(ctx, bson.M{"order_id": orderId})
+}
+
+func (r *mongoOrderItemRepository) ListByStatus(ctx context.Context, statuses ...string) ([]models.OrderItem, error) {
+	filter := bson.M{"status": bson.M{"$in": statuses}}
+	for _, status := range statuses {
+		if status == models.ItemQueued {
+			// Items written before statuses existed have none and count as queued.
+			filter = bson.M{"$or": bson.A{filter, bson.M{"status": nil}}}
+			break
+		}
+	}
+
+	return r.find(ctx, filter)
+}
+
+func (r *mongoOrderItemRepository) ListDepleted(ctx context.Context, since time.Time) ([]models.OrderItem, error) {
+	return r.find(ctx, bson.M{"stock_depleted": true, "created_at": bson.M{"$gte": since}})
+}
+
+type memoryOrderItemRepository struct {
+	*memoryCollection[models.OrderItem]
+}
+
+func (r *memoryOrderItemRepository) List(ctx context.Context) ([]models.OrderItem, error) {
+	return r.find(nil)
+}
+
+func (r *memoryOrderItemRepository) Get(ctx context.Context, orderItemId string) (models.OrderItem, error) {
+	return r.get(orderItemId)
+}
+
+func (r *memoryOrderItemRepository) Create(ctx context.Context, orderItem models.OrderItem) error {
+	return r.insert(ctx, orderItem)
+}
+
+func (r *memoryOrderItemRepository) Update(ctx context.Context, orderItem models.OrderItem) error {
+	return r.replace(ctx, orderItem.Order_Item_ID, orderItem)
+}
+
+func (r *memoryOrderItemRepository) Delete(ctx context.Context, orderItemId string) error {
+	return r.delete(ctx, orderItemId)
+}
+
+func (r *memoryOrderItemRepository) ListByOrder(ctx context.Context, orderId string) ([]models.OrderItem, error) {
+	return r.find(func(item models.OrderItem) bool {
+		return item.Order_ID != nil && *item.Order_ID == orderId
+	})
+}
+
+func (r *memoryOrderItemRepository) ListByStatus(ctx context.Context, statuses ...string) ([]models.OrderItem, error) {
+	return r.find(func(item models.OrderItem) bool {
+		for _, status := range statuses {
+			if item.CurrentStatus() == status {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r *memoryOrderItemRepository) ListDepleted(ctx context.Context, since time.Time) ([]models.OrderItem, error) {
+	return r.find(func(item models.OrderItem) bool {
+		return item.Stock_Depleted && !item.Created_At.Before(since)
+	})
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type OrderRepository interface {
+	List(ctx context.Context) ([]models.Order, error)
+	Get(ctx context.Context, orderId string) (models.Order, error)
+	Create(ctx context.Context, order models.Order) error
+	Update(ctx context.Context, order models.Order) error
+	Delete(ctx context.Context, orderId string) error
+	ListByStatus(ctx context.Context, statuses ...string) ([]models.Order, error)
+	// RecentlyClosed lists up to limit closed orders, latest first.
+	RecentlyClosed(ctx context.Context, limit int) ([]models.Order, error)
+}
+
+type mongoOrderRepository struct {
+	mongoCollection[models.Order]
+}
+
+func (r *mongoOrderRepository) List(ctx context.Context) ([]models.Order, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoOrderRepository) Get(ctx context.Context, orderId string) (models.Order, error) {
+	return r.get(ctx, orderId)
+}
+
+func (r *mongoOrderRepository) Create(ctx context.Context, order models.Order) error {
+	return r.insert(ctx, order)
+}
+
+func (r *mongoOrderRepository) Update(ctx context.Context, order models.Order) error {
+	return r.replace(ctx, order.Order_ID, order)
+}
+
+func (r *mongoOrderRepository) Delete(ctx context.Context, orderId string) error {
+	return r.delete(ctx, orderId)
+}
+
+func (r *mongoOrderRepository) ListByStatus(ctx context.Context, statuses ...string) ([]models.Order, error) {
+	filter := bson.M{"status": bson.M{"$in": statuses}}
+	for _, status := range statuses {
+		if status == models.OrderPlaced {
+			// Orders written before statuses existed have none and count as placed.
+			filter = bson.M{"$or": bson.A{filter, bson.M{"status": nil}}}
+			break
+		}
+	}
+
+	return r.find(ctx, filter)
+}
+
+func (r *mongoOrderRepository) RecentlyClosed(ctx context.Context, limit int) ([]models.Order, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}).SetLimit(int64(limit))
+	return r.find(ctx, bson.M{"status": models.OrderClosed}, opts)
+}
+
+type memoryOrderRepository struct {
+	*memoryCollection[models.Order]
+}
+
+func (r *memoryOrderRepository) List(ctx context.Context) ([]models.Order, error) {
+	return r.find(nil)
+}
+
+func (r *memoryOrderRepository) Get(ctx context.Context, orderId string) (models.Order, error) {
+	return r.get(orderId)
+}
+
+func (r *memoryOrderRepository) Create(ctx context.Context, order models.Order) error {
+	return r.insert(ctx, order)
+}
+
+func (r *memoryOrderRepository) Update(ctx context.Context, order models.Order) error {
+	return r.replace(ctx, order.Order_ID, order)
+}
+
+func (r *memoryOrderRepository) Delete(ctx context.Context, orderId string) error {
+	return r.delete(ctx, orderId)
+}
+
+func (r *memoryOrderRepository) ListByStatus(ctx context.Context, statuses ...string) ([]models.Order, error) {
+	return r.find(func(order models.Order) bool {
+		for _, status := range statuses {
+			if order.CurrentStatus() == status {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func (r *memoryOrderRepository) RecentlyClosed(ctx context.Context, limit int) ([]models.Order, error) {
+	orders, err := r.find(func(order models.Order) bool { return order.CurrentStatus() == models.OrderClosed })
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].Updated_At.After(orders[j].Updated_At) })
+	return paginate(orders, 0, limit), nil
+}
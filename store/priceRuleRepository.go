@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type PriceRuleRepository interface {
+	List(ctx context.Context) ([]models.PriceRule, error)
+	Get(ctx context.Context, ruleId string) (models.PriceRule, error)
+	Create(ctx context.Context, rule models.PriceRule) error
+	Update(ctx context.Context, rule models.PriceRule) error
+	Delete(ctx context.Context, ruleId string) error
+}
+
+type mongoPriceRuleRepository struct {
+	mongoCollection[models.PriceRule]
+}
+
+func (r *mongoPriceRuleRepository) List(ctx context.Context) ([]models.PriceRule, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoPriceRuleRepository) Get(ctx context.Context, ruleId string) (models.PriceRule, error) {
+	return r.get(ctx, ruleId)
+}
+
+func (r *mongoPriceRuleRepository) Create(ctx context.Context, rule models.PriceRule) error {
+	return r.insert(ctx, rule)
+}
+
+func (r *mongoPriceRuleRepository) Update(ctx context.Context, rule models.PriceRule) error {
+	return r.replace(ctx, rule.Rule_ID, rule)
+}
+
+func (r *mongoPriceRuleRepository) Delete(ctx context.Context, ruleId string) error {
+	return r.delete(ctx, ruleId)
+}
+
+type memoryPriceRuleRepository struct {
+	*memoryCollection[models.PriceRule]
+}
+
+func (r *memoryPriceRuleRepository) List(ctx context.Context) ([]models.PriceRule, error) {
+	return r.find(nil)
+}
+
+func (r *memoryPriceRuleRepository) Get(ctx context.Context, ruleId string) (models.PriceRule, error) {
+	return r.get(ruleId)
+}
+
+func (r *memoryPriceRuleRepository) Create(ctx context.Context, rule models.PriceRule) error {
+	return r.insert(ctx, rule)
+}
+
+func (r *memoryPriceRuleRepository) Update(ctx context.Context, rule models.PriceRule) error {
+	return r.replace(ctx, rule.Rule_ID, rule)
+}
+
+func (r *memoryPriceRuleRepository) Delete(ctx context.Context, ruleId string) error {
+	return r.delete(ctx, ruleId)
+}
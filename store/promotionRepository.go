@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type PromotionRepository interface {
+	List(ctx context.Context) ([]models.Promotion, error)
+	Get(ctx context.Context, promotionId string) (models.Promotion, error)
+	// GetByCode finds a coupon by its normalized code.
+	GetByCode(ctx context.Context, code string) (models.Promotion, error)
+	Create(ctx context.Context, promotion models.Promotion) error
+	Update(ctx context.Context, promotion models.Promotion) error
+	Delete(ctx context.Context, promotionId string) error
+}
+
+type mongoPromotionRepository struct {
+	mongoCollection[models.Promotion]
+}
+
+func (r *mongoPromotionRepository) List(ctx context.Context) ([]models.Promotion, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoPromotionRepository) Get(ctx context.Context, promotionId string) (models.Promotion, error) {
+	return r.get(ctx, promotionId)
+}
+
+func (r *mongoPromotionRepository) GetByCode(ctx context.Context, code string) (models.Promotion, error) {
+	return r.findOne(ctx, bson.M{"code": code})
+}
+
+func (r *mongoPromotionRepository) Create(ctx context.Context, promotion models.Promotion) error {
+	return r.insert(ctx, promotion)
+}
+
+func (r *mongoPromotionRepository) Update(ctx context.Context, promotion models.Promotion) error {
+	return r.replace(ctx, promotion.Promotion_ID, promotion)
+}
+
+func (r *mongoPromotionRepository) Delete(ctx context.Context, promotionId string) error {
+	return r.delete(ctx, promotionId)
+}
+
+func (r *mongoPromotionRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "code", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"code": bson.M{"$type": "string"}})},
+	})
+	return err
+}
+
+type memoryPromotionRepository struct {
+	*memoryCollection[models.Promotion]
+}
+
+func (r *memoryPromotionRepository) List(ctx context.Context) ([]models.Promotion, error) {
+	return r.find(nil)
+}
+
+func (r *memoryPromotionRepository) Get(ctx context.Context, promotionId string) (models.Promotion, error) {
+	return r.get(promotionId)
+}
+
+func (r *memoryPromotionRepository) GetByCode(ctx context.Context, code string) (models.Promotion, error) {
+	return r.findOne(func(p models.Promotion) bool { return p.Code != nil && *p.Code == code })
+}
+
+func (r *memoryPromotionRepository) Create(ctx context.Context, promotion models.Promotion) error {
+	return r.insert(ctx, promotion)
+}
+
+func (r *memoryPromotionRepository) Update(ctx context.Context, promotion models.Promotion) error {
+	return r.replace(ctx, promotion.Promotion_ID, promotion)
+}
+
+func (r *memoryPromotionRepository) Delete(ctx context.Context, promotionId string) error {
+	return r.delete(ctx, promotionId)
+}
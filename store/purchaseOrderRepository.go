@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PurchaseOrderFilter narrows a purchase order listing. Zero fields match
+// everything.
+type PurchaseOrderFilter struct {
+	Supplier_ID   string
+	Ingredient_ID string
+	Statuses      []string
+}
+
+func (f PurchaseOrderFilter) query() bson.M {
+	query := bson.M{}
+	if f.Supplier_ID != "" {
+		query["supplier_id"] = f.Supplier_ID
+	}
+	if f.Ingredient_ID != "" {
+		query["lines.ingredient_id"] = f.Ingredient_ID
+	}
+	if len(f.Statuses) > 0 {
+		query["status"] = bson.M{"$in": f.Statuses}
+	}
+
+	return query
+}
+
+func (f PurchaseOrderFilter) matches(order models.PurchaseOrder) bool {
+	if f.Supplier_ID != "" && (order.Supplier_ID == nil || *order.Supplier_ID != f.Supplier_ID) {
+		return false
+	}
+
+	if f.Ingredient_ID != "" {
+		found := false
+		for _, line := range order.Lines {
+			found = found || line.Ingredient_ID == f.Ingredient_ID
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Statuses) == 0 {
+		return true
+	}
+	for _, status := range f.Statuses {
+		if order.Status == status {
+			return true
+		}
+	}
+
+	return false
+}
+
+type PurchaseOrderRepository interface {
+	// List returns matching purchase orders, newest first.
+	List(ctx context.Context, filter PurchaseOrderFilter) ([]models.PurchaseOrder, error)
+	Get(ctx context.Context, purchaseOrderId string) (models.PurchaseOrder, error)
+	Create(ctx context.Context, order models.PurchaseOrder) error
+	Update(ctx context.Context, order models.PurchaseOrder) error
+	Delete(ctx context.Context, purchaseOrderId string) error
+}
+
+type mongoPurchaseOrderRepository struct {
+	mongoCollection[models.PurchaseOrder]
+}
+
+func (r *mongoPurchaseOrderRepository) List(ctx context.Context, filter PurchaseOrderFilter) ([]models.PurchaseOrder, error) {
+	return r.find(ctx, filter.query(), options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+}
+
+func (r *mongoPurchaseOrderRepository) Get(ctx context.Context, purchaseOrderId string) (models.PurchaseOrder, error) {
+	return r.get(ctx, purchaseOrderId)
+}
+
+func (r *mongoPurchaseOrderRepository) Create(ctx context.Context, order models.PurchaseOrder) error {
+	return r.insert(ctx, order)
+}
+
+func (r *mongoPurchaseOrderRepository) Update(ctx context.Context, order models.PurchaseOrder) error {
+	return r.replace(ctx, order.Purchase_Order_ID, order)
+}
+
+func (r *mongoPurchaseOrderRepository) Delete(ctx context.Context, purchaseOrderId string) error {
+	return r.delete(ctx, purchaseOrderId)
+}
+
+func (r *mongoPurchaseOrderRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "supplier_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+	})
+	return err
+}
+
+type memoryPurchaseOrderRepository struct {
+	*memoryCollection[models.PurchaseOrder]
+}
+
+func (r *memoryPurchaseOrderRepository) List(ctx context.Context, filter PurchaseOrderFilter) ([]models.PurchaseOrder, error) {
+	orders, err := r.find(filter.matches)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(orders)-1; i < j; i, j = i+1, j-1 {
+		orders[i], orders[j] = orders[j], orders[i]
+	}
+
+	return orders, nil
+}
+
+func (r *memoryPurchaseOrderRepository) Get(ctx context.Context, purchaseOrderId string) (models.PurchaseOrder, error) {
+	return r.get(purchaseOrderId)
+}
+
+func (r *memoryPurchaseOrderRepository) Create(ctx context.Context, order models.PurchaseOrder) error {
+	return r.insert(ctx, order)
+}
+
+func (r *memoryPurchaseOrderRepository) Update(ctx context.Context, order models.PurchaseOrder) error {
+	return r.replace(ctx, order.Purchase_Order_ID, order)
+}
+
+func (r *memoryPurchaseOrderRepository) Delete(ctx context.Context, purchaseOrderId string) error {
+	return r.delete(ctx, purchaseOrderId)
+}
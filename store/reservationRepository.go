@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ReservationRepository interface {
+	// Between lists reservations that overlap [from, to), whatever their
+	// status, earliest first.
+	Between(ctx context.Context, from, to time.Time) ([]models.Reservation, error)
+	Get(ctx context.Context, reservationId string) (models.Reservation, error)
+	Create(ctx context.Context, reservation models.Reservation) error
+	Update(ctx context.Context, reservation models.Reservation) error
+}
+
+type mongoReservationRepository struct {
+	mongoCollection[models.Reservation]
+}
+
+func (r *mongoReservationRepository) Between(ctx context.Context, from, to time.Time) ([]models.Reservation, error) {
+	filter := bson.M{"start_time": bson.M{"$lt": to}, "end_time": bson.M{"$gt": from}}
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "start_time", Value: 1}}))
+}
+
+func (r *mongoReservationRepository) Get(ctx context.Context, reservationId string) (models.Reservation, error) {
+	return r.get(ctx, reservationId)
+}
+
+func (r *mongoReservationRepository) Create(ctx context.Context, reservation models.Reservation) error {
+	return r.insert(ctx, reservation)
+}
+
+func (r *mongoReservationRepository) Update(ctx context.Context, reservation models.Reservation) error {
+	return r.replace(ctx, reservation.Reservation_ID, reservation)
+}
+
+func (r *mongoReservationRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "start_time", Value: 1}, {Key: "end_time", Value: 1}}},
+	})
+	return err
+}
+
+type memoryReservationRepository struct {
+	*memoryCollection[models.Reservation]
+}
+
+func (r *memoryReservationRepository) Between(ctx context.Context, from, to time.Time) ([]models.Reservation, error) {
+	reservations, err := r.find(func(reservation models.Reservation) bool {
+		return reservation.Start_Time != nil && reservation.Start_Time.Before(to) && reservation.End_Time.After(from)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(reservations, func(i, j int) bool {
+		return reservations[i].Start_Time.Before(*reservations[j].Start_Time)
+	})
+	return reservations, nil
+}
+
+func (r *memoryReservationRepository) Get(ctx context.Context, reservationId string) (models.Reservation, error) {
+	return r.get(reservationId)
+}
+
+func (r *memoryReservationRepository) Create(ctx context.Context, reservation models.Reservation) error {
+	return r.insert(ctx, reservation)
+}
+
+func (r *memoryReservationRepository) Update(ctx context.Context, reservation models.Reservation) error {
+	return r.replace(ctx, reservation.Reservation_ID, reservation)
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RevocationRepository is a denylist of token ids. Entries only need to live
+// as long as the token they revoke, after which they may be dropped. Spend is
+// Revoke for single-use tokens: it fails with ErrDuplicate when the token has
+// been used already.
+type RevocationRepository interface {
+	Revoke(ctx context.Context, tokenId, userId string, expiresAt time.Time) error
+	Spend(ctx context.Context, tokenId, userId string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenId string) (bool, error)
+}
+
+type mongoRevocationRepository struct {
+	mongoCollection[models.RevokedToken]
+}
+
+func (r *mongoRevocationRepository) Revoke(ctx context.Context, tokenId, userId string, expiresAt time.Time) error {
+	created_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	update := bson.D{{Key: "$setOnInsert", Value: bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "token_id", Value: tokenId},
+		{Key: "user_id", Value: userId},
+		{Key: "expires_at", Value: expiresAt},
+		{Key: "created_at", Value: created_at},
+	}}}
+
+	_, err := r.coll.UpdateOne(ctx, bson.M{"token_id": tokenId}, update, options.Update().SetUpsert(true))
+	return err
+}
+
+func (r *mongoRevocationRepository) Spend(ctx context.Context, tokenId, userId string, expiresAt time.Time) error {
+	created_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return r.insert(ctx, models.RevokedToken{
+		ID:         primitive.NewObjectID(),
+		Token_ID:   tokenId,
+		User_ID:    userId,
+		Expires_At: expiresAt,
+		Created_At: created_at,
+	})
+}
+
+func (r *mongoRevocationRepository) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	// The TTL monitor only runs once a minute, so check expiry explicitly.
+	count, err := r.count(ctx, bson.M{"token_id": tokenId, "expires_at": bson.M{"$gt": time.Now()}})
+	return count > 0, err
+}
+
+func (r *mongoRevocationRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	return err
+}
+
+type memoryRevocationRepository struct {
+	*memoryCollection[models.RevokedToken]
+}
+
+func (r *memoryRevocationRepository) Revoke(ctx context.Context, tokenId, userId string, expiresAt time.Time) error {
+	r.purgeExpired(ctx)
+
+	created_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	err := r.insert(ctx, models.RevokedToken{
+		ID:         primitive.NewObjectID(),
+		Token_ID:   tokenId,
+		User_ID:    userId,
+		Expires_At: expiresAt,
+		Created_At: created_at,
+	})
+	if err == ErrDuplicate {
+		return nil
+	}
+
+	return err
+}
+
+func (r *memoryRevocationRepository) Spend(ctx context.Context, tokenId, userId string, expiresAt time.Time) error {
+	r.purgeExpired(ctx)
+
+	created_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return r.insert(ctx, models.RevokedToken{
+		ID:         primitive.NewObjectID(),
+		Token_ID:   tokenId,
+		User_ID:    userId,
+		Expires_At: expiresAt,
+		Created_At: created_at,
+	})
+}
+
+func (r *memoryRevocationRepository) IsRevoked(ctx context.Context, tokenId string) (bool, error) {
+	revoked, err := r.get(tokenId)
+	if err == ErrNotFound {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return revoked.Expires_At.After(time.Now()), nil
+}
+
+func (r *memoryRevocationRepository) purgeExpired(ctx context.Context) {
+	expired, _ := r.find(func(revoked models.RevokedToken) bool {
+		return !revoked.Expires_At.After(time.Now())
+	})
+
+	for _, revoked := range expired {
+		r.delete(ctx, revoked.Token_ID)
+	}
+}
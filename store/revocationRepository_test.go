@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryRevocations(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	expires := time.Now().Add(time.Hour)
+
+	if err := s.Revocations.Revoke(ctx, "a", "user", expires); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revocations.Revoke(ctx, "a", "user", expires); err != nil {
+		t.Errorf("revoking twice: %v, want no error", err)
+	}
+
+	if err := s.Revocations.Spend(ctx, "b", "user", expires); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Revocations.Spend(ctx, "b", "user", expires); !errors.Is(err, ErrDuplicate) {
+		t.Errorf("spending twice: got %v, want ErrDuplicate", err)
+	}
+
+	for _, tokenId := range []string{"a", "b"} {
+		if revoked, err := s.Revocations.IsRevoked(ctx, tokenId); err != nil || !revoked {
+			t.Errorf("IsRevoked(%q) = %v, %v, want true", tokenId, revoked, err)
+		}
+	}
+
+	if revoked, err := s.Revocations.IsRevoked(ctx, "c"); err != nil || revoked {
+		t.Errorf("IsRevoked(%q) = %v, %v, want false", "c", revoked, err)
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+)
+
+var ErrNotFound = errors.New("store: document not found")
+var ErrDuplicate = errors.New("store: document already exists")
+
+// Store groups every repository the handlers depend on so a single value can
+// be threaded through the routes, whatever backend sits behind it.
+type Store struct {
+	Foods          FoodRepository
+	Menus          MenuRepository
+	Tables         TableRepository
+	Orders         OrderRepository
+	OrderItems     OrderItemRepository
+	Invoices       InvoiceRepository
+	Users          UserRepository
+	Revocations    RevocationRepository
+	Transactions   TransactionRepository
+	Adjustments    AdjustmentRepository
+	Reservations   ReservationRepository
+	Waitlist       WaitlistRepository
+	PriceRules     PriceRuleRepository
+	Promotions     PromotionRepository
+	Notes          NoteRepository
+	Ingredients    IngredientRepository
+	Suppliers      SupplierRepository
+	PurchaseOrders PurchaseOrderRepository
+	Waste          WasteRepository
+
+	tx transactor
+}
+
+type transactor interface {
+	withTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// WithTransaction runs fn so that the writes it makes through the
+// repositories either all stick or, if fn fails, none do. Repositories only
+// take part when called with the context fn is given.
+func (s *Store) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.tx.withTransaction(ctx, fn)
+}
+
+// repositories returns every repository on the store.
+func (s *Store) repositories() []interface{} {
+	repos := []interface{}{}
+	fields := reflect.ValueOf(s).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		if fields.Type().Field(i).IsExported() {
+			repos = append(repos, fields.Field(i).Interface())
+		}
+	}
+
+	return repos
+}
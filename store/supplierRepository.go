@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type SupplierRepository interface {
+	List(ctx context.Context) ([]models.Supplier, error)
+	Get(ctx context.Context, supplierId string) (models.Supplier, error)
+	Create(ctx context.Context, supplier models.Supplier) error
+	Update(ctx context.Context, supplier models.Supplier) error
+	Delete(ctx context.Context, supplierId string) error
+}
+
+type mongoSupplierRepository struct {
+	mongoCollection[models.Supplier]
+}
+
+func (r *mongoSupplierRepository) List(ctx context.Context) ([]models.Supplier, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoSupplierRepository) Get(ctx context.Context, supplierId string) (models.Supplier, error) {
+	return r.get(ctx, supplierId)
+}
+
+func (r *mongoSupplierRepository) Create(ctx context.Context, supplier models.Supplier) error {
+	return r.insert(ctx, supplier)
+}
+
+func (r *mongoSupplierRepository) Update(ctx context.Context, supplier models.Supplier) error {
+	return r.replace(ctx, supplier.Supplier_ID, supplier)
+}
+
+func (r *mongoSupplierRepository) Delete(ctx context.Context, supplierId string) error {
+	return r.delete(ctx, supplierId)
+}
+
+type memorySupplierRepository struct {
+	*memoryCollection[models.Supplier]
+}
+
+func (r *memorySupplierRepository) List(ctx context.Context) ([]models.Supplier, error) {
+	return r.find(nil)
+}
+
+func (r *memorySupplierRepository) Get(ctx context.Context, supplierId string) (models.Supplier, error) {
+	return r.get(supplierId)
+}
+
+func (r *memorySupplierRepository) Create(ctx context.Context, supplier models.Supplier) error {
+	return r.insert(ctx, supplier)
+}
+
+func (r *memorySupplierRepository) Update(ctx context.Context, supplier models.Supplier) error {
+	return r.replace(ctx, supplier.Supplier_ID, supplier)
+}
+
+func (r *memorySupplierRepository) Delete(ctx context.Context, supplierId string) error {
+	return r.delete(ctx, supplierId)
+}
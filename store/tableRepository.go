@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type TableRepository interface {
+	List(ctx context.Context) ([]models.Table, error)
+	Get(ctx context.Context, tableId string) (models.Table, error)
+	Create(ctx context.Context, table models.Table) error
+	Update(ctx context.Context, table models.Table) error
+	Delete(ctx context.Context, tableId string) error
+	// Hold writes to the table inside a booking transaction, so that two
+	// transactions booking the same table conflict and one of them is
+	// retried against the other's booking.
+	Hold(ctx context.Context, tableId string) error
+}
+
+type mongoTableRepository struct {
+	mongoCollection[models.Table]
+}
+
+func (r *mongoTableRepository) List(ctx context.Context) ([]models.Table, error) {
+	return r.find(ctx, bson.M{})
+}
+
+func (r *mongoTableRepository) Get(ctx context.Context, tableId string) (models.Table, error) {
+	return r.get(ctx, tableId)
+}
+
+func (r *mongoTableRepository) Create(ctx context.Context, table models.Table) error {
+	return r.insert(ctx, table)
+}
+
+func (r *mongoTableRepository) Update(ctx context.Context, table models.Table) error {
+	return r.replace(ctx, table.Table_ID, table)
+}
+
+func (r *mongoTableRepository) Delete(ctx context.Context, tableId string) error {
+	return r.delete(ctx, tableId)
+}
+
+func (r *mongoTableRepository) Hold(ctx context.Context, tableId string) error {
+	result, err := r.coll.UpdateOne(ctx, bson.M{"table_id": tableId}, bson.M{"$inc": bson.M{"bookings_made": 1}})
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+type memoryTableRepository struct {
+	*memoryCollection[models.Table]
+}
+
+func (r *memoryTableRepository) List(ctx context.Context) ([]models.Table, error) {
+	return r.find(nil)
+}
+
+func (r *memoryTableRepository) Get(ctx context.Context, tableId string) (models.Table, error) {
+	return r.get(tableId)
+}
+
+func (r *memoryTableRepository) Create(ctx context.Context, table models.Table) error {
+	return r.insert(ctx, table)
+}
+
+func (r *memoryTableRepository) Update(ctx context.Context, table models.Table) error {
+	return r.replace(ctx, table.Table_ID, table)
+}
+
+func (r *memoryTableRepository) Delete(ctx context.Context, tableId string) error {
+	return r.delete(ctx, tableId)
+}
+
+// Hold only has to find the table: memory transactions already run one at a
+// time.
+func (r *memoryTableRepository) Hold(ctx context.Context, tableId string) error {
+	_, err := r.update(ctx, tableId, func(*models.Table) {})
+	return err
+}
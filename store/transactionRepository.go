@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type TransactionRepository interface {
+	Get(ctx context.Context, transactionId string) (models.PaymentTransaction, error)
+	GetByReference(ctx context.Context, provider, reference string) (models.PaymentTransaction, error)
+	ListByInvoice(ctx context.Context, invoiceId string) ([]models.PaymentTransaction, error)
+	Create(ctx context.Context, transaction models.PaymentTransaction) error
+	Update(ctx context.Context, transaction models.PaymentTransaction) error
+	// ApplyWebhook records a provider webhook in one conditional write. It
+	// only applies while the transaction's status is still from and the
+	// webhook's event id has not been seen, and reports whether it applied.
+	ApplyWebhook(ctx context.Context, transactionId, from string, event models.TransactionEvent) (bool, error)
+}
+
+type mongoTransactionRepository struct {
+	mongoCollection[models.PaymentTransaction]
+}
+
+func (r *mongoTransactionRepository) Get(ctx context.Context, transactionId string) (models.PaymentTransaction, error) {
+	return r.get(ctx, transactionId)
+}
+
+func (r *mongoTransactionRepository) GetByReference(ctx context.Context, provider, reference string) (models.PaymentTransaction, error) {
+	return r.findOne(ctx, bson.M{"provider": provider, "provider_reference": reference})
+}
+
+func (r *mongoTransactionRepository) ListByInvoice(ctx context.Context, invoiceId string) ([]models.PaymentTransaction, error) {
+	return r.find(ctx, bson.M{"invoice_id": invoiceId})
+}
+
+func (r *mongoTransactionRepository) Create(ctx context.Context, transaction models.PaymentTransaction) error {
+	return r.insert(ctx, transaction)
+}
+
+func (r *mongoTransactionRepository) Update(ctx context.Context, transaction models.PaymentTransaction) error {
+	return r.replace(ctx, transaction.Transaction_ID, transaction)
+}
+
+func (r *mongoTransactionRepository) ApplyWebhook(ctx context.Context, transactionId, from string, event models.TransactionEvent) (bool, error) {
+	filter := bson.M{"transaction_id": transactionId, "status": from, "events.event_id": bson.M{"$ne": event.Event_ID}}
+	set := bson.M{"status": event.Status, "reconciled_at": event.At, "updated_at": event.At}
+	if event.Reason != "" {
+		set["failure_reason"] = event.Reason
+	}
+
+	result, err := r.coll.UpdateOne(ctx, filter, bson.M{"$set": set, "$push": bson.M{"events": event}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount > 0, nil
+}
+
+func (r *mongoTransactionRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "invoice_id", Value: 1}}},
+		{Keys: bson.D{{Key: "provider", Value: 1}, {Key: "provider_reference", Value: 1}}},
+	})
+	return err
+}
+
+type memoryTransactionRepository struct {
+	*memoryCollection[models.PaymentTransaction]
+}
+
+func (r *memoryTransactionRepository) Get(ctx context.Context, transactionId string) (models.PaymentTransaction, error) {
+	return r.get(transactionId)
+}
+
+func (r *memoryTransactionRepository) GetByReference(ctx context.Context, provider, reference string) (models.PaymentTransaction, error) {
+	return r.findOne(func(t models.PaymentTransaction) bool {
+		return t.Provider == provider && t.Provider_Reference == reference
+	})
+}
+
+func (r *memoryTransactionRepository) ListByInvoice(ctx context.Context, invoiceId string) ([]models.PaymentTransaction, error) {
+	return r.find(func(t models.PaymentTransaction) bool { return t.Invoice_ID == invoiceId })
+}
+
+func (r *memoryTransactionRepository) Create(ctx context.Context, transaction models.PaymentTransaction) error {
+	return r.insert(ctx, transaction)
+}
+
+func (r *memoryTransactionRepository) Update(ctx context.Context, transaction models.PaymentTransaction) error {
+	return r.replace(ctx, transaction.Transaction_ID, transaction)
+}
+
+func (r *memoryTransactionRepository) ApplyWebhook(ctx context.Context, transactionId, from string, event models.TransactionEvent) (bool, error) {
+	applied := false
+	_, err := r.update(ctx, transactionId, func(transaction *models.PaymentTransaction) {
+		if transaction.Status != from || transaction.SeenEvent(event.Event_ID) {
+			return
+		}
+
+		at := event.At
+		transaction.Status = event.Status
+		transaction.Reconciled_At = &at
+		transaction.Updated_At = at
+		if event.Reason != "" {
+			transaction.Failure_Reason = event.Reason
+		}
+		transaction.Events = append(transaction.Events, event)
+		applied = true
+	})
+
+	return applied, err
+}
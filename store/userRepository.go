@@ -0,0 +1,165 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserRepository interface {
+	List(ctx context.Context, offset, limit int) ([]models.User, int64, error)
+	Get(ctx context.Context, userId string) (models.User, error)
+	Create(ctx context.Context, user models.User) error
+	Update(ctx context.Context, user models.User) error
+	Delete(ctx context.Context, userId string) error
+	GetByEmail(ctx context.Context, email string) (models.User, error)
+	CountByEmail(ctx context.Context, email string) (int64, error)
+	CountByPhone(ctx context.Context, phone string) (int64, error)
+	CountByRole(ctx context.Context, role string) (int64, error)
+	UpdateTokens(ctx context.Context, userId, token, refreshToken string) error
+}
+
+type mongoUserRepository struct {
+	mongoCollection[models.User]
+}
+
+func (r *mongoUserRepository) List(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+	total, err := r.count(ctx, bson.M{})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().SetSkip(int64(offset)).SetLimit(int64(limit))
+	users, err := r.find(ctx, bson.M{}, opts)
+	return users, total, err
+}
+
+func (r *mongoUserRepository) Get(ctx context.Context, userId string) (models.User, error) {
+	return r.get(ctx, userId)
+}
+
+func (r *mongoUserRepository) Create(ctx context.Context, user models.User) error {
+	return r.insert(ctx, user)
+}
+
+func (r *mongoUserRepository) Update(ctx context.Context, user models.User) error {
+	return r.replace(ctx, user.User_ID, user)
+}
+
+func (r *mongoUserRepository) Delete(ctx context.Context, userId string) error {
+	return r.delete(ctx, userId)
+}
+
+func (r *mongoUserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.findOne(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) CountByEmail(ctx context.Context, email string) (int64, error) {
+	return r.count(ctx, bson.M{"email": email})
+}
+
+func (r *mongoUserRepository) CountByPhone(ctx context.Context, phone string) (int64, error) {
+	return r.count(ctx, bson.M{"phone": phone})
+}
+
+func (r *mongoUserRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	return r.count(ctx, bson.M{"role": role})
+}
+
+func (r *mongoUserRepository) UpdateTokens(ctx context.Context, userId, token, refreshToken string) error {
+	updated_at, _ := time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "token", Value: token},
+		{Key: "refresh_token", Value: refreshToken},
+		{Key: "updated_at", Value: updated_at},
+	}}}
+
+	result, err := r.coll.UpdateOne(ctx, bson.M{"user_id": userId}, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// ensureIndexes makes emails unique, so two accounts cannot be created for
+// the same email however close together they arrive.
+func (r *mongoUserRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	return err
+}
+
+type memoryUserRepository struct {
+	*memoryCollection[models.User]
+}
+
+func (r *memoryUserRepository) List(ctx context.Context, offset, limit int) ([]models.User, int64, error) {
+	users, err := r.find(nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return paginate(users, offset, limit), int64(len(users)), nil
+}
+
+func (r *memoryUserRepository) Get(ctx context.Context, userId string) (models.User, error) {
+	return r.get(userId)
+}
+
+func (r *memoryUserRepository) Create(ctx context.Context, user models.User) error {
+	return r.insert(ctx, user)
+}
+
+func (r *memoryUserRepository) Update(ctx context.Context, user models.User) error {
+	return r.replace(ctx, user.User_ID, user)
+}
+
+func (r *memoryUserRepository) Delete(ctx context.Context, userId string) error {
+	return r.delete(ctx, userId)
+}
+
+func (r *memoryUserRepository) GetByEmail(ctx context.Context, email string) (models.User, error) {
+	return r.findOne(func(user models.User) bool {
+		return user.Email != nil && *user.Email == email
+	})
+}
+
+func (r *memoryUserRepository) CountByEmail(ctx context.Context, email string) (int64, error) {
+	return r.count(func(user models.User) bool {
+		return user.Email != nil && *user.Email == email
+	})
+}
+
+func (r *memoryUserRepository) CountByPhone(ctx context.Context, phone string) (int64, error) {
+	return r.count(func(user models.User) bool {
+		return user.Phone != nil && *user.Phone == phone
+	})
+}
+
+func (r *memoryUserRepository) CountByRole(ctx context.Context, role string) (int64, error) {
+	return r.count(func(user models.User) bool {
+		return user.UserRole() == role
+	})
+}
+
+func (r *memoryUserRepository) UpdateTokens(ctx context.Context, userId, token, refreshToken string) error {
+	user, err := r.get(userId)
+	if err != nil {
+		return err
+	}
+
+	user.Token = &token
+	user.Refresh_Token = &refreshToken
+	user.Updated_At, _ = time.Parse(time.RFC3339, time.Now().Format(time.RFC3339))
+	return r.replace(ctx, userId, user)
+}
@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type WaitlistRepository interface {
+	// Waiting lists the parties still in the queue, first come first.
+	Waiting(ctx context.Context) ([]models.WaitlistEntry, error)
+	Get(ctx context.Context, entryId string) (models.WaitlistEntry, error)
+	Create(ctx context.Context, entry models.WaitlistEntry) error
+	Update(ctx context.Context, entry models.WaitlistEntry) error
+	// Seat marks the party seated at tableId for orderId, but only while
+	// it is still waiting; otherwise it fails with ErrNotFound, so the same
+	// party cannot be seated twice.
+	Seat(ctx context.Context, entryId, orderId, tableId string, at time.Time) (models.WaitlistEntry, error)
+}
+
+type mongoWaitlistRepository struct {
+	mongoCollection[models.WaitlistEntry]
+}
+
+func (r *mongoWaitlistRepository) Waiting(ctx context.Context) ([]models.WaitlistEntry, error) {
+	filter := bson.M{"status": bson.M{"$in": bson.A{models.WaitlistWaiting, models.WaitlistNotified}}}
+	return r.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+}
+
+func (r *mongoWaitlistRepository) Get(ctx context.Context, entryId string) (models.WaitlistEntry, error) {
+	return r.get(ctx, entryId)
+}
+
+func (r *mongoWaitlistRepository) Create(ctx context.Context, entry models.WaitlistEntry) error {
+	return r.insert(ctx, entry)
+}
+
+func (r *mongoWaitlistRepository) Update(ctx context.Context, entry models.WaitlistEntry) error {
+	return r.replace(ctx, entry.Entry_ID, entry)
+}
+
+func (r *mongoWaitlistRepository) Seat(ctx context.Context, entryId, orderId, tableId string, at time.Time) (models.WaitlistEntry, error) {
+	var entry models.WaitlistEntry
+	err := r.coll.FindOneAndUpdate(ctx,
+		bson.M{"entry_id": entryId, "status": bson.M{"$in": bson.A{models.WaitlistWaiting, models.WaitlistNotified}}},
+		bson.M{"$set": bson.M{
+			"status":           models.WaitlistSeated,
+			"order_id":         orderId,
+			"offered_table_id": tableId,
+			"seated_at":        at,
+			"updated_at":       at,
+		}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&entry)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return entry, ErrNotFound
+	}
+
+	return entry, err
+}
+
+func (r *mongoWaitlistRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}},
+	})
+	return err
+}
+
+type memoryWaitlistRepository struct {
+	*memoryCollection[models.WaitlistEntry]
+}
+
+func (r *memoryWaitlistRepository) Waiting(ctx context.Context) ([]models.WaitlistEntry, error) {
+	return r.find(func(entry models.WaitlistEntry) bool { return entry.Waiting() })
+}
+
+func (r *memoryWaitlistRepository) Get(ctx context.Context, entryId string) (models.WaitlistEntry, error) {
+	return r.get(entryId)
+}
+
+func (r *memoryWaitlistRepository) Create(ctx context.Context, entry models.WaitlistEntry) error {
+	return r.insert(ctx, entry)
+}
+
+func (r *memoryWaitlistRepository) Update(ctx context.Context, entry models.WaitlistEntry) error {
+	return r.replace(ctx, entry.Entry_ID, entry)
+}
+
+func (r *memoryWaitlistRepository) Seat(ctx context.Context, entryId, orderId, tableId string, at time.Time) (models.WaitlistEntry, error) {
+	return r.updateIf(ctx, entryId, models.WaitlistEntry.Waiting, func(entry *models.WaitlistEntry) {
+		entry.Seat(orderId, at)
+		entry.Offered_Table_ID = tableId
+	})
+}
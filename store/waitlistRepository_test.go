@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMemoryWaitlistSeat(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	name, phone, size := "Ada", "+15550100", 2
+	entry := models.WaitlistEntry{ID: primitive.NewObjectID(), Party_Name: &name, Party_Size: &size, Phone: &phone, Status: models.WaitlistNotified}
+	entry.Entry_ID = entry.ID.Hex()
+	if err := s.Waitlist.Create(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	seated, err := s.Waitlist.Seat(ctx, entry.Entry_ID, "order-1", "table-1", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if seated.Status != models.WaitlistSeated || seated.Order_ID != "order-1" || seated.Seated_At == nil {
+		t.Errorf("got %+v, want the party seated for order-1", seated)
+	}
+
+	if _, err := s.Waitlist.Seat(ctx, entry.Entry_ID, "order-2", "table-1", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("seating the party twice: got %v, want ErrNotFound", err)
+	}
+
+	got, err := s.Waitlist.Get(ctx, entry.Entry_ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Order_ID != "order-1" {
+		t.Errorf("the party is on order %q, want it left on order-1", got.Order_ID)
+	}
+
+	if _, err := s.Waitlist.Seat(ctx, "missing", "order-3", "table-1", now); !errors.Is(err, ErrNotFound) {
+		t.Errorf("seating a party that is not on the list: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,135 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WasteFilter narrows a waste listing. Zero fields match everything; From is
+// inclusive and To exclusive.
+type WasteFilter struct {
+	Ingredient_ID string
+	Food_ID       string
+	Reason        string
+	From          time.Time
+	To            time.Time
+}
+
+func (f WasteFilter) query() bson.M {
+	query := bson.M{}
+	if f.Ingredient_ID != "" {
+		// Food waste counts against the ingredients it took.
+		query["stock_used.ingredient_id"] = f.Ingredient_ID
+	}
+	if f.Food_ID != "" {
+		query["food_id"] = f.Food_ID
+	}
+	if f.Reason != "" {
+		query["reason"] = f.Reason
+	}
+
+	wastedAt := bson.M{}
+	if !f.From.IsZero() {
+		wastedAt["$gte"] = f.From
+	}
+	if !f.To.IsZero() {
+		wastedAt["$lt"] = f.To
+	}
+	if len(wastedAt) > 0 {
+		query["wasted_at"] = wastedAt
+	}
+
+	return query
+}
+
+func (f WasteFilter) matches(entry models.WasteEntry) bool {
+	if f.Ingredient_ID != "" {
+		found := false
+		for _, line := range entry.Stock_Used {
+			found = found || line.Ingredient_ID == f.Ingredient_ID
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Food_ID != "" && (entry.Food_ID == nil || *entry.Food_ID != f.Food_ID) {
+		return false
+	}
+	if f.Reason != "" && (entry.Reason == nil || *entry.Reason != f.Reason) {
+		return false
+	}
+	if !f.From.IsZero() && entry.Wasted_At.Before(f.From) {
+		return false
+	}
+
+	return f.To.IsZero() || entry.Wasted_At.Before(f.To)
+}
+
+type WasteRepository interface {
+	// List returns matching entries, earliest first.
+	List(ctx context.Context, filter WasteFilter) ([]models.WasteEntry, error)
+	Get(ctx context.Context, wasteId string) (models.WasteEntry, error)
+	Create(ctx context.Context, entry models.WasteEntry) error
+	Delete(ctx context.Context, wasteId string) error
+}
+
+type mongoWasteRepository struct {
+	mongoCollection[models.WasteEntry]
+}
+
+func (r *mongoWasteRepository) List(ctx context.Context, filter WasteFilter) ([]models.WasteEntry, error) {
+	return r.find(ctx, filter.query(), options.Find().SetSort(bson.D{{Key: "wasted_at", Value: 1}}))
+}
+
+func (r *mongoWasteRepository) Get(ctx context.Context, wasteId string) (models.WasteEntry, error) {
+	return r.get(ctx, wasteId)
+}
+
+func (r *mongoWasteRepository) Create(ctx context.Context, entry models.WasteEntry) error {
+	return r.insert(ctx, entry)
+}
+
+func (r *mongoWasteRepository) Delete(ctx context.Context, wasteId string) error {
+	return r.delete(ctx, wasteId)
+}
+
+func (r *mongoWasteRepository) ensureIndexes(ctx context.Context) error {
+	_, err := r.coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "wasted_at", Value: 1}}},
+	})
+	return err
+}
+
+type memoryWasteRepository struct {
+	*memoryCollection[models.WasteEntry]
+}
+
+func (r *memoryWasteRepository) List(ctx context.Context, filter WasteFilter) ([]models.WasteEntry, error) {
+	entries, err := r.find(filter.matches)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Wasted_At.Before(entries[j].Wasted_At)
+	})
+	return entries, nil
+}
+
+func (r *memoryWasteRepository) Get(ctx context.Context, wasteId string) (models.WasteEntry, error) {
+	return r.get(wasteId)
+}
+
+func (r *memoryWasteRepository) Create(ctx context.Context, entry models.WasteEntry) error {
+	return r.insert(ctx, entry)
+}
+
+func (r *memoryWasteRepository) Delete(ctx context.Context, wasteId string) error {
+	return r.delete(ctx, wasteId)
+}
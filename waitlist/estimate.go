@@ -0,0 +1,110 @@
+package waitlist
+
+import (
+	"sort"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+// minSamples is how many closed orders are needed before their durations
+// are trusted over the configured turn time.
+const minSamples = 5
+
+// overdue is how much longer a table that has outstayed its expected
+// sitting is assumed to take.
+const overdue = 5 * time.Minute
+
+// quoteStep is what quotes are rounded up to; guests are told "about 15
+// minutes", not "13".
+const quoteStep = 5 * time.Minute
+
+// Occupancy is when a table is expected to be free. A free table has a
+// Free_At no later than now.
+type Occupancy struct {
+	Table_ID string
+	Seats    int
+	Free_At  time.Time
+}
+
+// TypicalSitting is the median time from an order being placed to it being
+// closed, or fallback when there are too few closed orders to go by.
+func TypicalSitting(closed []models.Order, fallback time.Duration) time.Duration {
+	durations := []time.Duration{}
+	for _, order := range closed {
+		closedAt, ok := order.StatusChangedAt(models.OrderClosed)
+		if !ok || !closedAt.After(order.Created_At) {
+			continue
+		}
+		durations = append(durations, closedAt.Sub(order.Created_At))
+	}
+
+	if len(durations) < minSamples {
+		return fallback
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	return durations[len(durations)/2]
+}
+
+// FreeAt is when a table taken at since is expected back, given a typical
+// sitting.
+func FreeAt(since time.Time, sitting time.Duration, now time.Time) time.Time {
+	freeAt := since.Add(sitting)
+	if !freeAt.After(now) {
+		return now.Add(overdue)
+	}
+
+	return freeAt
+}
+
+// Estimate is how long a party of size would wait behind the parties ahead
+// of it, each taking the first table that seats it and keeping it for one
+// sitting. It is false when no table seats the party at all.
+func Estimate(tables []Occupancy, ahead []int, size int, sitting time.Duration, now time.Time) (time.Duration, bool) {
+	freeAt := make([]time.Time, len(tables))
+	for i, table := range tables {
+		freeAt[i] = table.Free_At
+		if freeAt[i].Before(now) {
+			freeAt[i] = now
+		}
+	}
+
+	for _, party := range ahead {
+		if i := firstFree(tables, freeAt, party); i >= 0 {
+			freeAt[i] = freeAt[i].Add(sitting)
+		}
+	}
+
+	i := firstFree(tables, freeAt, size)
+	if i < 0 {
+		return 0, false
+	}
+
+	return roundUp(freeAt[i].Sub(now)), true
+}
+
+// firstFree picks the table that seats party soonest, preferring the
+// smaller table when two free up together.
+func firstFree(tables []Occupancy, freeAt []time.Time, party int) int {
+	best := -1
+	for i, table := range tables {
+		if table.Seats < party {
+			continue
+		}
+
+		if best < 0 || freeAt[i].Before(freeAt[best]) || (freeAt[i].Equal(freeAt[best]) && table.Seats < tables[best].Seats) {
+			best = i
+		}
+	}
+
+	return best
+}
+
+func roundUp(wait time.Duration) time.Duration {
+	if wait <= 0 {
+		return 0
+	}
+
+	return ((wait + quoteStep - 1) / quoteStep) * quoteStep
+}
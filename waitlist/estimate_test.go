@@ -0,0 +1,111 @@
+package waitlist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+func TestEstimate(t *testing.T) {
+	now := time.Date(2026, 10, 19, 19, 0, 0, 0, time.UTC)
+	sitting := time.Hour
+	tables := []Occupancy{
+		{Table_ID: "two", Seats: 2, Free_At: now},
+		{Table_ID: "four", Seats: 4, Free_At: now.Add(30 * time.Minute)},
+	}
+
+	tests := []struct {
+		name   string
+		tables []Occupancy
+		ahead  []int
+		size   int
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "a free table", tables: tables, size: 2, want: 0, wantOK: true},
+		{name: "waits for the bigger table", tables: tables, size: 3, want: 30 * time.Minute, wantOK: true},
+		{name: "no table big enough", tables: tables, size: 6, wantOK: false},
+		{name: "no tables", tables: nil, size: 2, wantOK: false},
+		{name: "behind one party", tables: tables, ahead: []int{2}, size: 2, want: 30 * time.Minute, wantOK: true},
+		{name: "behind two parties", tables: tables, ahead: []int{2, 2}, size: 2, want: time.Hour, wantOK: true},
+		{name: "behind a party that fits nowhere", tables: tables, ahead: []int{8}, size: 2, want: 0, wantOK: true},
+		{name: "behind a party for the bigger table", tables: tables, ahead: []int{4}, size: 3, want: 90 * time.Minute, wantOK: true},
+		{
+			name:   "an overdue table counts as free now",
+			tables: []Occupancy{{Table_ID: "two", Seats: 2, Free_At: now.Add(-10 * time.Minute)}},
+			size:   2, want: 0, wantOK: true,
+		},
+		{
+			name:   "rounded up to five minutes",
+			tables: []Occupancy{{Table_ID: "two", Seats: 2, Free_At: now.Add(13 * time.Minute)}},
+			size:   2, want: 15 * time.Minute, wantOK: true,
+		},
+		{
+			name:   "exact five minutes stay as they are",
+			tables: []Occupancy{{Table_ID: "two", Seats: 2, Free_At: now.Add(20 * time.Minute)}},
+			size:   2, want: 20 * time.Minute, wantOK: true,
+		},
+		{
+			name: "the smaller table goes first when both are free",
+			tables: []Occupancy{
+				{Table_ID: "four", Seats: 4, Free_At: now},
+				{Table_ID: "two", Seats: 2, Free_At: now},
+			},
+			ahead: []int{2}, size: 4, want: 0, wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		got, ok := Estimate(tt.tables, tt.ahead, tt.size, sitting, now)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("%s: Estimate = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestTypicalSitting(t *testing.T) {
+	opened := time.Date(2026, 10, 19, 18, 0, 0, 0, time.UTC)
+	closedAfter := func(d time.Duration) models.Order {
+		return models.Order{
+			Created_At:     opened,
+			Status_History: []models.OrderTransition{{From: models.OrderServed, To: models.OrderClosed, At: opened.Add(d)}},
+		}
+	}
+	fallback := 75 * time.Minute
+
+	tests := []struct {
+		name   string
+		closed []models.Order
+		want   time.Duration
+	}{
+		{name: "no orders", want: fallback},
+		{
+			name:   "too few orders",
+			closed: []models.Order{closedAfter(time.Hour), closedAfter(time.Hour), closedAfter(time.Hour), closedAfter(time.Hour)},
+			want:   fallback,
+		},
+		{
+			name: "median of enough orders",
+			closed: []models.Order{
+				closedAfter(90 * time.Minute), closedAfter(40 * time.Minute), closedAfter(3 * time.Hour),
+				closedAfter(50 * time.Minute), closedAfter(60 * time.Minute),
+			},
+			want: 60 * time.Minute,
+		},
+		{
+			name: "orders never closed are left out",
+			closed: []models.Order{
+				closedAfter(time.Hour), closedAfter(time.Hour), closedAfter(time.Hour), closedAfter(time.Hour),
+				{Created_At: opened},
+			},
+			want: fallback,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := TypicalSitting(tt.closed, fallback); got != tt.want {
+			t.Errorf("%s: TypicalSitting = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package waitlist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jamesconfy/restaurant-management/models"
+)
+
+// Notifier tells a waiting party something, usually that their table is
+// ready. Real senders (SMS, push) plug in here; the log and file notifiers
+// stand in for them until then.
+type Notifier interface {
+	Notify(ctx context.Context, entry models.WaitlistEntry, message string) error
+}
+
+// Config selects the notifier: "log" (the default) or "file", which appends
+// one JSON line per notification to Path.
+type Config struct {
+	Notifier string
+	Path     string
+}
+
+func NewNotifier(cfg Config) (Notifier, error) {
+	switch cfg.Notifier {
+	case "", "log":
+		return LogNotifier{}, nil
+	case "file":
+		if cfg.Path == "" {
+			return nil, fmt.Errorf("waitlist: the file notifier needs a path")
+		}
+		return &FileNotifier{path: cfg.Path}, nil
+	}
+
+	return nil, fmt.Errorf("waitlist: unknown notifier %q", cfg.Notifier)
+}
+
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, entry models.WaitlistEntry, message string) error {
+	log.Printf("waitlist: notifying %s (%s): %s", *entry.Party_Name, *entry.Phone, message)
+	return nil
+}
+
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+type notification struct {
+	Entry_ID string    `json:"entry_id"`
+	Phone    string    `json:"phone"`
+	Message  string    `json:"message"`
+	At       time.Time `json:"at"`
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, entry models.WaitlistEntry, message string) error {
+	line, err := json.Marshal(notification{Entry_ID: entry.Entry_ID, Phone: *entry.Phone, Message: message, At: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("waitlist: opening %s: %w", n.path, err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(line, '\n'))
+	return err
+}